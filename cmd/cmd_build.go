@@ -1,23 +1,41 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"os"
 
 	"github.com/customrealms/cli/pkg/build"
-	"github.com/customrealms/cli/pkg/project"
+	"github.com/customrealms/cli/pkg/jarsign"
 )
 
+// signPasswordEnv is the environment variable holding the password of the signing keystore.
+const signPasswordEnv = "CRX_SIGN_PASSWORD"
+
 type BuildCmd struct {
-	ProjectDir      string `name:"project" short:"p" usage:"plugin project directory" optional:""`
-	ApiVersion      string `name:"mc" usage:"Minecraft version number target" optional:""`
-	TemplateJarFile string `name:"jar" short:"t" usage:"template JAR file" optional:""`
-	OutputFile      string `name:"output" short:"o" usage:"output JAR file path"`
+	ProjectDir      string   `name:"project" short:"p" usage:"plugin project directory" optional:""`
+	ApiVersion      string   `name:"mc" usage:"Minecraft version number target" optional:""`
+	TemplateJarFile string   `name:"jar" short:"t" usage:"template JAR file" optional:""`
+	OutputFile      string   `name:"output" short:"o" usage:"output JAR file path"`
+	Targets         []string `name:"target" usage:"project target to build, can be repeated (default: all targets)" optional:""`
+	Filters         []string `name:"filter" usage:"workspace packages to build, by name or by directory like ./plugins/*, can be repeated" optional:""`
+
+	VerifyReproducible bool `name:"verify-reproducible" usage:"build twice and check that the JAR files are identical"`
+	Checksums          bool `name:"checksums" usage:"write .sha256 and .sha512 checksum files next to the JAR file"`
+	Analyze            bool `name:"analyze" usage:"write esbuild's metafile and print a breakdown of the bundle size"`
+
+	SignKey  string `name:"sign-key" usage:"PKCS#12 keystore or PEM private key to sign the JAR file with" optional:""`
+	SignCert string `name:"sign-cert" usage:"PEM certificate chain for the signing key, if it's not in the key file" optional:""`
+	SignName string `name:"sign-name" usage:"base name of the signature files in META-INF" default:"CRX"`
 }
 
 func (c *BuildCmd) Run() error {
 	// Root context for the CLI
 	ctx, cancel := rootContext()
 	defer cancel()
+	if err := loadConfig(); err != nil {
+		return err
+	}
 
 	// Default to the current working directory
 	if c.ProjectDir == "" {
@@ -34,15 +52,59 @@ func (c *BuildCmd) Run() error {
 		jarTemplate = &build.GitHubJarTemplate{}
 	}
 
-	// Create the project
-	crProject := project.New(c.ProjectDir)
+	// Load the signing key. The password is only read from the environment, so it doesn't
+	// show up in the process list or in the shell history.
+	var signer *jarsign.Signer
+	if c.SignKey != "" {
+		var err error
+		signer, err = jarsign.LoadSigner(c.SignName, c.SignKey, c.SignCert, os.Getenv(signPasswordEnv))
+		if err != nil {
+			return fmt.Errorf("loading signing key: %w", err)
+		}
+	}
 
-	// Create the build action
-	buildAction := build.BuildAction{
-		Project:     crProject,
-		JarTemplate: jarTemplate,
-		ApiVersion:  c.ApiVersion,
-		OutputFile:  c.OutputFile,
+	// Find the projects to build
+	projects, workspace, err := selectProjects(c.ProjectDir, c.Filters)
+	if err != nil {
+		return err
+	}
+	if len(projects) > 1 {
+		if c.OutputFile != "" {
+			return errors.New("output file can only be set when building a single package")
+		}
+		if len(c.Targets) > 0 {
+			return errors.New("targets can only be selected when building a single package, use --filter to select it")
+		}
+
+		// Share the template JAR file between the packages
+		jarTemplate = build.NewOnceJarTemplate(jarTemplate)
+	}
+
+	for _, crProject := range projects {
+		// Create the build action
+		buildAction := build.BuildAction{
+			Project:       crProject,
+			JarTemplate:   jarTemplate,
+			ApiVersion:    c.ApiVersion,
+			CliVersion:    version,
+			OutputFile:    c.OutputFile,
+			DefaultOutput: workspace,
+			Signer:        signer,
+			Checksums:     c.Checksums,
+			Analyze:       c.Analyze,
+			Targets:       c.Targets,
+		}
+		if c.VerifyReproducible {
+			err = buildAction.VerifyReproducible(ctx)
+		} else {
+			err = buildAction.Run(ctx)
+		}
+		if err != nil {
+			if len(projects) > 1 {
+				return fmt.Errorf("building %s: %w", crProject.Dir(), err)
+			}
+			return err
+		}
 	}
-	return buildAction.Run(ctx)
+	return nil
 }
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/customrealms/cli/pkg/cache"
+	"github.com/customrealms/cli/pkg/project"
+)
+
+type CacheCmd struct {
+	List   CacheListCmd   `cmd:"" name:"list" help:"List the files in the cache."`
+	Clean  CacheCleanCmd  `cmd:"" name:"clean" help:"Remove files from the cache."`
+	Verify CacheVerifyCmd `cmd:"" name:"verify" help:"Check the files in the cache against their checksums."`
+	Path   CachePathCmd   `cmd:"" name:"path" help:"Print the path of the cache directory."`
+	Export CacheExportCmd `cmd:"" name:"export" help:"Write the cached files to a tarball."`
+	Import CacheImportCmd `cmd:"" name:"import" help:"Add the files of a tarball written by crx cache export to the cache."`
+}
+
+type CacheListCmd struct{}
+
+func (c *CacheListCmd) Run() error {
+	dlCache, err := cache.Existing()
+	if errors.Is(err, os.ErrNotExist) {
+		fmt.Println("The cache is empty.")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	entries, err := dlCache.List()
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Println("The cache is empty.")
+		return nil
+	}
+
+	var total int64
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TYPE\tVERSION\tSIZE\tLAST USED\tKEY")
+	for _, entry := range entries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			valueOrDash(entry.Kind),
+			valueOrDash(entry.Version),
+			project.Size(entry.Size),
+			entry.LastUsed.Local().Format("2006-01-02 15:04"),
+			entry.Key,
+		)
+		total += entry.Size
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("\n%d files, %s in %s\n", len(entries), project.Size(total), dlCache.Dir())
+	return nil
+}
+
+type CacheCleanCmd struct {
+	OlderThan string  `name:"older-than" usage:"only remove files not used for this long, like 30d or 12h" optional:""`
+	Type      *string `name:"type" enum:"server,runtime,versions,template,registry" usage:"only remove files of this type: server, runtime, versions, template or registry" optional:""`
+	Version   string  `name:"version" usage:"only remove files of this version, like a Minecraft version" optional:""`
+	MaxSize   string  `name:"max-size" usage:"remove the least recently used files until the cache is no larger than this, like 1GB" optional:""`
+}
+
+func (c *CacheCleanCmd) Run() error {
+	dlCache, err := cache.New()
+	if err != nil {
+		return err
+	}
+
+	var removed []cache.Entry
+	if c.MaxSize != "" {
+		// Shrink the cache to a size
+		if c.OlderThan != "" || c.Type != nil || c.Version != "" {
+			return fmt.Errorf("--max-size can't be combined with the other filters")
+		}
+		maxSize, err := project.ParseSize(c.MaxSize)
+		if err != nil {
+			return err
+		}
+		if removed, err = dlCache.Prune(int64(maxSize)); err != nil {
+			return err
+		}
+	} else {
+		// Remove the matching files
+		var before time.Time
+		if c.OlderThan != "" {
+			age, err := parseAge(c.OlderThan)
+			if err != nil {
+				return err
+			}
+			before = time.Now().Add(-age)
+		}
+		removed, err = dlCache.Clean(func(entry cache.Entry) bool {
+			return (before.IsZero() || entry.LastUsed.Before(before)) &&
+				(c.Type == nil || entry.Kind == *c.Type) &&
+				(c.Version == "" || entry.Version == c.Version)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	var freed int64
+	for _, entry := range removed {
+		fmt.Printf("Removed %s\n", entry.Key)
+		freed += entry.Size
+	}
+	fmt.Printf("Removed %d files, freed %s.\n", len(removed), project.Size(freed))
+	return nil
+}
+
+// parseAge parses a duration like "12h", or a number of days like "30d".
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	age, err := time.ParseDuration(s)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	return age, nil
+}
+
+type CacheVerifyCmd struct {
+	Remove bool `name:"remove" usage:"remove the corrupt files, so they're downloaded again"`
+}
+
+func (c *CacheVerifyCmd) Run() error {
+	dlCache, err := cache.New()
+	if err != nil {
+		return err
+	}
+
+	// Forget the files removed by hand
+	missing, err := dlCache.Tidy()
+	if err != nil {
+		return err
+	}
+	for _, key := range missing {
+		fmt.Printf("missing    %s (removed from the index)\n", key)
+	}
+
+	entries, err := dlCache.List()
+	if err != nil {
+		return err
+	}
+
+	var corrupt int
+	for _, entry := range entries {
+		err := dlCache.Verify(entry)
+		switch {
+		case err == nil:
+			fmt.Printf("ok         %s\n", entry.Key)
+		case errors.Is(err, cache.ErrNoChecksum):
+			fmt.Printf("unverified %s (no checksum recorded)\n", entry.Key)
+		case errors.Is(err, os.ErrNotExist):
+			fmt.Printf("missing    %s (removed from the index)\n", entry.Key)
+		default:
+			corrupt++
+			fmt.Printf("corrupt    %s (%s)\n", entry.Key, err)
+			if c.Remove {
+				if err := dlCache.Remove(entry.Key); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if corrupt > 0 && !c.Remove {
+		return fmt.Errorf("%d corrupt files in the cache, run with --remove to remove them", corrupt)
+	}
+	if corrupt > 0 {
+		fmt.Printf("Removed %d corrupt files.\n", corrupt)
+	}
+	return nil
+}
+
+type CachePathCmd struct{}
+
+func (c *CachePathCmd) Run() error {
+	dlCache, err := cache.Existing()
+	if errors.Is(err, os.ErrNotExist) {
+		// The cache is created by the first download
+		dir, err := cache.DefaultDir()
+		if err != nil {
+			return err
+		}
+		fmt.Println(dir)
+		fmt.Fprintln(os.Stderr, "The cache doesn't exist yet.")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println(dlCache.Dir())
+	return nil
+}
+
+type CacheExportCmd struct {
+	File string `arg:"" name:"file" usage:"path of the tarball to write, like crx-cache.tar.gz"`
+}
+
+func (c *CacheExportCmd) Run() error {
+	dlCache, err := cache.New()
+	if err != nil {
+		return err
+	}
+
+	// Write the tarball next to its destination, and move it there once it's complete
+	tempFile, err := os.CreateTemp(filepath.Dir(c.File), ".crx-cache-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile.Name())
+	entries, err := dlCache.Export(tempFile)
+	if err != nil {
+		tempFile.Close()
+		return err
+	}
+	if err := tempFile.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tempFile.Name(), c.File); err != nil {
+		return err
+	}
+
+	var size int64
+	for _, entry := range entries {
+		size += entry.Size
+	}
+	fmt.Printf("Exported %d files (%s) to %s\n", len(entries), project.Size(size), c.File)
+	return nil
+}
+
+type CacheImportCmd struct {
+	File string `arg:"" name:"file" usage:"path of the tarball to read" type:"existingfile"`
+}
+
+func (c *CacheImportCmd) Run() error {
+	// The configured maximum size applies to the imported files
+	if err := loadConfig(); err != nil {
+		return err
+	}
+	dlCache, err := cache.New()
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(c.File)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	entries, err := dlCache.Import(file)
+	for _, entry := range entries {
+		fmt.Printf("Imported %s\n", entry.Key)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d files to %s\n", len(entries), dlCache.Dir())
+	return nil
+}
+
+// valueOrDash returns a value, or "-" if it's empty.
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+
+	"github.com/customrealms/cli/pkg/doctor"
+)
+
+type DoctorCmd struct {
+	ProjectDir string `name:"project" short:"p" usage:"plugin project directory" optional:""`
+	McVersion  string `name:"mc" usage:"Minecraft version number target" optional:""`
+	Java       string `name:"java" usage:"java command or Java installation directory to check (default: detected)" optional:""`
+	Offline    bool   `name:"offline" usage:"skip the network checks"`
+}
+
+func (c *DoctorCmd) Run() error {
+	// Root context for the CLI
+	ctx, cancel := rootContext()
+	defer cancel()
+
+	// Default to the current working directory
+	if c.ProjectDir == "" {
+		c.ProjectDir, _ = os.Getwd()
+	}
+
+	// Use the configured mirrors. An invalid config file is reported by the checks.
+	_ = loadConfig()
+
+	// Run the checks and print the report
+	report := doctor.Run(ctx, &doctor.Options{
+		Dir:              c.ProjectDir,
+		MinecraftVersion: c.McVersion,
+		Java:             c.Java,
+		Offline:          c.Offline,
+	})
+	report.Print(os.Stdout)
+	return report.Err()
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/customrealms/cli/pkg/build"
+	"github.com/customrealms/cli/pkg/cache"
+	"github.com/customrealms/cli/pkg/initialize/template"
+	"github.com/customrealms/cli/pkg/minecraft"
+	"github.com/customrealms/cli/pkg/server"
+)
+
+type FetchCmd struct {
+	McVersions []string `name:"mc" usage:"Minecraft versions to fetch the server JARs of, comma-separated or repeated (default: the default version)" optional:""`
+	Templates  []string `name:"template" usage:"templates to fetch, from the registry or any template source, comma-separated or repeated (default: the default template)" optional:""`
+	Registry   string   `name:"registry" usage:"template registry file or URL (default: the registry shipped with the CLI)" env:"CRX_TEMPLATE_REGISTRY" optional:""`
+}
+
+func (c *FetchCmd) Run() error {
+	// Root context for the CLI
+	ctx, cancel := rootContext()
+	defer cancel()
+	if err := loadConfig(); err != nil {
+		return err
+	}
+
+	// Fetch the version metadata and the server JARs
+	if len(c.McVersions) == 0 {
+		c.McVersions = []string{minecraft.DefaultVersion}
+	}
+	serverJarFetcher, err := server.NewCachedFetcher(&server.HttpFetcher{})
+	if err != nil {
+		return err
+	}
+	for _, versionString := range c.McVersions {
+		minecraftVersion, err := resolveMinecraftVersion(ctx, versionString)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Fetching the server JAR for Minecraft %s\n", minecraftVersion)
+		jar, err := serverJarFetcher.Fetch(ctx, minecraftVersion)
+		if err != nil {
+			return fmt.Errorf("fetching the server JAR for Minecraft %s: %w", minecraftVersion, err)
+		}
+		jar.Close()
+	}
+
+	// Fetch the runtime JAR
+	fmt.Println("Fetching the runtime JAR")
+	jarTemplate := &build.GitHubJarTemplate{}
+	jar, err := jarTemplate.Jar(ctx)
+	if err != nil {
+		return fmt.Errorf("fetching the runtime JAR: %w", err)
+	}
+	jar.Close()
+
+	// Fetch the templates
+	registry, err := template.LoadRegistry(ctx, c.Registry)
+	if err != nil {
+		return err
+	}
+	if len(c.Templates) == 0 {
+		c.Templates = []string{template.DefaultSource}
+	}
+	for _, name := range c.Templates {
+		source := registry.Resolve(name)
+		fmt.Printf("Fetching the template %s\n", source)
+		if _, err := template.NewFromSource(ctx, source); err != nil {
+			return fmt.Errorf("fetching the template %s: %w", source, err)
+		}
+	}
+
+	// Show where everything went
+	dlCache, err := cache.New()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Done. The files are cached in %s\n", dlCache.Dir())
+	return nil
+}
@@ -1,31 +1,106 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/customrealms/cli/pkg/download"
 	"github.com/customrealms/cli/pkg/initialize"
+	"github.com/customrealms/cli/pkg/initialize/template"
+	"github.com/customrealms/cli/pkg/project"
 )
 
 type InitCmd struct {
-	ProjectDir string `name:"project" short:"p" usage:"plugin project directory" optional:""`
+	ProjectDir     string            `name:"project" short:"p" usage:"plugin project directory" optional:""`
+	Template       string            `name:"template" usage:"template name from the registry, directory, zip file, zip URL or github:<org>/<repo>#<ref>" optional:""`
+	Registry       string            `name:"registry" usage:"template registry file or URL (default: the registry shipped with the CLI)" env:"CRX_TEMPLATE_REGISTRY" optional:""`
+	Vars           map[string]string `name:"var" usage:"value of a template variable as key=value, can be repeated" optional:""`
+	Yes            bool              `name:"yes" short:"y" usage:"use the default values of template variables instead of asking"`
+	PackageManager *string           `name:"package-manager" enum:"npm,yarn,pnpm,bun" usage:"package manager to install dependencies with: npm, yarn, pnpm or bun (default: detected)" optional:""`
+	DryRun         bool              `name:"dry-run" usage:"list the files that would be written, without writing anything"`
+	Force          bool              `name:"force" usage:"initialize a non-empty directory, overwriting the existing files" xor:"existing"`
+	Merge          bool              `name:"merge" usage:"initialize a non-empty directory, keeping the existing files" xor:"existing"`
 }
 
 func (c *InitCmd) Run() error {
 	// Root context for the CLI
 	ctx, cancel := rootContext()
 	defer cancel()
+	if err := loadConfig(); err != nil {
+		return err
+	}
 
 	// Default to the current working directory
 	if c.ProjectDir == "" {
 		c.ProjectDir, _ = os.Getwd()
 	}
 
+	// Use the package manager from the flag, or detect it
+	var packageManager project.PackageManager
+	if c.PackageManager != nil {
+		packageManager = project.PackageManager(*c.PackageManager)
+	}
+
+	// Load the template
+	var tmpl template.Template
+	var source string
+	if c.Template != "" {
+		registry, err := template.LoadRegistry(ctx, c.Registry)
+		if err != nil {
+			return err
+		}
+		source = registry.Resolve(c.Template)
+		if tmpl, err = template.NewFromSource(ctx, source); err != nil {
+			return fmt.Errorf("loading template: %w", err)
+		}
+	}
+
+	// Ask for the template variables, unless the user opted out or can't answer
+	var prompter template.Prompter
+	if !c.Yes && download.IsTerminal(os.Stdin) {
+		prompter = template.NewLinePrompter(os.Stdin, os.Stdout)
+	}
+
+	// Decide what happens to the files already in the directory
+	existing := template.ExistingFail
+	if c.Force {
+		existing = template.ExistingOverwrite
+	} else if c.Merge {
+		existing = template.ExistingKeep
+	}
+
 	// Create the init runner
 	initAction := initialize.InitAction{
-		Name:     filepath.Base(c.ProjectDir),
-		Dir:      c.ProjectDir,
-		Template: nil,
+		Name:           filepath.Base(c.ProjectDir),
+		Dir:            c.ProjectDir,
+		Template:       tmpl,
+		Source:         recordedSource(source),
+		Vars:           c.Vars,
+		Prompter:       prompter,
+		PackageManager: packageManager,
+		Existing:       existing,
+		DryRun:         c.DryRun,
+	}
+	if err := initAction.Run(ctx); err != nil {
+		if errors.Is(err, template.ErrDirNotEmpty) {
+			return fmt.Errorf("%w, use --merge to keep the existing files or --force to overwrite them", err)
+		}
+		return err
+	}
+	return nil
+}
+
+// recordedSource returns the template source to record in the project. Local paths are
+// made absolute, so they still work from the project directory.
+func recordedSource(source string) string {
+	if source == "" || strings.Contains(source, ":") {
+		return source
+	}
+	if abs, err := filepath.Abs(source); err == nil {
+		return abs
 	}
-	return initAction.Run(ctx)
+	return source
 }
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/customrealms/cli/pkg/build"
+	"github.com/customrealms/cli/pkg/project"
+	"gopkg.in/yaml.v3"
+)
+
+type InspectCmd struct {
+	JarFile   string `arg:"" name:"jar" usage:"plugin JAR file to inspect" type:"existingfile"`
+	JSON      bool   `name:"json" usage:"print the JAR contents as JSON"`
+	ExtractJS string `name:"extract-js" short:"x" usage:"write the bundled plugin.js to this file ('-' for stdout)" optional:""`
+}
+
+func (c *InspectCmd) Run() error {
+	// Open the JAR file
+	zr, err := zip.OpenReader(c.JarFile)
+	if err != nil {
+		return fmt.Errorf("opening JAR file: %w", err)
+	}
+	defer zr.Close()
+
+	// Extract the plugin code, if requested
+	if c.ExtractJS != "" {
+		return c.extractJS(&zr.Reader)
+	}
+
+	// Read the contents of the JAR
+	info, err := build.InspectJar(&zr.Reader)
+	if err != nil {
+		return err
+	}
+
+	// Print the contents as JSON
+	if c.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(info)
+	}
+
+	// Print the plugin.yml file
+	printHeader("plugin.yml")
+	enc := yaml.NewEncoder(os.Stdout)
+	enc.SetIndent(2)
+	if err := enc.Encode(info.Plugin); err != nil {
+		return fmt.Errorf("encoding plugin.yml: %w", err)
+	}
+	fmt.Println()
+
+	// Print the runtime and bundle details
+	printHeader("Runtime")
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Main class:\t%s\n", info.Runtime.MainClass)
+	fmt.Fprintf(tw, "Runtime version:\t%s\n", valueOrNone(info.Runtime.Version))
+	fmt.Fprintf(tw, "Runtime SHA-256:\t%s\n", valueOrNone(info.Runtime.Sha256))
+	fmt.Fprintf(tw, "Build JDK:\t%s\n", valueOrNone(info.Runtime.BuildJdkSpec))
+	fmt.Fprintf(tw, "Bundle size:\t%s\n", project.Size(info.BundleSize))
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Println()
+
+	// Print the build metadata
+	printHeader("Build")
+	if metadata := info.Metadata; metadata != nil {
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "CLI version:\t%s\n", metadata.CliVersion)
+		fmt.Fprintf(tw, "Minecraft API version:\t%s\n", valueOrNone(metadata.ApiVersion))
+		if metadata.Git != nil {
+			commit := metadata.Git.Commit
+			if metadata.Git.Dirty {
+				commit += " (dirty)"
+			}
+			fmt.Fprintf(tw, "Git commit:\t%s\n", commit)
+		} else {
+			fmt.Fprintf(tw, "Git commit:\t(none)\n")
+		}
+		fmt.Fprintf(tw, "Built by:\t%s\n", valueOrNone(metadata.BuiltBy))
+		fmt.Fprintf(tw, "Build time:\t%s\n", metadata.BuildTime.Format(time.RFC3339))
+		fmt.Fprintf(tw, "Bundle SHA-256:\t%s\n", metadata.BundleSha256)
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	} else {
+		fmt.Println("No build metadata found. The JAR was built with an older version of crx.")
+	}
+	fmt.Println()
+
+	// Print the resources copied from the runtime
+	printHeader(fmt.Sprintf("Resources (%d)", len(info.Resources)))
+	tw = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	for _, resource := range info.Resources {
+		fmt.Fprintf(tw, "%s\t  %s\n", project.Size(resource.Size), resource.Name)
+	}
+	return tw.Flush()
+}
+
+func (c *InspectCmd) extractJS(zr *zip.Reader) error {
+	var w io.Writer = os.Stdout
+	if c.ExtractJS != "-" {
+		file, err := os.Create(c.ExtractJS)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	return build.ExtractPluginJS(zr, w)
+}
+
+func printHeader(title string) {
+	fmt.Println("============================================================")
+	fmt.Println(title)
+	fmt.Println("============================================================")
+}
+
+func valueOrNone(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return value
+}
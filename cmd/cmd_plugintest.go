@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/customrealms/cli/pkg/download"
+	"github.com/customrealms/cli/pkg/errdefs"
+	"github.com/customrealms/cli/pkg/plugintest"
+	"github.com/customrealms/cli/pkg/project"
+	"github.com/customrealms/cli/pkg/serve"
+	"github.com/customrealms/cli/pkg/server"
+	"github.com/customrealms/cli/pkg/serverlog"
+)
+
+type TestCmd struct {
+	ProjectDir      string        `name:"project" short:"p" usage:"plugin project directory" optional:""`
+	McVersion       string        `name:"mc" usage:"Minecraft version number target" optional:""`
+	TemplateJarFile string        `name:"jar" short:"t" usage:"template JAR file" optional:""`
+	Targets         []string      `name:"target" usage:"project target to test, can be repeated (default: all targets)" optional:""`
+	Filters         []string      `name:"filter" usage:"workspace packages to test, by name or by directory like ./plugins/*, can be repeated" optional:""`
+	Java            string        `name:"java" usage:"java command or Java installation directory to run the server with (default: detected)" optional:""`
+	JUnit           string        `name:"junit" usage:"write a JUnit XML report to this file" optional:""`
+	Timeout         time.Duration `name:"timeout" usage:"how long the server may take to start (default: the project's test.timeout, or 3m)" optional:""`
+	Verbose         bool          `name:"verbose" short:"v" usage:"show the console output of the server"`
+}
+
+func (c *TestCmd) Run() error {
+	// Root context for the CLI
+	ctx, cancel := rootContext()
+	defer cancel()
+	if err := loadConfig(); err != nil {
+		return err
+	}
+
+	// Default to the current working directory
+	if c.ProjectDir == "" {
+		c.ProjectDir, _ = os.Getwd()
+	}
+
+	// Find the projects to test
+	projects, _, err := selectProjects(c.ProjectDir, c.Filters)
+	if err != nil {
+		return err
+	}
+	if len(projects) > 1 && len(c.Targets) > 0 {
+		return errors.New("targets can only be selected when testing a single package, use --filter to select it")
+	}
+
+	// Read the test configuration of the projects. The commands of all the projects are run,
+	// and the server gets the longest start timeout.
+	var commands []project.TestCommand
+	startTimeout := c.Timeout
+	for _, crProject := range projects {
+		config, err := crProject.Config()
+		if err != nil {
+			return err
+		}
+		commands = append(commands, config.Test.Commands...)
+		if c.Timeout == 0 && time.Duration(config.Test.Timeout) > startTimeout {
+			startTimeout = time.Duration(config.Test.Timeout)
+		}
+	}
+
+	// Get the Minecraft version
+	minecraftVersion, err := resolveMinecraftVersion(ctx, c.McVersion)
+	if err != nil {
+		return err
+	}
+
+	// Create a temp directory for the plugin JAR files
+	outputDir, err := os.MkdirTemp("", "cr-jar-output-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(outputDir)
+
+	// Build the plugins
+	buildActions, pluginJarPaths, err := buildPlugins(ctx, projects, minecraftVersion, c.TemplateJarFile, c.Targets, outputDir)
+	if err != nil {
+		return err
+	}
+
+	// Find the names of the plugins, to check that they're enabled
+	plugins, err := pluginsOf(ctx, buildActions, pluginJarPaths)
+	if err != nil {
+		return err
+	}
+
+	// Create a fetcher for the Minecraft server JAR file that caches the files locally
+	serverJarFetcher, err := server.NewCachedFetcher(&server.HttpFetcher{})
+	if err != nil {
+		return err
+	}
+
+	// Run the test
+	testAction := plugintest.TestAction{
+		Serve: &serve.ServeAction{
+			MinecraftVersion: minecraftVersion,
+			PluginJarPaths:   pluginJarPaths,
+			ServerJarFetcher: serverJarFetcher,
+			Java:             c.Java,
+		},
+		Plugins:      plugins,
+		Commands:     commands,
+		StartTimeout: startTimeout,
+	}
+	if c.Verbose {
+		logWriter := serverlog.NewWriter(os.Stdout, serverlog.Options{
+			Plugins: plugins,
+			Color:   download.IsTerminal(os.Stdout) && os.Getenv("NO_COLOR") == "",
+		})
+		testAction.Output = logWriter
+		defer logWriter.Close()
+	}
+	report, err := testAction.Run(ctx)
+	if err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		// The test was interrupted, so the report is incomplete
+		return ctx.Err()
+	}
+	fmt.Println()
+	report.Print(os.Stdout)
+
+	// Write the JUnit report
+	if c.JUnit != "" {
+		if err := writeJUnit(c.JUnit, report); err != nil {
+			return fmt.Errorf("writing JUnit report: %w", err)
+		}
+	}
+	if report.Failures() > 0 {
+		return errdefs.TestFailed(fmt.Errorf("%d of %d tests failed", report.Failures(), len(report.Cases)))
+	}
+	return nil
+}
+
+// writeJUnit writes the JUnit XML report of a test to a file.
+func writeJUnit(filename string, report *plugintest.Report) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := report.WriteJUnit(file); err != nil {
+		return err
+	}
+	return file.Close()
+}
@@ -1,70 +1,109 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/customrealms/cli/pkg/build"
+	"github.com/customrealms/cli/pkg/download"
+	"github.com/customrealms/cli/pkg/minecraft"
 	"github.com/customrealms/cli/pkg/project"
 	"github.com/customrealms/cli/pkg/serve"
 	"github.com/customrealms/cli/pkg/server"
+	"github.com/customrealms/cli/pkg/serverlog"
 	"github.com/fsnotify/fsnotify"
 	"golang.org/x/sync/errgroup"
 )
 
 type RunCmd struct {
-	ProjectDir      string `name:"project" short:"p" usage:"plugin project directory" optional:""`
-	McVersion       string `name:"mc" usage:"Minecraft version number target" optional:""`
-	TemplateJarFile string `name:"jar" short:"t" usage:"template JAR file" optional:""`
+	ProjectDir      string   `name:"project" short:"p" usage:"plugin project directory" optional:""`
+	McVersion       string   `name:"mc" usage:"Minecraft version number target" optional:""`
+	TemplateJarFile string   `name:"jar" short:"t" usage:"template JAR file" optional:""`
+	Targets         []string `name:"target" usage:"project target to run, can be repeated (default: all targets)" optional:""`
+	Filters         []string `name:"filter" usage:"workspace packages to run, by name or by directory like ./plugins/*, can be repeated" optional:""`
+	Java            string   `name:"java" usage:"java command or Java installation directory to run the server with (default: detected)" optional:""`
+	LogLevel        string   `name:"log-level" usage:"minimum level of the server logs shown: trace, debug, info, warn or error (default: all)" optional:""`
+	LogFilter       string   `name:"log-filter" usage:"only show the server logs matching this regular expression" optional:""`
+	LogDir          string   `name:"log-dir" usage:"write the server logs of each session to a JSON lines file in this directory" optional:""`
 }
 
 func (c *RunCmd) Run() error {
 	// Root context for the CLI
 	ctx, cancel := rootContext()
 	defer cancel()
+	if err := loadConfig(); err != nil {
+		return err
+	}
 
 	// Default to the current working directory
 	if c.ProjectDir == "" {
 		c.ProjectDir, _ = os.Getwd()
 	}
 
-	// Get the Minecraft version
-	minecraftVersion := mustMinecraftVersion(ctx, c.McVersion)
-
-	// Generate a temp filename for the plugin JAR file
-	ofile, _ := os.CreateTemp("", "cr-jar-output-*.jar")
-	ofile.Close()
-	outputFile := ofile.Name()
-	defer os.Remove(outputFile)
+	// Find the projects to run
+	projects, _, err := selectProjects(c.ProjectDir, c.Filters)
+	if err != nil {
+		return err
+	}
+	if len(projects) > 1 && len(c.Targets) > 0 {
+		return errors.New("targets can only be selected when running a single package, use --filter to select it")
+	}
 
-	// Create the JAR template to build with
-	var jarTemplate build.JarTemplate
-	if len(c.TemplateJarFile) > 0 {
-		jarTemplate = &build.FileJarTemplate{
-			Filename: c.TemplateJarFile,
+	// Parse the log options before building anything
+	logOptions := serverlog.Options{Color: download.IsTerminal(os.Stdout) && os.Getenv("NO_COLOR") == ""}
+	if c.LogLevel != "" {
+		if logOptions.Level, err = serverlog.ParseLevel(c.LogLevel); err != nil {
+			return err
+		}
+	}
+	if c.LogFilter != "" {
+		if logOptions.Filter, err = regexp.Compile(c.LogFilter); err != nil {
+			return fmt.Errorf("invalid log filter: %w", err)
 		}
-	} else {
-		jarTemplate = &build.GitHubJarTemplate{}
 	}
 
-	// Create the project
-	crProject := project.New(c.ProjectDir)
+	// Get the Minecraft version
+	minecraftVersion, err := resolveMinecraftVersion(ctx, c.McVersion)
+	if err != nil {
+		return err
+	}
 
-	// Create the build action
-	buildAction := build.BuildAction{
-		Project:     crProject,
-		JarTemplate: jarTemplate,
-		ApiVersion:  minecraftVersion.ApiVersion(),
-		OutputFile:  outputFile,
+	// Create a temp directory for the plugin JAR files
+	outputDir, err := os.MkdirTemp("", "cr-jar-output-*")
+	if err != nil {
+		return err
 	}
+	defer os.RemoveAll(outputDir)
 
-	// Run the build action
-	if err := buildAction.Run(ctx); err != nil {
+	// Build the plugins
+	buildActions, pluginJarPaths, err := buildPlugins(ctx, projects, minecraftVersion, c.TemplateJarFile, c.Targets, outputDir)
+	if err != nil {
 		return err
 	}
 
+	// Highlight the errors of the plugins
+	if logOptions.Plugins, err = pluginsOf(ctx, buildActions, pluginJarPaths); err != nil {
+		return err
+	}
+
+	// Write the logs of the session to a file
+	if c.LogDir != "" {
+		logFile, err := createLogFile(c.LogDir)
+		if err != nil {
+			return err
+		}
+		defer logFile.Close()
+		fmt.Println("Writing the server logs to", logFile.Name())
+		logOptions.JSON = logFile
+	}
+
 	// Create a fetcher for the Minecraft server JAR file that caches the files locally
 	serverJarFetcher, err := server.NewCachedFetcher(&server.HttpFetcher{})
 	if err != nil {
@@ -92,8 +131,8 @@ func (c *RunCmd) Run() error {
 						return
 					}
 					if event.Has(fsnotify.Write) {
-						// Rebuild the plugin JAR file
-						if err := buildAction.Run(ctx); err != nil {
+						// Rebuild the plugin JAR files of the changed project
+						if err := rebuild(ctx, buildActions, event.Name); err != nil {
 							log.Println("Error: ", err)
 						} else {
 							select {
@@ -116,11 +155,15 @@ func (c *RunCmd) Run() error {
 			}
 		}()
 
-		// Add the project directory and src directory to the watcher
-		if err := errors.Join(
-			watcher.Add(c.ProjectDir),
-			watcher.Add(filepath.Join(c.ProjectDir, "src")),
-		); err != nil {
+		// Add the project directories and their src directories to the watcher
+		var errs []error
+		for _, buildAction := range buildActions {
+			errs = append(errs,
+				watcher.Add(buildAction.Project.Dir()),
+				watcher.Add(filepath.Join(buildAction.Project.Dir(), "src")),
+			)
+		}
+		if err := errors.Join(errs...); err != nil {
 			return err
 		}
 
@@ -135,13 +178,100 @@ func (c *RunCmd) Run() error {
 	eg.Go(func() error {
 		defer close(chanServerStopped)
 
+		// Show the console output of the server through the log parser
+		logWriter := serverlog.NewWriter(os.Stdout, logOptions)
+		defer logWriter.Close()
+
 		// Create the serve runner
 		serveAction := serve.ServeAction{
 			MinecraftVersion: minecraftVersion,
-			PluginJarPath:    outputFile,
+			PluginJarPaths:   pluginJarPaths,
 			ServerJarFetcher: serverJarFetcher,
+			Java:             c.Java,
+			Stdout:           logWriter,
 		}
 		return serveAction.Run(ctx, chanPluginUpdated)
 	})
 	return eg.Wait()
 }
+
+// buildPlugins builds the plugin JAR files of projects to an output directory, for a
+// Minecraft version. It returns the build actions, to rebuild the plugins, and the paths
+// of the JAR files.
+func buildPlugins(ctx context.Context, projects []project.Project, minecraftVersion minecraft.Version, templateJarFile string, targets []string, outputDir string) ([]*build.BuildAction, []string, error) {
+	// Create the JAR template to build with, shared by all the builds
+	var jarTemplate build.JarTemplate
+	if len(templateJarFile) > 0 {
+		jarTemplate = &build.FileJarTemplate{
+			Filename: templateJarFile,
+		}
+	} else {
+		jarTemplate = &build.GitHubJarTemplate{}
+	}
+	jarTemplate = build.NewOnceJarTemplate(jarTemplate)
+
+	// Create the build actions
+	buildActions := make([]*build.BuildAction, 0, len(projects))
+	var pluginJarPaths []string
+	for _, crProject := range projects {
+		buildAction := &build.BuildAction{
+			Project:     crProject,
+			JarTemplate: jarTemplate,
+			ApiVersion:  minecraftVersion.ApiVersion(),
+			CliVersion:  version,
+			Targets:     targets,
+			OutputDir:   outputDir,
+		}
+		outputFiles, err := buildAction.OutputFiles(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		buildActions = append(buildActions, buildAction)
+		pluginJarPaths = append(pluginJarPaths, outputFiles...)
+	}
+
+	// Run the build actions
+	for _, buildAction := range buildActions {
+		if err := buildAction.Run(ctx); err != nil {
+			return nil, nil, err
+		}
+	}
+	return buildActions, pluginJarPaths, nil
+}
+
+// pluginsOf returns the names and the JAR files of the plugins built by build actions.
+func pluginsOf(ctx context.Context, buildActions []*build.BuildAction, pluginJarPaths []string) ([]serverlog.Plugin, error) {
+	var plugins []serverlog.Plugin
+	for _, buildAction := range buildActions {
+		pluginYmls, err := buildAction.Plugins(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, pluginYml := range pluginYmls {
+			plugins = append(plugins, serverlog.Plugin{
+				Name:    pluginYml.Name,
+				JarName: filepath.Base(pluginJarPaths[len(plugins)]),
+			})
+		}
+	}
+	return plugins, nil
+}
+
+// createLogFile creates the log file of a session in a directory, named after the time.
+func createLogFile(dir string) (*os.File, error) {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, err
+	}
+	return os.Create(filepath.Join(dir, "crx-run-"+time.Now().Format("20060102-150405")+".jsonl"))
+}
+
+// rebuild runs the build action of the project containing a changed file.
+func rebuild(ctx context.Context, buildActions []*build.BuildAction, filename string) error {
+	for _, buildAction := range buildActions {
+		rel, err := filepath.Rel(buildAction.Project.Dir(), filename)
+		if err == nil && !strings.HasPrefix(rel, "..") {
+			return buildAction.Run(ctx)
+		}
+	}
+	return nil
+}
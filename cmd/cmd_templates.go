@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/customrealms/cli/pkg/cache"
+	"github.com/customrealms/cli/pkg/initialize/template"
+)
+
+type TemplatesCmd struct {
+	Registry string `name:"registry" usage:"template registry file or URL (default: the registry shipped with the CLI)" env:"CRX_TEMPLATE_REGISTRY" optional:""`
+
+	List   TemplatesListCmd   `cmd:"" name:"list" help:"List the templates in the registry."`
+	Search TemplatesSearchCmd `cmd:"" name:"search" help:"Search the templates in the registry."`
+	Info   TemplatesInfoCmd   `cmd:"" name:"info" help:"Show the details of a template."`
+}
+
+type TemplatesListCmd struct{}
+
+func (c *TemplatesListCmd) Run(parent *TemplatesCmd) error {
+	ctx, cancel := rootContext()
+	defer cancel()
+	if err := loadConfig(); err != nil {
+		return err
+	}
+
+	registry, err := template.LoadRegistry(ctx, parent.Registry)
+	if err != nil {
+		return err
+	}
+	return printTemplates(registry.Templates)
+}
+
+type TemplatesSearchCmd struct {
+	Query string `arg:"" name:"query" usage:"text to find in the template names, descriptions and tags"`
+}
+
+func (c *TemplatesSearchCmd) Run(parent *TemplatesCmd) error {
+	ctx, cancel := rootContext()
+	defer cancel()
+	if err := loadConfig(); err != nil {
+		return err
+	}
+
+	registry, err := template.LoadRegistry(ctx, parent.Registry)
+	if err != nil {
+		return err
+	}
+	results := registry.Search(c.Query)
+	if len(results) == 0 {
+		fmt.Printf("No templates match %q.\n", c.Query)
+		return nil
+	}
+	return printTemplates(results)
+}
+
+type TemplatesInfoCmd struct {
+	Name string `arg:"" name:"name" usage:"name of the template"`
+}
+
+func (c *TemplatesInfoCmd) Run(parent *TemplatesCmd) error {
+	ctx, cancel := rootContext()
+	defer cancel()
+	if err := loadConfig(); err != nil {
+		return err
+	}
+
+	registry, err := template.LoadRegistry(ctx, parent.Registry)
+	if err != nil {
+		return err
+	}
+	entry := registry.Lookup(c.Name)
+	if entry == nil {
+		return fmt.Errorf("no template named %q in the registry", c.Name)
+	}
+
+	// Check if the template archive is cached
+	cached := "no"
+	archiveURL, err := template.ArchiveURL(entry.Source)
+	if err != nil {
+		return err
+	}
+	if archiveURL == "" {
+		cached = "local template"
+	} else if archiveCache, err := cache.Existing(); err == nil && archiveCache.Has(template.ArchiveCacheKey(archiveURL)) {
+		cached = "yes"
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Name:\t%s\n", entry.Name)
+	fmt.Fprintf(tw, "Description:\t%s\n", valueOrNone(entry.Description))
+	fmt.Fprintf(tw, "Source:\t%s\n", entry.Source)
+	fmt.Fprintf(tw, "Tags:\t%s\n", valueOrNone(strings.Join(entry.Tags, ", ")))
+	fmt.Fprintf(tw, "Cached:\t%s\n", cached)
+	return tw.Flush()
+}
+
+// printTemplates prints a table of templates.
+func printTemplates(entries []template.RegistryEntry) error {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tDESCRIPTION\tSOURCE")
+	for _, entry := range entries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", entry.Name, entry.Description, entry.Source)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/customrealms/cli/pkg/download"
+	"github.com/customrealms/cli/pkg/initialize"
+	"github.com/customrealms/cli/pkg/initialize/template"
+)
+
+type UpgradeCmd struct {
+	ProjectDir string            `name:"project" short:"p" usage:"plugin project directory" optional:""`
+	Template   string            `name:"template" usage:"template to upgrade to, instead of the one the project was created from" optional:""`
+	Registry   string            `name:"registry" usage:"template registry file or URL (default: the registry shipped with the CLI)" env:"CRX_TEMPLATE_REGISTRY" optional:""`
+	Vars       map[string]string `name:"var" usage:"value of a template variable as key=value, can be repeated" optional:""`
+	Yes        bool              `name:"yes" short:"y" usage:"apply the changes without asking"`
+	DryRun     bool              `name:"dry-run" usage:"show the changes without applying them"`
+}
+
+func (c *UpgradeCmd) Run() error {
+	// Root context for the CLI
+	ctx, cancel := rootContext()
+	defer cancel()
+	if err := loadConfig(); err != nil {
+		return err
+	}
+
+	// Default to the current working directory
+	if c.ProjectDir == "" {
+		c.ProjectDir, _ = os.Getwd()
+	}
+
+	// Resolve the new template source
+	var source string
+	if c.Template != "" {
+		registry, err := template.LoadRegistry(ctx, c.Registry)
+		if err != nil {
+			return err
+		}
+		source = recordedSource(registry.Resolve(c.Template))
+	}
+
+	// Ask for new template variables, unless the user opted out or can't answer
+	interactive := !c.Yes && download.IsTerminal(os.Stdin)
+	var prompter template.Prompter
+	if interactive {
+		prompter = template.NewLinePrompter(os.Stdin, os.Stdout)
+	}
+
+	// Compute the changes
+	upgradeAction := initialize.UpgradeAction{
+		Dir:      c.ProjectDir,
+		Source:   source,
+		Vars:     c.Vars,
+		Prompter: prompter,
+	}
+	upgrade, err := upgradeAction.Plan(ctx)
+	if err != nil {
+		return err
+	}
+	if len(upgrade.Changes) == 0 {
+		fmt.Println("The project is up to date with its template.")
+		return nil
+	}
+
+	// Show the changes
+	upgrade.Print(os.Stdout, true)
+	fmt.Println()
+	if conflicts := upgrade.Conflicts(); conflicts > 0 {
+		fmt.Printf("%d files have conflicts, marked with %q and %q.\n", conflicts, "<<<<<<<", ">>>>>>>")
+	}
+	if c.DryRun {
+		return nil
+	}
+
+	// Confirm the changes
+	if !c.Yes {
+		if !interactive {
+			return fmt.Errorf("not applying the changes, run with --yes to apply them")
+		}
+		fmt.Print("Apply these changes? [y/N]: ")
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+			fmt.Println("Not applying the changes.")
+			return nil
+		}
+	}
+
+	// Write the changes
+	if err := upgrade.Apply(); err != nil {
+		return err
+	}
+	fmt.Println("Upgraded the project. Review the changes, and install the dependencies if package.json changed.")
+	return nil
+}
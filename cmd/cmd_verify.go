@@ -0,0 +1,92 @@
+package main
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/customrealms/cli/pkg/build"
+	"github.com/customrealms/cli/pkg/jarsign"
+)
+
+type VerifyCmd struct {
+	JarFile       string `arg:"" name:"jar" usage:"plugin JAR file to verify" type:"existingfile"`
+	CertFile      string `name:"cert" usage:"PEM certificate the JAR file must be signed with" optional:""`
+	AllowUnsigned bool   `name:"allow-unsigned" usage:"only verify checksums if the JAR file isn't signed"`
+}
+
+func (c *VerifyCmd) Run() error {
+	// Check the checksum files next to the JAR
+	checked, err := build.VerifyChecksumFiles(c.JarFile)
+	if err != nil {
+		return err
+	}
+	for _, checksumFile := range checked {
+		fmt.Printf(" -> Checksum OK: %s\n", filepath.Base(checksumFile))
+	}
+	if len(checked) == 0 {
+		fmt.Println(" -> No checksum files found")
+	}
+
+	// Open the JAR file
+	zr, err := zip.OpenReader(c.JarFile)
+	if err != nil {
+		return fmt.Errorf("opening JAR file: %w", err)
+	}
+	defer zr.Close()
+
+	// Verify the signatures
+	signatures, err := jarsign.Verify(&zr.Reader)
+	if errors.Is(err, jarsign.ErrNotSigned) && c.AllowUnsigned && c.CertFile == "" {
+		fmt.Println(" -> JAR file is not signed")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, signature := range signatures {
+		cert := signature.Chain[0]
+		fmt.Printf(" -> Signature OK: %s\n", signature.Name)
+		fmt.Printf("      Subject:     %s\n", cert.Subject)
+		fmt.Printf("      Issuer:      %s\n", cert.Issuer)
+		fmt.Printf("      Valid until: %s\n", cert.NotAfter.Format("2006-01-02"))
+		fmt.Printf("      SHA-256:     %s\n", certFingerprint(cert))
+	}
+
+	// Check that the JAR is signed with the expected certificate
+	if c.CertFile != "" {
+		expected, err := readCertificate(c.CertFile)
+		if err != nil {
+			return err
+		}
+		for _, signature := range signatures {
+			if signature.Chain[0].Equal(expected) {
+				return nil
+			}
+		}
+		return fmt.Errorf("JAR file is not signed with the certificate in %s", c.CertFile)
+	}
+	return nil
+}
+
+func readCertificate(filename string) (*x509.Certificate, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading certificate: %w", err)
+	}
+	certs, err := jarsign.ParseCertificates(data)
+	if err != nil {
+		return nil, err
+	}
+	return certs[0], nil
+}
+
+func certFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
@@ -12,9 +12,14 @@ import (
 type YmlCmd struct {
 	ProjectDir string `name:"project" short:"p" usage:"plugin project directory" optional:""`
 	ApiVersion string `name:"mc" usage:"Minecraft version number target" optional:""`
+	Target     string `name:"target" usage:"project target to generate the plugin.yml file for" optional:""`
 }
 
 func (c *YmlCmd) Run() error {
+	// Root context for the CLI
+	ctx, cancel := rootContext()
+	defer cancel()
+
 	// Default to the current working directory
 	if c.ProjectDir == "" {
 		c.ProjectDir, _ = os.Getwd()
@@ -23,8 +28,20 @@ func (c *YmlCmd) Run() error {
 	// Create the project
 	crProject := project.New(c.ProjectDir)
 
+	// Find the target
+	var target *project.Target
+	if c.Target != "" {
+		config, err := crProject.Config()
+		if err != nil {
+			return err
+		}
+		if target = config.Target(c.Target); target == nil {
+			return fmt.Errorf("unknown target %q", c.Target)
+		}
+	}
+
 	// Generate the plugin.yml file
-	pluginYML, err := build.GeneratePluginYML(crProject, c.ApiVersion)
+	pluginYML, err := build.GenerateTargetPluginYML(ctx, crProject, target, c.ApiVersion)
 	if err != nil {
 		return fmt.Errorf("generating plugin.yml: %w", err)
 	}
@@ -2,12 +2,19 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"github.com/alecthomas/kong"
+	"github.com/customrealms/cli/pkg/build"
+	"github.com/customrealms/cli/pkg/cache"
+	"github.com/customrealms/cli/pkg/config"
+	"github.com/customrealms/cli/pkg/download"
+	"github.com/customrealms/cli/pkg/errdefs"
+	"github.com/customrealms/cli/pkg/initialize/template"
 	"github.com/customrealms/cli/pkg/minecraft"
 )
 
@@ -20,9 +27,18 @@ var (
 var cli struct {
 	VersionCmd VersionCmd `cmd:"" name:"version" help:"Show the version of the CLI."`
 	InitCmd    InitCmd    `cmd:"" name:"init" help:"Initialize a new plugin project."`
+	UpgradeCmd UpgradeCmd `cmd:"" name:"upgrade" help:"Apply the changes made to the project's template since it was created."`
 	BuildCmd   BuildCmd   `cmd:"" name:"build" help:"Build the plugin JAR file."`
 	RunCmd     RunCmd     `cmd:"" name:"run" help:"Build and serve the plugin in a Minecraft server."`
+	TestCmd    TestCmd    `cmd:"" name:"test" help:"Build the plugin, and check that it works in a headless Minecraft server."`
 	YmlCmd     YmlCmd     `cmd:"" name:"yml" help:"Generate the plugin.yml file."`
+	InspectCmd InspectCmd `cmd:"" name:"inspect" help:"Show the contents of a plugin JAR file."`
+	VerifyCmd  VerifyCmd  `cmd:"" name:"verify" help:"Verify the signature and checksums of a plugin JAR file."`
+	DoctorCmd  DoctorCmd  `cmd:"" name:"doctor" help:"Check that the machine and the project are set up correctly."`
+	FetchCmd   FetchCmd   `cmd:"" name:"fetch" help:"Download and cache the files needed to build and run plugins offline."`
+	CacheCmd   CacheCmd   `cmd:"" name:"cache" help:"Manage the cache of downloaded files."`
+
+	TemplatesCmd TemplatesCmd `cmd:"" name:"templates" help:"Find templates for new plugin projects."`
 }
 
 func rootContext() (context.Context, context.CancelFunc) {
@@ -30,22 +46,94 @@ func rootContext() (context.Context, context.CancelFunc) {
 	return signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
 }
 
+// Exit codes of the CLI, documented in the README.
+const (
+	exitError        = 1
+	exitUsage        = 2
+	exitPrerequisite = 3
+	exitNetwork      = 4
+	exitTemplate     = 5
+	exitBuild        = 6
+	exitTestFailed   = 7
+	exitInterrupted  = 130
+)
+
 func main() {
-	ctx := kong.Parse(&cli)
-	err := ctx.Run()
-	ctx.FatalIfErrorf(err)
+	parser, err := kong.New(&cli)
+	if err != nil {
+		panic(err)
+	}
+	ctx, err := parser.Parse(os.Args[1:])
+	if err != nil {
+		parser.Errorf("%s", err)
+		os.Exit(exitUsage)
+	}
+	if err := ctx.Run(); err != nil {
+		parser.Errorf("%s", err)
+		os.Exit(exitCode(err))
+	}
+}
+
+// mirrors are the config names of the upstream servers that can be mirrored, with their
+// base URLs.
+var mirrors = map[string]string{
+	config.MirrorPaperMC: minecraft.PaperMCURL,
+	config.MirrorRuntime: build.RuntimeURL,
+	config.MirrorGitHub:  template.GitHubURL,
+}
+
+// loadConfig loads the user configuration for the commands that download files: it points
+// the downloads to the configured mirrors, turns them off in offline mode, and limits the
+// size of the cache. Other commands don't load it, so they work with an invalid config file.
+func loadConfig() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return errdefs.Config(err)
+	}
+	download.Default.Mirrors = make(map[string]string)
+	for name, upstream := range mirrors {
+		if mirror := cfg.MirrorURL(name); mirror != "" {
+			download.Default.Mirrors[upstream] = mirror
+		}
+	}
+	cache.DefaultMaxSize = int64(cfg.CacheMaxSize)
+	download.Default.Offline = cfg.Offline
+	return nil
+}
+
+// exitCode returns the exit code for an error, depending on its kind. When an error has
+// several kinds, the cause of the failure comes first: e.g. a template that couldn't be
+// downloaded is a network error.
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return exitInterrupted
+	case errors.Is(err, errdefs.ErrConfig):
+		return exitUsage
+	case errors.Is(err, errdefs.ErrPrerequisite):
+		return exitPrerequisite
+	case errors.Is(err, errdefs.ErrNetwork):
+		return exitNetwork
+	case errors.Is(err, errdefs.ErrTemplate):
+		return exitTemplate
+	case errors.Is(err, errdefs.ErrBuild):
+		return exitBuild
+	case errors.Is(err, errdefs.ErrTestFailed):
+		return exitTestFailed
+	default:
+		return exitError
+	}
 }
 
-// mustMinecraftVersion takes a user-supplied Minecraft version string and resolves the corresponding minecraft.Version
-// instance. If nothing can be found, it exits the process
-func mustMinecraftVersion(ctx context.Context, versionString string) minecraft.Version {
+// resolveMinecraftVersion takes a user-supplied Minecraft version string and resolves the
+// corresponding minecraft.Version instance.
+func resolveMinecraftVersion(ctx context.Context, versionString string) (minecraft.Version, error) {
 	if len(versionString) == 0 {
-		versionString = "26.1.2"
+		versionString = minecraft.DefaultVersion
 	}
 	minecraftVersion, err := minecraft.LookupVersion(ctx, versionString)
 	if err != nil {
-		fmt.Println("Failed to resolve the Minecraft version: ", err)
-		os.Exit(1)
+		return nil, fmt.Errorf("failed to resolve the Minecraft version: %w", err)
 	}
-	return minecraftVersion
+	return minecraftVersion, nil
 }
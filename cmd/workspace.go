@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/customrealms/cli/pkg/project"
+)
+
+// selectProjects returns the projects a command operates on. In the root directory of a
+// workspace, these are the plugin packages of the workspace matching the filters.
+// Anywhere else, it's the project in the directory itself. The workspace result reports
+// whether the projects are packages selected from a workspace.
+func selectProjects(dir string, filters []string) (projects []project.Project, workspace bool, err error) {
+	// Find the workspace containing the directory
+	ws, err := project.FindWorkspace(dir)
+	if err != nil {
+		return nil, false, fmt.Errorf("finding workspace: %w", err)
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Outside the root of a workspace, there is a single project
+	if ws == nil || ws.Dir != absDir {
+		if len(filters) > 0 {
+			return nil, false, errors.New("--filter can only be used in the root directory of a workspace")
+		}
+		return []project.Project{project.New(dir)}, false, nil
+	}
+
+	// Select the plugin packages of the workspace
+	packages, err := ws.PluginPackages()
+	if err != nil {
+		return nil, false, err
+	}
+	if len(packages) == 0 {
+		return nil, false, fmt.Errorf("no plugin packages found in workspace %s", ws.Dir)
+	}
+	if projects, err = ws.Filter(packages, filters); err != nil {
+		return nil, false, err
+	}
+	return projects, true, nil
+}
@@ -0,0 +1,180 @@
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/customrealms/cli/pkg/project"
+	"github.com/evanw/esbuild/pkg/api"
+)
+
+// metafile is the subset of esbuild's metafile format used for the size report.
+// See https://esbuild.github.io/api/#metafile
+type metafile struct {
+	Outputs map[string]struct {
+		Bytes  int64 `json:"bytes"`
+		Inputs map[string]struct {
+			BytesInOutput int64 `json:"bytesInOutput"`
+		} `json:"inputs"`
+	} `json:"outputs"`
+}
+
+// PackageSize is the number of bytes a package contributes to the bundle.
+type PackageSize struct {
+	// Name is the name of the npm package, or "(project)" for the project's own code.
+	Name string
+	// Bytes is the number of bytes in the bundle.
+	Bytes int64
+	// Modules is the number of modules from the package in the bundle.
+	Modules int
+}
+
+// packageName returns the npm package an input path belongs to, based on the last
+// node_modules directory in the path.
+func packageName(inputPath string) string {
+	idx := strings.LastIndex(inputPath, "node_modules/")
+	if idx < 0 {
+		return "(project)"
+	}
+	parts := strings.Split(inputPath[idx+len("node_modules/"):], "/")
+	if strings.HasPrefix(parts[0], "@") && len(parts) > 1 {
+		return parts[0] + "/" + parts[1]
+	}
+	return parts[0]
+}
+
+// AnalyzePackages groups the inputs of an esbuild metafile by npm package, sorted by size
+// from largest to smallest.
+func AnalyzePackages(metafileJSON string) ([]PackageSize, error) {
+	var meta metafile
+	if err := json.Unmarshal([]byte(metafileJSON), &meta); err != nil {
+		return nil, fmt.Errorf("decoding metafile: %w", err)
+	}
+
+	sizes := make(map[string]*PackageSize)
+	for _, output := range meta.Outputs {
+		for inputPath, input := range output.Inputs {
+			name := packageName(inputPath)
+			if sizes[name] == nil {
+				sizes[name] = &PackageSize{Name: name}
+			}
+			sizes[name].Bytes += input.BytesInOutput
+			sizes[name].Modules++
+		}
+	}
+
+	packages := make([]PackageSize, 0, len(sizes))
+	for _, size := range sizes {
+		packages = append(packages, *size)
+	}
+	sort.Slice(packages, func(i, j int) bool {
+		if packages[i].Bytes != packages[j].Bytes {
+			return packages[i].Bytes > packages[j].Bytes
+		}
+		return packages[i].Name < packages[j].Name
+	})
+	return packages, nil
+}
+
+// filterMetafile returns the part of an esbuild metafile that describes a single output
+// file: the output itself, and the inputs bundled into it.
+func filterMetafile(metafileJSON, outputName string) (string, error) {
+	var meta struct {
+		Inputs  map[string]json.RawMessage `json:"inputs"`
+		Outputs map[string]json.RawMessage `json:"outputs"`
+	}
+	if err := json.Unmarshal([]byte(metafileJSON), &meta); err != nil {
+		return "", fmt.Errorf("decoding metafile: %w", err)
+	}
+
+	filtered := struct {
+		Inputs  map[string]json.RawMessage `json:"inputs"`
+		Outputs map[string]json.RawMessage `json:"outputs"`
+	}{
+		Inputs:  make(map[string]json.RawMessage),
+		Outputs: make(map[string]json.RawMessage),
+	}
+	for outputPath, output := range meta.Outputs {
+		if path.Base(outputPath) != outputName {
+			continue
+		}
+		var outputInputs struct {
+			Inputs map[string]json.RawMessage `json:"inputs"`
+		}
+		if err := json.Unmarshal(output, &outputInputs); err != nil {
+			return "", fmt.Errorf("decoding metafile: %w", err)
+		}
+		filtered.Outputs[outputPath] = output
+		for inputPath := range outputInputs.Inputs {
+			if input, ok := meta.Inputs[inputPath]; ok {
+				filtered.Inputs[inputPath] = input
+			}
+		}
+	}
+	if len(filtered.Outputs) == 0 {
+		return "", fmt.Errorf("metafile has no output %s", outputName)
+	}
+
+	data, err := json.MarshalIndent(filtered, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("encoding metafile: %w", err)
+	}
+	return string(data), nil
+}
+
+// writeAnalysis prints the per-module and per-package size breakdown of a bundle, under
+// headings starting with title.
+func writeAnalysis(w io.Writer, title, metafileJSON string) error {
+	fmt.Fprintln(w, "============================================================")
+	fmt.Fprintln(w, title+" by module")
+	fmt.Fprintln(w, "============================================================")
+	fmt.Fprint(w, api.AnalyzeMetafile(metafileJSON, api.AnalyzeMetafileOptions{}))
+	fmt.Fprintln(w)
+
+	packages, err := AnalyzePackages(metafileJSON)
+	if err != nil {
+		return err
+	}
+	var total int64
+	for _, pkg := range packages {
+		total += pkg.Bytes
+	}
+
+	fmt.Fprintln(w, "============================================================")
+	fmt.Fprintln(w, title+" by package")
+	fmt.Fprintln(w, "============================================================")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, pkg := range packages {
+		percent := 0.0
+		if total > 0 {
+			percent = float64(pkg.Bytes) * 100 / float64(total)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%.1f%%\t%d modules\n", pkg.Name, project.Size(pkg.Bytes), percent, pkg.Modules)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	return nil
+}
+
+// checkBudget returns an error if a file is larger than its size budget.
+func checkBudget(what, filename string, budget project.Size) error {
+	if budget <= 0 {
+		return nil
+	}
+	stat, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+	if size := project.Size(stat.Size()); size > budget {
+		return fmt.Errorf("%s is %s, which exceeds the budget of %s", what, size, budget)
+	}
+	return nil
+}
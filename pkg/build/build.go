@@ -1,28 +1,145 @@
 package build
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/customrealms/cli/pkg/errdefs"
+	"github.com/customrealms/cli/pkg/jarsign"
+	"github.com/customrealms/cli/pkg/pluginyml"
 	"github.com/customrealms/cli/pkg/project"
 	"github.com/evanw/esbuild/pkg/api"
+	"golang.org/x/sync/errgroup"
 )
 
 type BuildAction struct {
 	Project     project.Project
 	JarTemplate JarTemplate
 	ApiVersion  string
+	CliVersion  string
 	OutputFile  string
+	// BuildTime is the time recorded in the build metadata. If it's zero, the time from
+	// SOURCE_DATE_EPOCH or the current time is used.
+	BuildTime time.Time
+	// Signer signs the JAR file, if it's not nil.
+	Signer *jarsign.Signer
+	// Checksums writes .sha256 and .sha512 checksum files next to the JAR file.
+	Checksums bool
+	// Analyze writes esbuild's metafile next to the JAR file, and prints a breakdown of the
+	// bundle size by module and by package.
+	Analyze bool
+	// Targets selects the project targets to build by name. If it's empty, all of them
+	// are built.
+	Targets []string
+	// DefaultOutput writes the JAR file of a project without targets to dist/<name>.jar
+	// when OutputFile is empty. Otherwise, OutputFile is required for such projects.
+	DefaultOutput bool
+	// OutputDir, if it's not empty, replaces the directory of every output JAR file. The
+	// files are named after the package, so that packages of a workspace don't overwrite
+	// each other's JAR files.
+	OutputDir string
 }
 
 func (a *BuildAction) Run(ctx context.Context) error {
-	// Create a temporary directory for the output bundle.
-	// The code will be written to "bundle.js" in that directory.
+	targets, err := a.resolveTargets(ctx)
+	if err != nil {
+		return errdefs.Build(err)
+	}
+	return errdefs.Build(a.run(ctx, targets))
+}
+
+// resolveTargets returns the targets selected for the build.
+func (a *BuildAction) resolveTargets(ctx context.Context) ([]buildTarget, error) {
+	// Parse the plugin.yml file
+	pluginYML, err := a.Project.PluginYML(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("parse plugin.yml: %w", err)
+	}
+
+	// Read the project configuration
+	config, err := a.Project.Config()
+	if err != nil {
+		return nil, fmt.Errorf("read project config: %w", err)
+	}
+	targets, err := resolveTargets(a.Project, config, pluginYML, a.Targets, a.OutputFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// Place the JAR files in the output directory
+	if a.OutputDir != "" {
+		packageName, err := packageFileName(a.Project)
+		if err != nil {
+			return nil, err
+		}
+		for i := range targets {
+			name := packageName + ".jar"
+			if targets[i].target != nil {
+				name = packageName + "-" + filepath.Base(targets[i].outputFile)
+			}
+			targets[i].outputFile = filepath.Join(a.OutputDir, name)
+		}
+		return targets, nil
+	}
+
+	// Projects without targets need an output file
+	for i := range targets {
+		if targets[i].outputFile != "" {
+			continue
+		}
+		if !a.DefaultOutput {
+			return nil, errors.New("missing output file")
+		}
+		if targets[i].outputFile, err = DefaultOutputFile(a.Project, pluginYML); err != nil {
+			return nil, err
+		}
+	}
+	return targets, nil
+}
+
+// OutputFiles returns the paths of the JAR files the build produces.
+func (a *BuildAction) OutputFiles(ctx context.Context) ([]string, error) {
+	targets, err := a.resolveTargets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	outputFiles := make([]string, 0, len(targets))
+	for _, target := range targets {
+		outputFiles = append(outputFiles, target.outputFile)
+	}
+	return outputFiles, nil
+}
+
+// Plugins returns the plugin descriptors of the JAR files the build produces, in the same
+// order as OutputFiles.
+func (a *BuildAction) Plugins(ctx context.Context) ([]*pluginyml.Plugin, error) {
+	targets, err := a.resolveTargets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	plugins := make([]*pluginyml.Plugin, 0, len(targets))
+	for _, target := range targets {
+		plugin, err := GenerateTargetPluginYML(ctx, a.Project, target.target, a.ApiVersion)
+		if err != nil {
+			return nil, target.wrapError(err)
+		}
+		plugins = append(plugins, plugin)
+	}
+	return plugins, nil
+}
+
+func (a *BuildAction) run(ctx context.Context, targets []buildTarget) error {
+	// Create a temporary directory for the output bundles.
+	// The code for each target will be written to "<name>.js" in that directory.
 	buildOutputDir := filepath.Join(
 		os.TempDir(),
 		fmt.Sprintf("cr-build-%d-%d", time.Now().Unix(), rand.Uint32()),
@@ -32,39 +149,42 @@ func (a *BuildAction) Run(ctx context.Context) error {
 	}
 	defer os.RemoveAll(buildOutputDir)
 
-	// Parse the plugin.yml file
-	pluginYML, err := a.Project.PluginYML()
+	// Read the project configuration
+	config, err := a.Project.Config()
 	if err != nil {
-		return fmt.Errorf("parse plugin.yml: %w", err)
+		return fmt.Errorf("read project config: %w", err)
 	}
 
 	fmt.Println("============================================================")
 	fmt.Println("Bundling JavaScript code using esbuild")
 	fmt.Println("============================================================")
 
-	// Determine the entrypoint for the TypeScript project
-	var entrypoint string
-	if pluginYML != nil && strings.HasSuffix(pluginYML.Main, ".ts") {
-		entrypoint = pluginYML.Main
-	} else {
-		entrypoint = "./src/main.ts"
+	// Bundle all the entrypoints in a single build, so they share the parsing of the
+	// project's common code and dependencies
+	entryPoints := make([]api.EntryPoint, 0, len(targets))
+	for _, target := range targets {
+		entryPoints = append(entryPoints, api.EntryPoint{
+			InputPath:  target.entrypoint,
+			OutputPath: target.name(),
+		})
 	}
 
 	// Build the local directory using esbuild's Go API.
 	result := api.Build(api.BuildOptions{
-		AbsWorkingDir:     a.Project.Dir(),
-		EntryPoints:       []string{entrypoint},
-		Outfile:           filepath.Join(buildOutputDir, "bundle.js"),
-		Bundle:            true,
-		MinifyWhitespace:  true,
-		MinifyIdentifiers: true,
-		MinifySyntax:      true,
-		TreeShaking:       api.TreeShakingTrue,
-		Platform:          api.PlatformBrowser,
-		Format:            api.FormatIIFE,
-		Target:            api.ES2015,
-		LogLevel:          api.LogLevelInfo,
-		Write:             true,
+		AbsWorkingDir:       a.Project.Dir(),
+		EntryPointsAdvanced: entryPoints,
+		Outdir:              buildOutputDir,
+		Bundle:              true,
+		MinifyWhitespace:    true,
+		MinifyIdentifiers:   true,
+		MinifySyntax:        true,
+		TreeShaking:         api.TreeShakingTrue,
+		Platform:            api.PlatformBrowser,
+		Format:              api.FormatIIFE,
+		Target:              api.ES2015,
+		LogLevel:            api.LogLevelInfo,
+		Write:               true,
+		Metafile:            a.Analyze,
 	})
 	if len(result.Errors) > 0 {
 		return fmt.Errorf("bundle code with esbuild: %s", result.Errors[0].Text)
@@ -72,12 +192,217 @@ func (a *BuildAction) Run(ctx context.Context) error {
 
 	fmt.Println()
 
-	// Package the jar file
-	ja := JarAction{
-		Project:     a.Project,
-		JarTemplate: a.JarTemplate,
-		BundleFile:  filepath.Join(buildOutputDir, "bundle.js"),
-		OutputFile:  a.OutputFile,
+	// Write the metafile of each target next to its JAR file, and print its size analysis
+	if a.Analyze {
+		for _, target := range targets {
+			targetMetafile, err := filterMetafile(result.Metafile, target.name()+".js")
+			if err != nil {
+				return target.wrapError(err)
+			}
+			metafileName := strings.TrimSuffix(target.outputFile, filepath.Ext(target.outputFile)) + ".meta.json"
+			if err := os.MkdirAll(filepath.Dir(metafileName), 0777); err != nil {
+				return err
+			}
+			if err := os.WriteFile(metafileName, []byte(targetMetafile), 0666); err != nil {
+				return fmt.Errorf("writing metafile: %w", err)
+			}
+			fmt.Println("Wrote metafile to: ", metafileName)
+			if err := writeAnalysis(os.Stdout, target.describe("Bundle size"), targetMetafile); err != nil {
+				return target.wrapError(err)
+			}
+		}
+	}
+
+	// Check the bundle sizes against the budget
+	for _, target := range targets {
+		bundleFile := filepath.Join(buildOutputDir, target.name()+".js")
+		if err := checkBudget(target.describe("plugin.js"), bundleFile, config.Budgets.Bundle); err != nil {
+			return err
+		}
+	}
+
+	// Share the template JAR file between the targets
+	jarTemplate := a.JarTemplate
+	if len(targets) > 1 {
+		jarTemplate = &onceJarTemplate{template: a.JarTemplate}
+	}
+
+	// Package the jar files in parallel. The progress of each target is buffered and
+	// printed once it's done, so the output of the targets doesn't interleave.
+	var outputMu sync.Mutex
+	eg, ctx := errgroup.WithContext(ctx)
+	for _, target := range targets {
+		eg.Go(func() error {
+			var output bytes.Buffer
+			defer func() {
+				outputMu.Lock()
+				defer outputMu.Unlock()
+				os.Stdout.Write(output.Bytes())
+			}()
+			ja := JarAction{
+				Project:     a.Project,
+				Target:      target.target,
+				JarTemplate: jarTemplate,
+				ApiVersion:  a.ApiVersion,
+				CliVersion:  a.CliVersion,
+				BuildTime:   a.BuildTime,
+				BundleFile:  filepath.Join(buildOutputDir, target.name()+".js"),
+				OutputFile:  target.outputFile,
+				Signer:      a.Signer,
+				Checksums:   a.Checksums,
+				Budget:      config.Budgets.Jar,
+				Output:      &output,
+			}
+			if err := ja.Run(ctx); err != nil {
+				return target.wrapError(err)
+			}
+			return nil
+		})
+	}
+	return eg.Wait()
+}
+
+// VerifyReproducible builds the plugin twice and checks that both builds produce identical
+// JAR files. Both builds are written to temporary files, and the first one is copied to the
+// output file once they match.
+func (a *BuildAction) VerifyReproducible(ctx context.Context) error {
+	return errdefs.Build(a.verifyReproducible(ctx))
+}
+
+func (a *BuildAction) verifyReproducible(ctx context.Context) error {
+	// Both builds need to record the same build time
+	verify := *a
+	if verify.BuildTime.IsZero() {
+		verify.BuildTime = defaultBuildTime()
+	}
+	targets, err := verify.resolveTargets(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Run both builds to temporary files. Writing the first build to the project would
+	// change it, e.g. its git status, before the second build.
+	tempDir, err := os.MkdirTemp("", "cr-jar-verify-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+	var builds [2][]buildTarget
+	for b := range builds {
+		builds[b] = make([]buildTarget, len(targets))
+		for i, target := range targets {
+			builds[b][i] = target
+			builds[b][i].outputFile = filepath.Join(tempDir, strconv.Itoa(b), strconv.Itoa(i), filepath.Base(target.outputFile))
+		}
+	}
+	if err := verify.run(ctx, builds[0]); err != nil {
+		return err
+	}
+	verify.Checksums = false
+	verify.Analyze = false
+	if err := verify.run(ctx, builds[1]); err != nil {
+		return err
+	}
+
+	fmt.Println("============================================================")
+	fmt.Println("Verifying reproducible build")
+	fmt.Println("============================================================")
+
+	// Compare the JAR files of both builds. Signatures that aren't deterministic differ
+	// between the builds, so they're left out of the comparison.
+	ignore := func(name string) bool { return false }
+	if a.Signer != nil && !a.Signer.Deterministic() {
+		ignore = jarsign.IsSignatureBlockFile
+	}
+	var errs []error
+	for i, target := range targets {
+		if err := compareJarFiles(builds[0][i].outputFile, builds[1][i].outputFile, ignore); err != nil {
+			errs = append(errs, target.wrapError(err))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	if a.Signer != nil && !a.Signer.Deterministic() {
+		fmt.Println(" -> Both builds are identical, except for the signature block files: ECDSA signatures")
+		fmt.Println("    are randomized, so they differ between builds. Sign with an RSA key to compare them too.")
+	} else {
+		fmt.Println(" -> Both builds are identical")
+	}
+
+	// Copy the first build to the output files
+	fmt.Println()
+	for i, target := range targets {
+		if err := copyOutputFiles(builds[0][i].outputFile, target.outputFile); err != nil {
+			return target.wrapError(err)
+		}
+		fmt.Println("Wrote JAR file to: ", target.outputFile)
+	}
+	return nil
+}
+
+// compareJarFiles returns an error if two JAR files aren't identical, naming the entries
+// that differ. Entries for which ignore returns true may differ.
+func compareJarFiles(firstFile, secondFile string, ignore func(name string) bool) error {
+	first, err := os.ReadFile(firstFile)
+	if err != nil {
+		return err
+	}
+	second, err := os.ReadFile(secondFile)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(first, second) {
+		return nil
+	}
+
+	// Find the entries that differ
+	diff, err := DiffJars(first, second)
+	if err != nil {
+		return fmt.Errorf("comparing JAR files: %w", err)
+	}
+	var differences []string
+	for _, name := range diff {
+		if !ignore(name) {
+			differences = append(differences, name)
+		}
+	}
+	if len(diff) > 0 && len(differences) == 0 {
+		return nil
+	}
+	if len(diff) == 0 {
+		return errors.New("build is not reproducible: JAR files differ in ZIP metadata only")
+	}
+	return fmt.Errorf("build is not reproducible: JAR entries differ: %s", strings.Join(differences, ", "))
+}
+
+// copyOutputFiles copies a JAR file built in another directory to its output file, with the
+// checksum files and the metafile written next to it.
+func copyOutputFiles(from, to string) error {
+	if err := os.MkdirAll(filepath.Dir(to), 0777); err != nil {
+		return err
+	}
+	suffixes := []string{"", ".sha256", ".sha512"}
+	for _, suffix := range suffixes {
+		if err := copyFile(from+suffix, to+suffix); err != nil {
+			return err
+		}
+	}
+	metafile := func(jarFile string) string {
+		return strings.TrimSuffix(jarFile, filepath.Ext(jarFile)) + ".meta.json"
+	}
+	return copyFile(metafile(from), metafile(to))
+}
+
+// copyFile copies a file, if it exists.
+func copyFile(from, to string) error {
+	data, err := os.ReadFile(from)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
 	}
-	return ja.Run(ctx)
+	return os.WriteFile(to, data, 0666)
 }
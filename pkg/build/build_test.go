@@ -0,0 +1,85 @@
+package build_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/customrealms/cli/pkg/build"
+	"github.com/customrealms/cli/pkg/project"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildActionAnalyze(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"package.json": `{"name": "my-plugins", "version": "1.0.0", "crx": {"targets": [
+			{"name": "a", "entrypoint": "src/a.ts"},
+			{"name": "b", "entrypoint": "src/b.ts"}
+		]}}`,
+		"plugin.yml":     "name: Shared\n",
+		"src/shared.ts":  "export const shared = () => console.log('shared');\n",
+		"src/only_a.ts":  "export const onlyA = () => console.log('a');\n",
+		"src/a.ts":       "import { shared } from './shared';\nimport { onlyA } from './only_a';\nshared();\nonlyA();\n",
+		"src/b.ts":       "import { shared } from './shared';\nshared();\n",
+		"runtime/rt.jar": string(testTemplateJar(t, "a.txt")),
+	}
+	for name, contents := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0777))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(contents), 0644))
+	}
+
+	action := &build.BuildAction{
+		Project:     project.New(dir),
+		JarTemplate: &build.FileJarTemplate{Filename: filepath.Join(dir, "runtime", "rt.jar")},
+		ApiVersion:  "1.21",
+		CliVersion:  "1.2.3",
+		Analyze:     true,
+	}
+	require.NoError(t, action.Run(context.Background()))
+
+	// Each target's metafile only describes its own bundle
+	inputs := func(target string) []string {
+		data, err := os.ReadFile(filepath.Join(dir, "dist", target+".meta.json"))
+		require.NoError(t, err)
+		var meta struct {
+			Inputs  map[string]any `json:"inputs"`
+			Outputs map[string]any `json:"outputs"`
+		}
+		require.NoError(t, json.Unmarshal(data, &meta))
+		require.Len(t, meta.Outputs, 1)
+		var names []string
+		for name := range meta.Inputs {
+			names = append(names, name)
+		}
+		return names
+	}
+	require.ElementsMatch(t, []string{"src/a.ts", "src/shared.ts", "src/only_a.ts"}, inputs("a"))
+	require.ElementsMatch(t, []string{"src/b.ts", "src/shared.ts"}, inputs("b"))
+}
+
+func TestBuildActionOutputFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"name": "@acme/economy"}`), 0644))
+	p := project.New(dir)
+
+	t.Run("missing output file", func(t *testing.T) {
+		_, err := (&build.BuildAction{Project: p}).OutputFiles(context.Background())
+		require.ErrorContains(t, err, "missing output file")
+	})
+
+	t.Run("default output", func(t *testing.T) {
+		outputFiles, err := (&build.BuildAction{Project: p, DefaultOutput: true}).OutputFiles(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, []string{filepath.Join(dir, "dist", "economy.jar")}, outputFiles)
+	})
+
+	t.Run("output dir", func(t *testing.T) {
+		outputDir := t.TempDir()
+		outputFiles, err := (&build.BuildAction{Project: p, OutputDir: outputDir}).OutputFiles(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, []string{filepath.Join(outputDir, "acme-economy.jar")}, outputFiles)
+	})
+}
@@ -0,0 +1,101 @@
+package build
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// checksumAlgorithms are the checksum file extensions and the hash functions they use.
+var checksumAlgorithms = []struct {
+	ext  string
+	hash func() hash.Hash
+}{
+	{".sha256", sha256.New},
+	{".sha512", sha512.New},
+}
+
+// ErrChecksumMismatch is returned when a file doesn't match its checksum file.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// WriteChecksumFiles writes ".sha256" and ".sha512" checksum files next to a file, in the
+// format used by sha256sum and sha512sum. It returns the paths of the checksum files.
+func WriteChecksumFiles(filename string) ([]string, error) {
+	var checksumFiles []string
+	for _, algorithm := range checksumAlgorithms {
+		sum, err := hashFile(filename, algorithm.hash())
+		if err != nil {
+			return nil, err
+		}
+		checksumFile := filename + algorithm.ext
+		line := fmt.Sprintf("%s  %s\n", sum, filepath.Base(filename))
+		if err := os.WriteFile(checksumFile, []byte(line), 0666); err != nil {
+			return nil, err
+		}
+		checksumFiles = append(checksumFiles, checksumFile)
+	}
+	return checksumFiles, nil
+}
+
+// VerifyChecksumFiles checks a file against the checksum files next to it. It returns the
+// paths of the checksum files that were checked, which is empty if there are none.
+func VerifyChecksumFiles(filename string) ([]string, error) {
+	var checked []string
+	for _, algorithm := range checksumAlgorithms {
+		checksumFile := filename + algorithm.ext
+		expected, err := readChecksumFile(checksumFile)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		sum, err := hashFile(filename, algorithm.hash())
+		if err != nil {
+			return nil, err
+		}
+		if !strings.EqualFold(sum, expected) {
+			return nil, fmt.Errorf("%w: %s", ErrChecksumMismatch, filepath.Base(checksumFile))
+		}
+		checked = append(checked, checksumFile)
+	}
+	return checked, nil
+}
+
+func hashFile(filename string, h hash.Hash) (string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// readChecksumFile reads the checksum from the first line of a checksum file.
+func readChecksumFile(checksumFile string) (string, error) {
+	file, err := os.Open(checksumFile)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("%s is empty", filepath.Base(checksumFile))
+	}
+	sum, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+	return sum, nil
+}
@@ -0,0 +1,174 @@
+package build
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+
+	"github.com/customrealms/cli/pkg/jarsign"
+	"github.com/customrealms/cli/pkg/manifest"
+	"github.com/customrealms/cli/pkg/pluginyml"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// PluginJSFilename is the name of the file in the JAR that holds the bundled plugin code.
+	PluginJSFilename = "plugin.js"
+	// PluginYMLFilename is the name of the file in the JAR that holds the plugin descriptor.
+	PluginYMLFilename = "plugin.yml"
+)
+
+// isGeneratedEntry returns true if the JAR entry is generated for each plugin, rather than
+// copied from the runtime template. This includes signature files, since a signature of the
+// template is no longer valid once the plugin is added.
+func isGeneratedEntry(name string) bool {
+	switch name {
+	case PluginJSFilename, PluginYMLFilename, BuildMetadataFilename, manifest.Filename:
+		return true
+	}
+	return jarsign.IsSignatureFile(name)
+}
+
+// JarInfo describes the contents of a plugin JAR file.
+type JarInfo struct {
+	// Plugin is the parsed plugin.yml file.
+	Plugin *pluginyml.Plugin `json:"plugin"`
+	// Runtime identifies the runtime template the plugin was built on.
+	Runtime RuntimeInfo `json:"runtime"`
+	// BundleSize is the size of the plugin.js file, in bytes.
+	BundleSize int64 `json:"bundleSize"`
+	// Metadata is the embedded build metadata, or nil if the JAR doesn't have any.
+	Metadata *BuildMetadata `json:"metadata,omitempty"`
+	// Resources is the list of files copied from the runtime template, sorted by name.
+	Resources []JarResource `json:"resources"`
+}
+
+type RuntimeInfo struct {
+	// MainClass is the main Java class of the plugin.
+	MainClass string `json:"mainClass"`
+	// Version is the version or tag of the runtime template, if known.
+	Version string `json:"version,omitempty"`
+	// Sha256 is the hex-encoded SHA-256 checksum of the runtime template JAR, if known.
+	Sha256 string `json:"sha256,omitempty"`
+	// BuildJdkSpec is the Java version the runtime was compiled with, if known.
+	BuildJdkSpec string `json:"buildJdkSpec,omitempty"`
+}
+
+type JarResource struct {
+	// Name is the path of the file within the JAR.
+	Name string `json:"name"`
+	// Size is the uncompressed size of the file, in bytes.
+	Size int64 `json:"size"`
+}
+
+// InspectJar reads the contents of a plugin JAR file.
+func InspectJar(zr *zip.Reader) (*JarInfo, error) {
+	var info JarInfo
+
+	// Read the plugin.yml file
+	ymlData, err := readZipEntry(zr, PluginYMLFilename)
+	if err != nil {
+		return nil, err
+	}
+	if ymlData == nil {
+		return nil, fmt.Errorf("missing %s, this is not a plugin JAR file", PluginYMLFilename)
+	}
+	if err := yaml.Unmarshal(ymlData, &info.Plugin); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", PluginYMLFilename, err)
+	}
+	if info.Plugin == nil {
+		return nil, fmt.Errorf("%s is empty", PluginYMLFilename)
+	}
+	info.Runtime.MainClass = info.Plugin.Main
+
+	// Read the build metadata
+	if info.Metadata, err = ReadBuildMetadata(zr); err != nil {
+		return nil, err
+	}
+	if info.Metadata != nil {
+		info.Runtime.Version = info.Metadata.Runtime.Version
+		info.Runtime.Sha256 = info.Metadata.Runtime.Sha256
+		info.Runtime.BuildJdkSpec = info.Metadata.Runtime.BuildJdkSpec
+	}
+
+	// JAR files without the JDK in their build metadata have it in their manifest
+	manifestData, err := readZipEntry(zr, manifest.Filename)
+	if err != nil {
+		return nil, err
+	}
+	if manifestData != nil && info.Runtime.BuildJdkSpec == "" {
+		m, err := manifest.Parse(manifestData)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", manifest.Filename, err)
+		}
+		info.Runtime.BuildJdkSpec, _ = m.Main.Get("Build-Jdk-Spec")
+	}
+
+	// List the rest of the files
+	info.Resources = []JarResource{}
+	for _, f := range zr.File {
+		if f.Name == PluginJSFilename {
+			info.BundleSize = int64(f.UncompressedSize64)
+		}
+		if isGeneratedEntry(f.Name) || f.FileInfo().IsDir() {
+			continue
+		}
+		info.Resources = append(info.Resources, JarResource{
+			Name: f.Name,
+			Size: int64(f.UncompressedSize64),
+		})
+	}
+	sort.Slice(info.Resources, func(i, j int) bool {
+		return info.Resources[i].Name < info.Resources[j].Name
+	})
+	return &info, nil
+}
+
+// ExtractPluginJS copies the bundled plugin code from a plugin JAR file to a writer.
+func ExtractPluginJS(zr *zip.Reader, w io.Writer) error {
+	data, err := readZipEntry(zr, PluginJSFilename)
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		return fmt.Errorf("missing %s in JAR file", PluginJSFilename)
+	}
+	_, err = io.Copy(w, bytes.NewReader(data))
+	return err
+}
+
+// readZipEntry reads the contents of a file in a ZIP archive. If the file doesn't exist,
+// it returns nil.
+func readZipEntry(zr *zip.Reader, name string) ([]byte, error) {
+	file, err := zr.Open(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("opening %s: %w", name, err)
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", name, err)
+	}
+	return data, nil
+}
+
+// readZipFile reads the contents of a file in a ZIP archive.
+func readZipFile(f *zip.File) ([]byte, error) {
+	reader, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", f.Name, err)
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", f.Name, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,145 @@
+package build_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/customrealms/cli/pkg/build"
+	"github.com/stretchr/testify/require"
+)
+
+// testZip creates a ZIP archive with the given files, by name.
+func testZip(t *testing.T, files map[string]string) *zip.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, contents := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(contents))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	return zr
+}
+
+func TestInspectJar(t *testing.T) {
+	built := writeTestJar(t, testTemplateJar(t, "io/customrealms/MainPlugin.class", "a.txt"), time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC))
+	builtReader, err := zip.NewReader(bytes.NewReader(built), int64(len(built)))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		jar     *zip.Reader
+		want    func(t *testing.T, info *build.JarInfo)
+		wantErr string
+	}{
+		{
+			name: "built by crx",
+			jar:  builtReader,
+			want: func(t *testing.T, info *build.JarInfo) {
+				require.Equal(t, "TestPlugin", info.Plugin.Name)
+				require.Equal(t, build.JarMainClass, info.Runtime.MainClass)
+				require.Equal(t, int64(len("console.log('hello');")), info.BundleSize)
+				require.NotNil(t, info.Metadata)
+				require.Equal(t, "1.2.3", info.Metadata.CliVersion)
+				require.Equal(t, []build.JarResource{
+					{Name: "a.txt", Size: int64(len("contents of a.txt"))},
+					{Name: "io/customrealms/MainPlugin.class", Size: int64(len("contents of io/customrealms/MainPlugin.class"))},
+				}, info.Resources)
+			},
+		},
+		{
+			name: "without build metadata",
+			jar: testZip(t, map[string]string{
+				"plugin.yml":           "name: Old\nmain: io.customrealms.MainPlugin\nversion: 0.1.0\n",
+				"plugin.js":            "old();",
+				"META-INF/MANIFEST.MF": "Manifest-Version: 1.0\r\nBuild-Jdk-Spec: 11\r\n\r\n",
+				"META-INF/":            "",
+			}),
+			want: func(t *testing.T, info *build.JarInfo) {
+				require.Equal(t, "Old", info.Plugin.Name)
+				require.Nil(t, info.Metadata)
+				require.Equal(t, "11", info.Runtime.BuildJdkSpec)
+				require.Equal(t, int64(len("old();")), info.BundleSize)
+				require.Empty(t, info.Resources)
+			},
+		},
+		{
+			name:    "missing plugin.yml",
+			jar:     testZip(t, map[string]string{"plugin.js": "x"}),
+			wantErr: "missing plugin.yml, this is not a plugin JAR file",
+		},
+		{
+			name:    "invalid plugin.yml",
+			jar:     testZip(t, map[string]string{"plugin.yml": "name: [unclosed"}),
+			wantErr: "decoding plugin.yml",
+		},
+		{
+			name:    "empty plugin.yml",
+			jar:     testZip(t, map[string]string{"plugin.yml": "# nothing here\n"}),
+			wantErr: "plugin.yml is empty",
+		},
+		{
+			name: "invalid build metadata",
+			jar: testZip(t, map[string]string{
+				"plugin.yml":     "name: Broken\n",
+				"crx-build.json": "{",
+			}),
+			wantErr: "decoding crx-build.json",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			info, err := build.InspectJar(test.jar)
+			if test.wantErr != "" {
+				require.ErrorContains(t, err, test.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			test.want(t, info)
+		})
+	}
+}
+
+func TestExtractPluginJS(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		want    string
+		wantErr string
+	}{
+		{
+			name:  "bundle",
+			files: map[string]string{"plugin.js": "console.log('hello');", "plugin.yml": "name: Test\n"},
+			want:  "console.log('hello');",
+		},
+		{
+			name:  "empty bundle",
+			files: map[string]string{"plugin.js": ""},
+			want:  "",
+		},
+		{
+			name:    "missing bundle",
+			files:   map[string]string{"plugin.yml": "name: Test\n"},
+			wantErr: "missing plugin.js in JAR file",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out strings.Builder
+			err := build.ExtractPluginJS(testZip(t, test.files), &out)
+			if test.wantErr != "" {
+				require.ErrorContains(t, err, test.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.want, out.String())
+		})
+	}
+}
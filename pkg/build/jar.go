@@ -4,31 +4,57 @@ import (
 	"archive/zip"
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"os/user"
 	"path/filepath"
+	"time"
 
+	"github.com/customrealms/cli/pkg/jarsign"
+	"github.com/customrealms/cli/pkg/manifest"
 	"github.com/customrealms/cli/pkg/pluginyml"
 	"github.com/customrealms/cli/pkg/project"
 	"gopkg.in/yaml.v3"
 )
 
 type JarAction struct {
-	Project     project.Project
+	Project project.Project
+	// Target is the project target the JAR file is built for, or nil for projects without
+	// targets.
+	Target      *project.Target
 	JarTemplate JarTemplate
 	ApiVersion  string
+	CliVersion  string
+	BuildTime   time.Time
 	BundleFile  string
 	OutputFile  string
+	// Signer signs the JAR file, if it's not nil.
+	Signer *jarsign.Signer
+	// Checksums writes .sha256 and .sha512 checksum files next to the JAR file.
+	Checksums bool
+	// Budget is the maximum size of the JAR file. If it's exceeded, the build fails and no
+	// JAR file is written. Zero means there is no limit.
+	Budget project.Size
+	// Output receives the progress messages. If it's nil, they're printed to stdout.
+	Output io.Writer
 }
 
 func (a *JarAction) Run(ctx context.Context) error {
-	fmt.Println("============================================================")
-	fmt.Println("Downloading JAR plugin runtime")
-	fmt.Println("============================================================")
+	out := a.Output
+	if out == nil {
+		out = os.Stdout
+	}
+
+	fmt.Fprintln(out, "============================================================")
+	fmt.Fprintln(out, "Downloading JAR plugin runtime")
+	fmt.Fprintln(out, "============================================================")
 
 	// Get the reader of the Jar file
-	jarReader, err := a.JarTemplate.Jar()
+	jarReader, err := a.JarTemplate.Jar(ctx)
 	if err != nil {
 		return err
 	}
@@ -40,60 +66,151 @@ func (a *JarAction) Run(ctx context.Context) error {
 		return err
 	}
 
-	fmt.Println(" -> DONE")
-	fmt.Println()
+	fmt.Fprintln(out, " -> DONE")
+	fmt.Fprintln(out)
 
 	// Make sure the directory above the output file exists
 	if err := os.MkdirAll(filepath.Dir(a.OutputFile), 0777); err != nil {
 		return err
 	}
 
-	// Open the output file for the final JAR
-	file, err := os.Create(a.OutputFile)
+	// Write the final JAR to a temporary file first, so a failed build doesn't leave a JAR
+	// file that looks valid
+	file, err := os.CreateTemp(filepath.Dir(a.OutputFile), "."+filepath.Base(a.OutputFile)+"-*.tmp")
 	if err != nil {
 		return err
 	}
+	defer os.Remove(file.Name())
 	defer file.Close()
+	if err := file.Chmod(0644); err != nil {
+		return err
+	}
 
-	// Create a reader for the plugin source code
-	pluginCode, err := os.Open(a.BundleFile)
+	// Read the plugin source code
+	pluginCode, err := os.ReadFile(a.BundleFile)
 	if err != nil {
 		return err
 	}
-	defer pluginCode.Close()
 
 	// Generate the plugin.yml file for the project
-	pluginYML, err := GeneratePluginYML(a.Project, a.ApiVersion)
+	pluginYML, err := GenerateTargetPluginYML(ctx, a.Project, a.Target, a.ApiVersion)
 	if err != nil {
 		return fmt.Errorf("generating plugin.yml: %w", err)
 	}
 
+	// Collect the metadata describing this build
+	metadata, err := a.buildMetadata(ctx, jarTemplateBuf.Bytes(), pluginCode)
+	if err != nil {
+		return fmt.Errorf("collecting build metadata: %w", err)
+	}
+
 	// Produce the final JAR file
-	if err := WriteJarFile(
+	if err := writeJarFile(
+		out,
 		file,
 		jarTemplateBuf.Bytes(),
-		pluginCode,
+		bytes.NewReader(pluginCode),
 		pluginYML,
+		metadata,
+		entryModTime(),
+		a.Signer,
 	); err != nil {
 		return err
 	}
 
-	fmt.Println("Wrote JAR file to: ", a.OutputFile)
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	// Check the JAR size against the budget
+	if err := checkBudget("JAR file", file.Name(), a.Budget); err != nil {
+		return err
+	}
+	if err := os.Rename(file.Name(), a.OutputFile); err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "Wrote JAR file to: ", a.OutputFile)
+
+	// Write the checksum files
+	if a.Checksums {
+		checksumFiles, err := WriteChecksumFiles(a.OutputFile)
+		if err != nil {
+			return fmt.Errorf("writing checksum files: %w", err)
+		}
+		for _, checksumFile := range checksumFiles {
+			fmt.Fprintln(out, "Wrote checksum file to: ", checksumFile)
+		}
+	}
 
 	return nil
 
 }
 
+func (a *JarAction) buildMetadata(ctx context.Context, templateJarData, pluginCode []byte) (*BuildMetadata, error) {
+	templateSum := sha256.Sum256(templateJarData)
+	bundleSum := sha256.Sum256(pluginCode)
+	metadata := BuildMetadata{
+		CliVersion: a.CliVersion,
+		Runtime: RuntimeMetadata{
+			Version: a.JarTemplate.Version(),
+			Sha256:  hex.EncodeToString(templateSum[:]),
+		},
+		ApiVersion:   a.ApiVersion,
+		BuildTime:    a.BuildTime,
+		BundleSha256: hex.EncodeToString(bundleSum[:]),
+	}
+	if metadata.BuildTime.IsZero() {
+		metadata.BuildTime = defaultBuildTime()
+	}
+
+	// Record the user building the JAR, unless this is meant to be a reproducible build
+	if _, ok := sourceDateEpoch(); !ok {
+		if u, err := user.Current(); err == nil {
+			metadata.BuiltBy = u.Username
+		}
+	}
+
+	// Record the commit the JAR is built from
+	gitInfo, err := a.Project.Git(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if gitInfo != nil {
+		metadata.Git = &GitMetadata{
+			Commit: gitInfo.Commit,
+			Dirty:  gitInfo.Dirty,
+		}
+	}
+	return &metadata, nil
+}
+
 func WriteJarFile(
 	writer io.Writer,
 	templateJarData []byte,
 	pluginSourceCode io.Reader,
 	pluginYML *pluginyml.Plugin,
+	metadata *BuildMetadata,
+	modTime time.Time,
+	signer *jarsign.Signer,
 ) error {
+	return writeJarFile(os.Stdout, writer, templateJarData, pluginSourceCode, pluginYML, metadata, modTime, signer)
+}
 
-	fmt.Println("============================================================")
-	fmt.Println("Generating final JAR file for your plugin")
-	fmt.Println("============================================================")
+// writeJarFile writes the final JAR file, printing its progress to out.
+func writeJarFile(
+	out io.Writer,
+	writer io.Writer,
+	templateJarData []byte,
+	pluginSourceCode io.Reader,
+	pluginYML *pluginyml.Plugin,
+	metadata *BuildMetadata,
+	modTime time.Time,
+	signer *jarsign.Signer,
+) error {
+
+	fmt.Fprintln(out, "============================================================")
+	fmt.Fprintln(out, "Generating final JAR file for your plugin")
+	fmt.Fprintln(out, "============================================================")
 
 	// Create the ZIP writer
 	zw := zip.NewWriter(writer)
@@ -106,13 +223,16 @@ func WriteJarFile(
 		return err
 	}
 
-	fmt.Println(" -> Copying template files to new JAR file")
+	// The entries of the final JAR file, which are sorted before writing
+	var entries []jarEntry
+
+	fmt.Fprintln(out, " -> Copying template files to new JAR file")
 
 	// Copy all the files back to the jar file
 	for _, f := range zr.File {
 
 		// Skip some files
-		if f.Name == "plugin.js" || f.Name == "plugin.yml" {
+		if isGeneratedEntry(f.Name) {
 			continue
 		}
 
@@ -122,40 +242,133 @@ func WriteJarFile(
 		}
 
 		// Copy the rest
-		if err := zw.Copy(f); err != nil {
-			return fmt.Errorf("copying file %s: %w", f.Name, err)
-		}
+		entries = append(entries, jarEntry{name: f.Name, file: f})
 
 	}
 
-	fmt.Println(" -> Writing bundle JS code to JAR file")
+	fmt.Fprintln(out, " -> Writing bundle JS code to JAR file")
 
 	// Write the plugin code to the jar
-	codeFile, err := zw.Create("plugin.js")
+	pluginCode, err := io.ReadAll(pluginSourceCode)
 	if err != nil {
 		return err
 	}
-	if _, err := io.Copy(codeFile, pluginSourceCode); err != nil {
+	entries = append(entries, jarEntry{name: PluginJSFilename, data: pluginCode})
+
+	fmt.Fprintln(out, " -> Writing plugin.yml file to JAR file")
+
+	// Write the plugin YML file to the jar. The encoder writes struct fields in declaration
+	// order and sorts map keys, so the output is stable.
+	var ymlBuf bytes.Buffer
+	enc := yaml.NewEncoder(&ymlBuf)
+	enc.SetIndent(2)
+	if err := enc.Encode(pluginYML); err != nil {
+		return fmt.Errorf("encoding plugin.yml: %w", err)
+	}
+	entries = append(entries, jarEntry{name: PluginYMLFilename, data: ymlBuf.Bytes()})
+
+	// Read the manifest from the template, and the JDK the runtime was compiled with, which
+	// is recorded in the build metadata and in the manifest
+	jarManifest, err := readTemplateManifest(zr)
+	if err != nil {
 		return err
 	}
+	if buildJdkSpec, ok := jarManifest.Main.Get("Build-Jdk-Spec"); ok && metadata.Runtime.BuildJdkSpec == "" {
+		withJdk := *metadata
+		withJdk.Runtime.BuildJdkSpec = buildJdkSpec
+		metadata = &withJdk
+	}
 
-	fmt.Println(" -> Writing plugin.yml file to JAR file")
+	fmt.Fprintln(out, " -> Writing build metadata to JAR file")
 
-	// Write the plugin YML file to the jar
-	ymlFile, err := zw.Create("plugin.yml")
+	// Write the build metadata to the jar
+	metadataBytes, err := json.MarshalIndent(metadata, "", "  ")
 	if err != nil {
-		return err
+		return fmt.Errorf("encoding %s: %w", BuildMetadataFilename, err)
 	}
-	enc := yaml.NewEncoder(ymlFile)
-	enc.SetIndent(2)
-	if err := enc.Encode(pluginYML); err != nil {
-		return fmt.Errorf("encoding plugin.yml: %w", err)
+	entries = append(entries, jarEntry{name: BuildMetadataFilename, data: append(metadataBytes, '\n')})
+
+	fmt.Fprintln(out, " -> Writing manifest to JAR file")
+
+	// Update the manifest from the template
+	updateManifest(jarManifest, pluginYML, metadata)
+
+	// Record the digest of every entry in the manifest, so the signature covers them
+	if signer != nil {
+		for _, entry := range entries {
+			data, err := entry.contents()
+			if err != nil {
+				return fmt.Errorf("reading file %s: %w", entry.name, err)
+			}
+			jarsign.AddDigest(jarManifest, entry.name, data)
+		}
+	}
+	entries = append(entries, jarEntry{name: manifest.Filename, data: jarManifest.Bytes()})
+
+	// Sign the manifest
+	if signer != nil {
+		fmt.Fprintln(out, " -> Signing JAR file")
+
+		signatureFiles, err := signer.Sign(jarManifest, fmt.Sprintf("crx %s", metadata.CliVersion))
+		if err != nil {
+			return fmt.Errorf("signing JAR file: %w", err)
+		}
+		for _, file := range signatureFiles {
+			entries = append(entries, jarEntry{name: file.Name, data: file.Data})
+		}
+	}
+
+	// Write the entries in a stable order, with a fixed modification time
+	sortJarEntries(entries)
+	for _, entry := range entries {
+		if err := entry.write(zw, modTime); err != nil {
+			return fmt.Errorf("writing file %s: %w", entry.name, err)
+		}
 	}
 
-	fmt.Println(" -> DONE")
-	fmt.Println()
+	fmt.Fprintln(out, " -> DONE")
+	fmt.Fprintln(out)
 
 	// We're done, no errors
 	return nil
 
 }
+
+// readTemplateManifest reads the manifest from the template JAR file. If the template
+// doesn't have a manifest, it returns an empty one.
+func readTemplateManifest(zr *zip.Reader) (*manifest.Manifest, error) {
+	data, err := readZipEntry(zr, manifest.Filename)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return manifest.New(), nil
+	}
+	m, err := manifest.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing template %s: %w", manifest.Filename, err)
+	}
+	return m, nil
+}
+
+// updateManifest sets the standard manifest attributes describing the plugin and its build.
+// "Build-Jdk-Spec" is the Java version of the runtime, the only compiled code in the JAR
+// file. Other attributes from the template are kept as they are.
+func updateManifest(m *manifest.Manifest, pluginYML *pluginyml.Plugin, metadata *BuildMetadata) {
+	if _, ok := m.Main.Get("Manifest-Version"); !ok {
+		m.Main = append(manifest.Attributes{{Name: "Manifest-Version", Value: "1.0"}}, m.Main...)
+	}
+	m.Main.Set("Created-By", fmt.Sprintf("crx %s", metadata.CliVersion))
+	if metadata.BuiltBy != "" {
+		m.Main.Set("Built-By", metadata.BuiltBy)
+	} else {
+		m.Main.Delete("Built-By")
+	}
+	m.Main.Set("Implementation-Title", pluginYML.Name)
+	m.Main.Set("Implementation-Version", pluginYML.Version)
+	if metadata.Runtime.BuildJdkSpec != "" {
+		m.Main.Set("Build-Jdk-Spec", metadata.Runtime.BuildJdkSpec)
+	} else {
+		m.Main.Delete("Build-Jdk-Spec")
+	}
+}
@@ -1,9 +1,12 @@
 package build
 
 import (
+	"context"
 	"io"
 )
 
 type JarTemplate interface {
-	Jar() (io.ReadCloser, error)
+	Jar(ctx context.Context) (io.ReadCloser, error)
+	// Version identifies the template JAR returned by the last call to Jar.
+	Version() string
 }
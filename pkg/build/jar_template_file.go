@@ -1,14 +1,20 @@
 package build
 
 import (
+	"context"
 	"io"
 	"os"
+	"path/filepath"
 )
 
 type FileJarTemplate struct {
 	Filename string
 }
 
-func (t *FileJarTemplate) Jar() (io.ReadCloser, error) {
+func (t *FileJarTemplate) Jar(ctx context.Context) (io.ReadCloser, error) {
 	return os.Open(t.Filename)
 }
+
+func (t *FileJarTemplate) Version() string {
+	return "file:" + filepath.Base(t.Filename)
+}
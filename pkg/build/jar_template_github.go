@@ -1,24 +1,71 @@
 package build
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
+	"path"
+
+	"github.com/customrealms/cli/pkg/cache"
+	"github.com/customrealms/cli/pkg/download"
 )
 
-type GitHubJarTemplate struct{}
+// RuntimeURL is the base URL of the releases of the runtime JAR. It can be replaced with a
+// mirror with the Mirrors of the download client, see the "Mirrors" section of the README.
+const RuntimeURL = "https://github.com/customrealms/bukkit-runtime/releases"
+
+// RuntimeCacheKey is the cache key of the last runtime JAR downloaded.
+const RuntimeCacheKey = "runtime/bukkit-runtime.jar"
 
-func (t *GitHubJarTemplate) Jar() (io.ReadCloser, error) {
+type GitHubJarTemplate struct {
+	// tag is the release tag the latest download was redirected to
+	tag string
+}
+
+func (t *GitHubJarTemplate) Jar(ctx context.Context) (io.ReadCloser, error) {
 	// Get the JAR url
-	jarUrl := "https://github.com/customrealms/bukkit-runtime/releases/latest/download/bukkit-runtime.jar"
+	jarUrl := RuntimeURL + "/latest/download/bukkit-runtime.jar"
 
-	// Download the JAR file
-	fmt.Printf(" -> %s\n", jarUrl)
-	res, err := http.Get(jarUrl)
+	// Download the JAR file, or use the cached copy if the download fails
+	fmt.Printf(" -> %s\n", download.Default.URL(jarUrl))
+	data, meta, err := cache.Fetch(ctx, RuntimeCacheKey, "the runtime JAR", func(ctx context.Context) ([]byte, cache.Meta, error) {
+		res, err := download.Default.Get(ctx, jarUrl)
+		if err != nil {
+			return nil, cache.Meta{}, err
+		}
+		defer res.Body.Close()
+		data, err := io.ReadAll(res.Body)
+		return data, cache.Meta{Kind: "runtime", Version: releaseTag(res), Source: jarUrl}, err
+	})
 	if err != nil {
 		return nil, err
 	}
+	t.tag = meta.Version
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
 
-	// Return the response body
-	return res.Body, nil
+// releaseTag returns the tag of the release a download of the latest runtime JAR was
+// redirected to. The latest download redirects to
+// ".../releases/download/<tag>/bukkit-runtime.jar" before redirecting again to the storage
+// host, so it walks back through the redirects to find out which release we got.
+func releaseTag(res *http.Response) string {
+	for req := res.Request; req != nil; {
+		if dir := path.Dir(req.URL.Path); path.Base(path.Dir(dir)) == "download" {
+			return path.Base(dir)
+		}
+		if req.Response == nil {
+			break
+		}
+		req = req.Response.Request
+	}
+	return ""
+}
+
+func (t *GitHubJarTemplate) Version() string {
+	if t.tag == "" || t.tag == "latest" {
+		return "latest"
+	}
+	return t.tag
 }
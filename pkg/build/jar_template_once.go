@@ -0,0 +1,42 @@
+package build
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+)
+
+// NewOnceJarTemplate wraps a template so it's only read once, and shared by all the JAR
+// files built with it.
+func NewOnceJarTemplate(template JarTemplate) JarTemplate {
+	return &onceJarTemplate{template: template}
+}
+
+// onceJarTemplate reads a template JAR file once and keeps it in memory, so several JAR
+// files built together share a single download.
+type onceJarTemplate struct {
+	template JarTemplate
+	once     sync.Once
+	data     []byte
+	err      error
+}
+
+func (t *onceJarTemplate) Jar(ctx context.Context) (io.ReadCloser, error) {
+	t.once.Do(func() {
+		var rc io.ReadCloser
+		if rc, t.err = t.template.Jar(ctx); t.err != nil {
+			return
+		}
+		defer rc.Close()
+		t.data, t.err = io.ReadAll(rc)
+	})
+	if t.err != nil {
+		return nil, t.err
+	}
+	return io.NopCloser(bytes.NewReader(t.data)), nil
+}
+
+func (t *onceJarTemplate) Version() string {
+	return t.template.Version()
+}
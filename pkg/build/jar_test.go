@@ -0,0 +1,212 @@
+package build_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/customrealms/cli/pkg/build"
+	"github.com/customrealms/cli/pkg/manifest"
+	"github.com/customrealms/cli/pkg/pluginyml"
+	"github.com/customrealms/cli/pkg/project"
+	"github.com/stretchr/testify/require"
+)
+
+// testTemplateJar creates a runtime template JAR with the given files, in order.
+func testTemplateJar(t *testing.T, files ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for i, name := range files {
+		w, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: time.Now().Add(time.Duration(i) * time.Hour),
+		})
+		require.NoError(t, err)
+		_, err = w.Write([]byte("contents of " + name))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func writeTestJar(t *testing.T, templateJar []byte, modTime time.Time) []byte {
+	t.Helper()
+	description := "A test plugin"
+	plugin := &pluginyml.Plugin{
+		Name:        "TestPlugin",
+		Version:     "1.0.0",
+		Main:        build.JarMainClass,
+		Description: &description,
+		Permissions: map[string]pluginyml.Permission{
+			"test.b": {Description: &description},
+			"test.a": {Description: &description},
+			"test.c": {Description: &description},
+		},
+	}
+	metadata := &build.BuildMetadata{
+		CliVersion: "1.2.3",
+		BuildTime:  modTime,
+	}
+
+	var buf bytes.Buffer
+	err := build.WriteJarFile(&buf, templateJar, strings.NewReader("console.log('hello');"), plugin, metadata, modTime, nil)
+	require.NoError(t, err)
+	return buf.Bytes()
+}
+
+func TestWriteJarFileReproducible(t *testing.T) {
+	modTime := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	templateJar := testTemplateJar(t, "io/customrealms/MainPlugin.class", "META-INF/LICENSE", "a.txt")
+
+	// Two builds with the same inputs produce identical bytes
+	first := writeTestJar(t, templateJar, modTime)
+	second := writeTestJar(t, templateJar, modTime)
+	require.Equal(t, first, second)
+
+	diff, err := build.DiffJars(first, second)
+	require.NoError(t, err)
+	require.Empty(t, diff)
+
+	// The entries are sorted, with the manifest first, and have a fixed modification time
+	zr, err := zip.NewReader(bytes.NewReader(first), int64(len(first)))
+	require.NoError(t, err)
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		require.True(t, f.Modified.Equal(modTime), "modification time of %s", f.Name)
+	}
+	require.Equal(t, []string{
+		"META-INF/MANIFEST.MF",
+		"META-INF/LICENSE",
+		"a.txt",
+		"crx-build.json",
+		"io/customrealms/MainPlugin.class",
+		"plugin.js",
+		"plugin.yml",
+	}, names)
+}
+
+func TestWriteJarFileTimeRange(t *testing.T) {
+	templateJar := testTemplateJar(t, "a.txt")
+	tests := []struct {
+		modTime time.Time
+		want    time.Time
+	}{
+		{time.Unix(0, 0).UTC(), time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2200, time.June, 1, 0, 0, 0, 0, time.UTC), time.Date(2107, time.December, 31, 23, 59, 58, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		// Times out of the range of ZIP files are clamped to it
+		jar := writeTestJar(t, templateJar, test.modTime)
+		zr, err := zip.NewReader(bytes.NewReader(jar), int64(len(jar)))
+		require.NoError(t, err)
+		for _, f := range zr.File {
+			require.True(t, f.Modified.Equal(test.want), "modification time of %s is %s", f.Name, f.Modified)
+		}
+	}
+}
+
+func TestDiffJars(t *testing.T) {
+	modTime := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	first := writeTestJar(t, testTemplateJar(t, "a.txt"), modTime)
+	second := writeTestJar(t, testTemplateJar(t, "b.txt"), modTime.Add(time.Hour))
+
+	diff, err := build.DiffJars(first, second)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a.txt", "b.txt", "crx-build.json"}, diff)
+
+	// Directories and names that aren't valid paths are compared too
+	withDirs := func(contents string) []byte {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		for _, name := range []string{"META-INF/", "../outside.txt", "a//b.txt"} {
+			w, err := zw.Create(name)
+			require.NoError(t, err)
+			if !strings.HasSuffix(name, "/") {
+				_, err = w.Write([]byte(contents))
+				require.NoError(t, err)
+			}
+		}
+		require.NoError(t, zw.Close())
+		return buf.Bytes()
+	}
+	diff, err = build.DiffJars(withDirs("one"), withDirs("one"))
+	require.NoError(t, err)
+	require.Empty(t, diff)
+	diff, err = build.DiffJars(withDirs("one"), withDirs("two"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"../outside.txt", "a//b.txt"}, diff)
+}
+
+func TestWriteJarFileBuildJdkSpec(t *testing.T) {
+	// Create a template whose manifest says which JDK compiled it
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("META-INF/MANIFEST.MF")
+	require.NoError(t, err)
+	_, err = w.Write([]byte("Manifest-Version: 1.0\r\nBuild-Jdk-Spec: 17\r\nX-Runtime: yes\r\n\r\n"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	jar := writeTestJar(t, buf.Bytes(), time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC))
+	zr, err := zip.NewReader(bytes.NewReader(jar), int64(len(jar)))
+	require.NoError(t, err)
+
+	// The plugin's manifest has the JDK of the runtime, and keeps the other attributes
+	f, err := zr.Open(manifest.Filename)
+	require.NoError(t, err)
+	data, err := io.ReadAll(f)
+	require.NoError(t, err)
+	m, err := manifest.Parse(data)
+	require.NoError(t, err)
+	value, _ := m.Main.Get("Build-Jdk-Spec")
+	require.Equal(t, "17", value)
+	value, _ = m.Main.Get("X-Runtime")
+	require.Equal(t, "yes", value)
+
+	// The JDK of the runtime is in the build metadata
+	info, err := build.InspectJar(zr)
+	require.NoError(t, err)
+	require.Equal(t, "17", info.Metadata.Runtime.BuildJdkSpec)
+	require.Equal(t, "17", info.Runtime.BuildJdkSpec)
+}
+
+func TestJarActionBudget(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"name": "test", "version": "1.0.0"}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "template.jar"), testTemplateJar(t, "a.txt"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "bundle.js"), []byte("console.log('hello');"), 0644))
+	action := func(budget project.Size) *build.JarAction {
+		return &build.JarAction{
+			Project:     project.New(dir),
+			JarTemplate: &build.FileJarTemplate{Filename: filepath.Join(dir, "template.jar")},
+			CliVersion:  "1.2.3",
+			BundleFile:  filepath.Join(dir, "bundle.js"),
+			OutputFile:  filepath.Join(dir, "dist", "test.jar"),
+			Checksums:   true,
+			Budget:      budget,
+		}
+	}
+
+	// A JAR file over budget isn't written, nor are its checksums
+	err := action(100).Run(context.Background())
+	require.ErrorContains(t, err, "exceeds the budget of 100B")
+	entries, err := os.ReadDir(filepath.Join(dir, "dist"))
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	// A JAR file within budget is written
+	require.NoError(t, action(1<<20).Run(context.Background()))
+	_, err = os.Stat(filepath.Join(dir, "dist", "test.jar"))
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Join(dir, "dist", "test.jar.sha256"))
+	require.NoError(t, err)
+}
@@ -0,0 +1,61 @@
+package build
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// BuildMetadataFilename is the name of the file in the JAR that holds the build metadata.
+const BuildMetadataFilename = "crx-build.json"
+
+// BuildMetadata describes how a plugin JAR file was built.
+type BuildMetadata struct {
+	// CliVersion is the version of the CLI that built the JAR.
+	CliVersion string `json:"cliVersion"`
+	// Runtime identifies the runtime template JAR the plugin was built on.
+	Runtime RuntimeMetadata `json:"runtime"`
+	// ApiVersion is the Minecraft API version the plugin targets.
+	ApiVersion string `json:"apiVersion,omitempty"`
+	// Git is the state of the project's Git repository, if it has one.
+	Git *GitMetadata `json:"git,omitempty"`
+	// BuiltBy is the name of the user that built the JAR.
+	BuiltBy string `json:"builtBy,omitempty"`
+	// BuildTime is the time the JAR was built.
+	BuildTime time.Time `json:"buildTime"`
+	// BundleSha256 is the hex-encoded SHA-256 checksum of the plugin.js bundle.
+	BundleSha256 string `json:"bundleSha256"`
+}
+
+type RuntimeMetadata struct {
+	// Version is the version or tag of the runtime template.
+	Version string `json:"version"`
+	// Sha256 is the hex-encoded SHA-256 checksum of the runtime template JAR.
+	Sha256 string `json:"sha256"`
+	// BuildJdkSpec is the Java version the runtime was compiled with, from the
+	// "Build-Jdk-Spec" attribute of its manifest.
+	BuildJdkSpec string `json:"buildJdkSpec,omitempty"`
+}
+
+type GitMetadata struct {
+	// Commit is the full hash of the commit the JAR was built from.
+	Commit string `json:"commit"`
+	// Dirty is true if the working tree had uncommitted changes.
+	Dirty bool `json:"dirty"`
+}
+
+// ReadBuildMetadata reads the build metadata from a plugin JAR file. If the JAR doesn't
+// contain any build metadata, it returns nil.
+func ReadBuildMetadata(zr *zip.Reader) (*BuildMetadata, error) {
+	data, err := readZipEntry(zr, BuildMetadataFilename)
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	var metadata BuildMetadata
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", BuildMetadataFilename, err)
+	}
+	return &metadata, nil
+}
@@ -1,6 +1,7 @@
 package build
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
@@ -11,7 +12,13 @@ import (
 
 const JarMainClass = "io.customrealms.MainPlugin"
 
-func GeneratePluginYML(project project.Project, apiVersion string) (*pluginyml.Plugin, error) {
+func GeneratePluginYML(ctx context.Context, project project.Project, apiVersion string) (*pluginyml.Plugin, error) {
+	return GenerateTargetPluginYML(ctx, project, nil, apiVersion)
+}
+
+// GenerateTargetPluginYML generates the plugin.yml file for a target of the project. If the
+// target is nil, it generates the plugin.yml file of the project itself.
+func GenerateTargetPluginYML(ctx context.Context, project project.Project, target *project.Target, apiVersion string) (*pluginyml.Plugin, error) {
 	// Read the package.json file
 	packageJSON, err := project.PackageJSON()
 	if err != nil {
@@ -19,7 +26,7 @@ func GeneratePluginYML(project project.Project, apiVersion string) (*pluginyml.P
 	}
 
 	// Read the plugin.yml file
-	plugin, err := project.PluginYML()
+	plugin, err := project.TargetPluginYML(ctx, target)
 	if err != nil {
 		return nil, fmt.Errorf("getting plugin.yml: %w", err)
 	}
@@ -0,0 +1,184 @@
+package build
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/customrealms/cli/pkg/manifest"
+)
+
+// defaultModTime is the modification time of every JAR entry when SOURCE_DATE_EPOCH isn't
+// set. It's the earliest time the ZIP format can represent.
+var defaultModTime = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// sourceDateEpoch returns the time from the SOURCE_DATE_EPOCH environment variable, if it's
+// set to a valid Unix timestamp. See https://reproducible-builds.org/specs/source-date-epoch/
+func sourceDateEpoch() (time.Time, bool) {
+	value, err := strconv.ParseInt(os.Getenv("SOURCE_DATE_EPOCH"), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(value, 0).UTC(), true
+}
+
+// entryModTime returns the modification time to use for every JAR entry.
+func entryModTime() time.Time {
+	if epoch, ok := sourceDateEpoch(); ok {
+		return epoch
+	}
+	return defaultModTime
+}
+
+// defaultBuildTime returns the build time recorded in the build metadata when none is given.
+func defaultBuildTime() time.Time {
+	if epoch, ok := sourceDateEpoch(); ok {
+		return epoch
+	}
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+// jarEntry is a single file to be written to a JAR file. It's either copied from another
+// ZIP file, or written from data in memory.
+type jarEntry struct {
+	name string
+	file *zip.File
+	data []byte
+}
+
+// contents returns the uncompressed contents of the entry.
+func (e *jarEntry) contents() ([]byte, error) {
+	if e.file == nil {
+		return e.data, nil
+	}
+	reader, err := e.file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+func (e *jarEntry) write(zw *zip.Writer, modTime time.Time) error {
+	// Copy the entry from another ZIP file without recompressing it. The extra fields are
+	// dropped, since they can hold timestamps and other platform-specific details. Raw
+	// entries only use the MS-DOS timestamp fields, so those are set directly.
+	if e.file != nil {
+		header := e.file.FileHeader
+		header.Modified = time.Time{}
+		header.ModifiedDate, header.ModifiedTime = msDosTime(modTime)
+		header.Extra = nil
+		header.Comment = ""
+		reader, err := e.file.OpenRaw()
+		if err != nil {
+			return err
+		}
+		w, err := zw.CreateRaw(&header)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(w, reader)
+		return err
+	}
+
+	// Write the entry from memory
+	header := zip.FileHeader{
+		Name:   e.name,
+		Method: zip.Deflate,
+	}
+	header.ModifiedDate, header.ModifiedTime = msDosTime(modTime)
+	w, err := zw.CreateHeader(&header)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(e.data)
+	return err
+}
+
+// msDosTime converts a time to the MS-DOS date and time format used in ZIP headers. Times
+// outside of the range of the format, from 1980 to 2107, are clamped to it like archive/zip
+// does, e.g. for SOURCE_DATE_EPOCH=0.
+func msDosTime(t time.Time) (uint16, uint16) {
+	minTime := time.Date(1980, time.January, 1, 0, 0, 0, 0, t.Location())
+	maxTime := time.Date(2107, time.December, 31, 23, 59, 58, 0, t.Location())
+	if t.Before(minTime) {
+		t = minTime
+	} else if t.After(maxTime) {
+		t = maxTime
+	}
+	date := uint16(t.Day() + int(t.Month())<<5 + (t.Year()-1980)<<9)
+	tm := uint16(t.Second()/2 + t.Minute()<<5 + t.Hour()<<11)
+	return date, tm
+}
+
+// sortJarEntries sorts JAR entries into a stable order. The manifest comes first, followed
+// by the rest of the META-INF directory, so that they can be found by streaming JAR readers.
+// All other entries are sorted by name.
+func sortJarEntries(entries []jarEntry) {
+	rank := func(name string) int {
+		switch {
+		case name == manifest.Filename:
+			return 0
+		case strings.HasPrefix(name, "META-INF/"):
+			return 1
+		default:
+			return 2
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		ri, rj := rank(entries[i].name), rank(entries[j].name)
+		if ri != rj {
+			return ri < rj
+		}
+		return entries[i].name < entries[j].name
+	})
+}
+
+// DiffJars compares two JAR files and returns the names of the entries that differ between
+// them, including entries that only exist in one of them.
+func DiffJars(a, b []byte) ([]string, error) {
+	readEntries := func(data []byte) (map[string][]byte, error) {
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		entries := make(map[string][]byte)
+		// Entries are read through the file list rather than by name, so directories and
+		// names that aren't valid paths are compared too
+		for _, f := range zr.File {
+			contents, err := readZipFile(f)
+			if err != nil {
+				return nil, err
+			}
+			entries[f.Name] = contents
+		}
+		return entries, nil
+	}
+	entriesA, err := readEntries(a)
+	if err != nil {
+		return nil, err
+	}
+	entriesB, err := readEntries(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var diff []string
+	for name, contents := range entriesA {
+		if other, ok := entriesB[name]; !ok || !bytes.Equal(contents, other) {
+			diff = append(diff, name)
+		}
+	}
+	for name := range entriesB {
+		if _, ok := entriesA[name]; !ok {
+			diff = append(diff, name)
+		}
+	}
+	sort.Strings(diff)
+	return diff, nil
+}
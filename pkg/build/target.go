@@ -0,0 +1,151 @@
+package build
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/customrealms/cli/pkg/pluginyml"
+	"github.com/customrealms/cli/pkg/project"
+)
+
+// DefaultEntrypoint is the TypeScript entrypoint of projects that don't declare one.
+const DefaultEntrypoint = "./src/main.ts"
+
+// buildTarget is a plugin JAR file produced by a build.
+type buildTarget struct {
+	// target is the project target, or nil for projects without targets.
+	target *project.Target
+	// entrypoint is the TypeScript entrypoint, relative to the project directory.
+	entrypoint string
+	// outputFile is the path of the output JAR file.
+	outputFile string
+}
+
+// name returns the name of the target, used to name its bundle.
+func (t *buildTarget) name() string {
+	if t.target == nil {
+		return "bundle"
+	}
+	return t.target.Name
+}
+
+// describe qualifies a description of a build output with the target name.
+func (t *buildTarget) describe(what string) string {
+	if t.target == nil {
+		return what
+	}
+	return fmt.Sprintf("%s of target %q", what, t.target.Name)
+}
+
+// wrapError adds the target name to an error.
+func (t *buildTarget) wrapError(err error) error {
+	if t.target == nil {
+		return err
+	}
+	return fmt.Errorf("target %q: %w", t.target.Name, err)
+}
+
+// resolveTargets returns the targets selected for the build. Projects without targets
+// produce a single JAR file at outputFile, which may be empty. Otherwise, outputFile is only allowed when a
+// single target is selected, and replaces the target's output path.
+func resolveTargets(
+	p project.Project,
+	config *project.Config,
+	pluginYML *pluginyml.Plugin,
+	names []string,
+	outputFile string,
+) ([]buildTarget, error) {
+	// Projects without targets build a single plugin
+	if len(config.Targets) == 0 {
+		if len(names) > 0 {
+			return nil, errors.New("project doesn't declare any targets")
+		}
+		entrypoint := DefaultEntrypoint
+		if pluginYML != nil && strings.HasSuffix(pluginYML.Main, ".ts") {
+			entrypoint = pluginYML.Main
+		}
+		return []buildTarget{{entrypoint: entrypoint, outputFile: outputFile}}, nil
+	}
+
+	// Select the targets by name, or all of them
+	var selected []*project.Target
+	if len(names) == 0 {
+		for i := range config.Targets {
+			selected = append(selected, &config.Targets[i])
+		}
+	} else {
+		for _, name := range names {
+			target := config.Target(name)
+			if target == nil {
+				return nil, fmt.Errorf("unknown target %q", name)
+			}
+			selected = append(selected, target)
+		}
+	}
+	if outputFile != "" && len(selected) > 1 {
+		return nil, errors.New("output file can only be set when building a single target")
+	}
+
+	targets := make([]buildTarget, 0, len(selected))
+	for _, target := range selected {
+		bt := buildTarget{
+			target:     target,
+			entrypoint: target.Entrypoint,
+			outputFile: outputFile,
+		}
+		if bt.outputFile == "" {
+			bt.outputFile = TargetOutputFile(p, target)
+		}
+		targets = append(targets, bt)
+	}
+	return targets, nil
+}
+
+// DefaultOutputFile returns the path of the JAR file built for a project without targets.
+// It's "dist/<name>.jar" in the project directory, named after the package without its
+// scope, or after the plugin if there's no package.json file.
+func DefaultOutputFile(p project.Project, pluginYML *pluginyml.Plugin) (string, error) {
+	packageJSON, err := p.PackageJSON()
+	if err != nil {
+		return "", err
+	}
+	var name string
+	if packageJSON != nil && packageJSON.Name != "" {
+		name = path.Base(packageJSON.Name)
+	} else if pluginYML != nil && pluginYML.Name != "" {
+		name = pluginYML.Name
+	} else {
+		return "", errors.New("missing output file, and the project has no name to default to")
+	}
+	return filepath.Join(p.Dir(), "dist", name+".jar"), nil
+}
+
+// packageFileName returns a name for the files of a project that's unique within its
+// workspace: the package name with its scope, or the name of the project directory if
+// there's no package.json file.
+func packageFileName(p project.Project) (string, error) {
+	packageJSON, err := p.PackageJSON()
+	if err != nil {
+		return "", err
+	}
+	if packageJSON == nil || packageJSON.Name == "" {
+		return filepath.Base(p.Dir()), nil
+	}
+	return strings.ReplaceAll(strings.TrimPrefix(packageJSON.Name, "@"), "/", "-"), nil
+}
+
+// TargetOutputFile returns the path of the JAR file built for a target. It defaults to
+// "dist/<name>.jar" in the project directory.
+func TargetOutputFile(p project.Project, target *project.Target) string {
+	output := target.Output
+	if output == "" {
+		output = filepath.Join("dist", target.Name+".jar")
+	}
+	if filepath.IsAbs(output) {
+		return output
+	}
+	return filepath.Join(p.Dir(), output)
+}
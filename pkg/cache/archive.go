@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Export writes the entries of the cache to a gzipped tar archive, which Import reads into
+// another cache. The archive starts with the index of the entries, followed by their files.
+func (c *Cache) Export(w io.Writer) ([]Entry, error) {
+	entries, err := c.List()
+	if err != nil {
+		return nil, err
+	}
+	index, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	now := time.Now()
+	err = tw.WriteHeader(&tar.Header{
+		Name:    indexFilename,
+		Mode:    0644,
+		Size:    int64(len(index)),
+		ModTime: now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tw.Write(index); err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if err := c.exportEntry(tw, entry); err != nil {
+			return nil, fmt.Errorf("exporting %s: %w", entry.Key, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// exportEntry writes the file of an entry to a tar archive.
+func (c *Cache) exportEntry(tw *tar.Writer, entry Entry) error {
+	f, err := os.Open(c.Path(entry.Key))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	err = tw.WriteHeader(&tar.Header{
+		Name:    entry.Key,
+		Mode:    0644,
+		Size:    stat.Size(),
+		ModTime: stat.ModTime(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// Import reads the entries of an archive written by Export into the cache, replacing the
+// entries with the same keys. Entries are checked against the checksums in the archive's
+// index before they replace anything, and the import fails at the first corrupt one.
+func (c *Cache) Import(r io.Reader) ([]Entry, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading cache archive: %w", err)
+	}
+	defer gr.Close()
+	tr := tar.NewReader(gr)
+
+	index := make(map[string]Entry)
+	var imported []Entry
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return imported, fmt.Errorf("reading cache archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		// Read the index
+		if header.Name == indexFilename {
+			var entries []Entry
+			if err := json.NewDecoder(tr).Decode(&entries); err != nil {
+				return imported, fmt.Errorf("decoding cache archive index: %w", err)
+			}
+			for _, entry := range entries {
+				index[entry.Key] = entry
+			}
+			continue
+		}
+
+		// Store the entry if it matches its checksum, keeping the existing one otherwise
+		entry := index[header.Name]
+		if _, err := c.write(header.Name, tr, entry.Meta, entry.SHA256); err != nil {
+			return imported, fmt.Errorf("importing %s: %w", header.Name, err)
+		}
+		stored, err := c.Stat(header.Name)
+		if err != nil {
+			return imported, err
+		}
+		imported = append(imported, *stored)
+	}
+
+	// Make room for the imported entries, once they're all stored
+	if c.MaxSize > 0 {
+		keys := make([]string, len(imported))
+		for i, entry := range imported {
+			keys[i] = entry.Key
+		}
+		if _, err := c.prune(c.MaxSize, keys...); err != nil {
+			return imported, err
+		}
+	}
+	return imported, nil
+}
@@ -0,0 +1,361 @@
+// Package cache stores downloaded files in the user's cache directory, so they're only
+// downloaded once and available offline.
+package cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// DirName is the name of the CLI's directory in the user's cache directory.
+const DirName = "cr-cli-cache"
+
+// DefaultMaxSize is the maximum size of the cache opened with New, in bytes. When it's
+// exceeded, the least recently used entries are removed. Zero means there's no limit.
+var DefaultMaxSize int64
+
+// Cache is a directory of cached files, identified by slash-separated keys like
+// "templates/<hash>.zip". An index records the checksum of each entry, and when it was
+// last used.
+type Cache struct {
+	dir string
+	// MaxSize is the maximum size of the cache, in bytes. When it's exceeded after adding
+	// an entry, the least recently used entries are removed. Zero means there's no limit.
+	MaxSize int64
+}
+
+// New opens the cache in the user's cache directory, creating it if needed.
+func New() (*Cache, error) {
+	dir, err := DefaultDir()
+	if err != nil {
+		return nil, err
+	}
+	c, err := NewAt(dir)
+	if err != nil {
+		return nil, err
+	}
+	c.MaxSize = DefaultMaxSize
+	return c, nil
+}
+
+// Existing opens the cache in the user's cache directory without creating it, for queries
+// that shouldn't write anything. If there's no cache yet, the error is os.ErrNotExist.
+func Existing() (*Cache, error) {
+	dir, err := DefaultDir()
+	if err != nil {
+		return nil, err
+	}
+	stat, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !stat.IsDir() {
+		return nil, fmt.Errorf("cache %s is not a directory", dir)
+	}
+	return &Cache{dir: dir, MaxSize: DefaultMaxSize}, nil
+}
+
+// DefaultDir returns the directory of the cache in the user's cache directory. It may not
+// exist yet.
+func DefaultDir() (string, error) {
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("finding user cache directory: %w", err)
+	}
+	return filepath.Join(userCacheDir, DirName), nil
+}
+
+// NewAt opens the cache in a directory, creating it if needed.
+func NewAt(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, fmt.Errorf("creating cache directory: %w", err)
+	}
+	return &Cache{dir: dir}, nil
+}
+
+// Dir returns the directory of the cache.
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Path returns the path of the file of a cache entry.
+func (c *Cache) Path(key string) string {
+	return filepath.Join(c.dir, filepath.FromSlash(key))
+}
+
+// Open opens the file of a cache entry, and records that the entry was used, if the index
+// can be written. If there's no such entry, the error is os.ErrNotExist.
+func (c *Cache) Open(key string) (*os.File, error) {
+	f, err := os.Open(c.Path(key))
+	if err != nil {
+		return nil, err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if stat.IsDir() {
+		f.Close()
+		return nil, fmt.Errorf("cache entry %s is a directory", key)
+	}
+	c.touch(key)
+	return f, nil
+}
+
+// ReadFile reads the contents of a cache entry, and records that the entry was used. If
+// there's no such entry, the error is os.ErrNotExist.
+func (c *Cache) ReadFile(key string) ([]byte, error) {
+	f, err := c.Open(key)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// Stat returns the entry for a key. If there's no such entry, the error is os.ErrNotExist.
+func (c *Cache) Stat(key string) (*Entry, error) {
+	stat, err := os.Stat(c.Path(key))
+	if err != nil {
+		return nil, err
+	}
+	if stat.IsDir() {
+		return nil, fmt.Errorf("cache entry %s is a directory", key)
+	}
+	return fileEntry(c.readIndex(), key, stat), nil
+}
+
+// Has reports whether there's a cache entry for a key.
+func (c *Cache) Has(key string) bool {
+	stat, err := os.Stat(c.Path(key))
+	return err == nil && !stat.IsDir()
+}
+
+// Meta describes what a cache entry is, for listing and cleaning the cache.
+type Meta struct {
+	// Kind is the kind of file, like "server" or "template".
+	Kind string `json:"kind,omitempty"`
+	// Version is the version of the file, if it has one.
+	Version string `json:"version,omitempty"`
+	// Source is where the file was downloaded from.
+	Source string `json:"source,omitempty"`
+}
+
+// Put stores the contents of a reader as a cache entry, and returns the path of its file.
+// The contents are written to a temporary file first, so an interrupted download never
+// leaves a partial entry behind. If the cache gets larger than its maximum size, the least
+// recently used entries are removed, except for this one.
+func (c *Cache) Put(key string, r io.Reader, meta Meta) (string, error) {
+	return c.put(key, r, meta, "")
+}
+
+// put stores a cache entry like Put. If checksum isn't empty, the entry is only stored if
+// its contents have this hex-encoded SHA-256 checksum, and the existing entry is kept
+// otherwise.
+func (c *Cache) put(key string, r io.Reader, meta Meta, checksum string) (string, error) {
+	filename, err := c.write(key, r, meta, checksum)
+	if err != nil {
+		return "", err
+	}
+	if c.MaxSize > 0 {
+		if _, err := c.prune(c.MaxSize, key); err != nil {
+			return "", err
+		}
+	}
+	return filename, nil
+}
+
+// write stores a cache entry like put, without removing entries if the cache gets larger
+// than its maximum size.
+func (c *Cache) write(key string, r io.Reader, meta Meta, checksum string) (string, error) {
+	if key == "" || key == indexFilename || strings.HasPrefix(key, "/") || strings.HasPrefix(key, ".") || strings.Contains(key, "..") {
+		return "", fmt.Errorf("invalid cache key %q", key)
+	}
+	filename := c.Path(key)
+	if err := os.MkdirAll(filepath.Dir(filename), 0777); err != nil {
+		return "", err
+	}
+
+	// Write the contents to a temporary file
+	tempFile, err := os.CreateTemp(filepath.Dir(filename), ".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tempFile.Name())
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tempFile, hash), r)
+	if err != nil {
+		tempFile.Close()
+		return "", err
+	}
+	if err := tempFile.Close(); err != nil {
+		return "", err
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+	if checksum != "" && sum != checksum {
+		return "", fmt.Errorf("%w: checksum is %s, expected %s", ErrCorrupt, sum, checksum)
+	}
+
+	// Move the temporary file in place
+	if err := os.Rename(tempFile.Name(), filename); err != nil {
+		return "", err
+	}
+
+	// Record the entry. The file is usable without it, so a failure is only reported: the
+	// entry is missing its checksum and metadata.
+	now := time.Now()
+	err = c.updateIndex(func(index map[string]*Entry) {
+		index[key] = &Entry{
+			Key:      key,
+			Meta:     meta,
+			Size:     size,
+			SHA256:   sum,
+			Created:  now,
+			LastUsed: now,
+		}
+	})
+	if err != nil {
+		log.Printf("Couldn't record cache entry %s: %s", key, err)
+	}
+	return filename, nil
+}
+
+// fetches de-duplicates the concurrent loads of an entry within the process.
+var fetches singleflight.Group
+
+// Load opens the file of a cache entry, and fetches it first if it isn't cached. The entry
+// is fetched once, even when it's loaded concurrently by several goroutines or processes:
+// the others wait for it to be stored. Cancelling the context stops waiting for the entry,
+// but the fetch goes on for the other goroutines loading it.
+func (c *Cache) Load(
+	ctx context.Context,
+	key string,
+	meta Meta,
+	fetch func(ctx context.Context) (io.ReadCloser, error),
+) (*os.File, error) {
+	if f, err := c.Open(key); !errors.Is(err, os.ErrNotExist) {
+		return f, err
+	}
+
+	// The fetch is shared by the goroutines waiting for it, so it doesn't run with the
+	// context of the first one
+	fetched := fetches.DoChan(c.Path(key), func() (any, error) {
+		return nil, c.fetch(context.WithoutCancel(ctx), key, meta, fetch)
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result := <-fetched:
+		if result.Err != nil {
+			return nil, result.Err
+		}
+	}
+	return c.Open(key)
+}
+
+// fetch fetches and stores a cache entry, holding its lock, unless another process stored
+// it first.
+func (c *Cache) fetch(
+	ctx context.Context,
+	key string,
+	meta Meta,
+	fetch func(ctx context.Context) (io.ReadCloser, error),
+) error {
+	lock, err := c.lock(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	if c.Has(key) {
+		return nil
+	}
+
+	r, err := fetch(ctx)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	_, err = c.Put(key, r, meta)
+	return err
+}
+
+// Fetch fetches the latest contents of a cache entry from its source and stores them, so
+// the cached copy can be used when a later fetch fails, e.g. when offline. It returns the
+// contents and the metadata of the entry. The fetch returns the metadata along with the
+// contents, since some of it, like the version, may only be known once fetched.
+//
+// When the fetch fails and the entry is cached, the cached copy is returned instead. Without
+// a usable cache, the contents are fetched without caching them. what describes the entry in
+// the messages printed when falling back to the cached copy or failing to cache it, e.g.
+// "the template registry".
+func Fetch(
+	ctx context.Context,
+	key string,
+	what string,
+	fetch func(ctx context.Context) ([]byte, Meta, error),
+) ([]byte, Meta, error) {
+	// Fetch the latest contents
+	c, cacheErr := New()
+	data, meta, err := fetch(ctx)
+
+	// Fall back to the cached copy
+	if err != nil {
+		if cacheErr != nil || ctx.Err() != nil {
+			return nil, Meta{}, err
+		}
+		entry, statErr := c.Stat(key)
+		if statErr != nil {
+			return nil, Meta{}, err
+		}
+		cached, readErr := c.ReadFile(key)
+		if readErr != nil {
+			return nil, Meta{}, err
+		}
+		fmt.Printf("Couldn't download %s (%s), using the cached copy.\n", what, err)
+		return cached, entry.Meta, nil
+	}
+
+	// Cache the contents for later
+	if cacheErr == nil {
+		if err := c.store(ctx, key, data, meta); err != nil {
+			fmt.Printf("Couldn't cache %s: %s\n", what, err)
+		}
+	}
+	return data, meta, nil
+}
+
+// store stores the contents of a cache entry, holding its lock like Load, so it doesn't
+// replace an entry while another process stores it. Readers don't need the lock, since Put
+// moves the complete file in place in one step.
+func (c *Cache) store(ctx context.Context, key string, data []byte, meta Meta) error {
+	lock, err := c.lock(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	_, err = c.Put(key, bytes.NewReader(data), meta)
+	return err
+}
+
+// Remove removes a cache entry. It's not an error if there's no such entry.
+func (c *Cache) Remove(key string) error {
+	if err := os.Remove(c.Path(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return c.updateIndex(func(index map[string]*Entry) {
+		delete(index, key)
+	})
+}
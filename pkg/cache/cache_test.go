@@ -0,0 +1,300 @@
+package cache_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/customrealms/cli/pkg/cache"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	c, err := cache.NewAt(t.TempDir())
+	require.NoError(t, err)
+
+	_, err = c.Open("templates/a.zip")
+	require.True(t, errors.Is(err, os.ErrNotExist))
+	require.False(t, c.Has("templates/a.zip"))
+
+	filename, err := c.Put("templates/a.zip", strings.NewReader("contents"), cache.Meta{Kind: "template"})
+	require.NoError(t, err)
+	require.Equal(t, c.Path("templates/a.zip"), filename)
+	require.True(t, c.Has("templates/a.zip"))
+
+	f, err := c.Open("templates/a.zip")
+	require.NoError(t, err)
+	data, err := io.ReadAll(f)
+	f.Close()
+	require.NoError(t, err)
+	require.Equal(t, "contents", string(data))
+
+	require.NoError(t, c.Remove("templates/a.zip"))
+	require.NoError(t, c.Remove("templates/a.zip"))
+	require.False(t, c.Has("templates/a.zip"))
+
+	_, err = c.Put("../outside", strings.NewReader(""), cache.Meta{})
+	require.ErrorContains(t, err, "invalid cache key")
+}
+
+func TestCacheIndex(t *testing.T) {
+	dir := t.TempDir()
+	c, err := cache.NewAt(dir)
+	require.NoError(t, err)
+
+	_, err = c.Put("paper-1.21.4.jar", strings.NewReader("server"), cache.Meta{Kind: "server", Version: "1.21.4"})
+	require.NoError(t, err)
+	_, err = c.Put("templates/a.zip", strings.NewReader("template"), cache.Meta{Kind: "template"})
+	require.NoError(t, err)
+
+	// Files stored by older versions are listed too
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "paper-1.20.jar"), []byte("old"), 0666))
+
+	entries, err := c.List()
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	require.Equal(t, "paper-1.20.jar", entries[0].Key)
+	require.Equal(t, "", entries[0].Kind)
+	require.Equal(t, "paper-1.21.4.jar", entries[1].Key)
+	require.Equal(t, "1.21.4", entries[1].Version)
+	require.Equal(t, int64(6), entries[1].Size)
+
+	// Entries are checked against their checksums
+	require.NoError(t, c.Verify(entries[1]))
+	require.ErrorIs(t, c.Verify(entries[0]), cache.ErrNoChecksum)
+	require.NoError(t, os.WriteFile(c.Path("paper-1.21.4.jar"), []byte("broken"), 0666))
+	require.ErrorIs(t, c.Verify(entries[1]), cache.ErrCorrupt)
+
+	// Entries are cleaned with a filter
+	removed, err := c.Clean(func(entry cache.Entry) bool { return entry.Kind == "server" })
+	require.NoError(t, err)
+	require.Len(t, removed, 1)
+	require.False(t, c.Has("paper-1.21.4.jar"))
+	require.True(t, c.Has("paper-1.20.jar"))
+
+	// Entries of files removed by hand are forgotten
+	require.NoError(t, os.Remove(c.Path("templates/a.zip")))
+	missing, err := c.Tidy()
+	require.NoError(t, err)
+	require.Equal(t, []string{"templates/a.zip"}, missing)
+	require.ErrorIs(t, c.Verify(cache.Entry{Key: "templates/a.zip"}), os.ErrNotExist)
+}
+
+func TestCacheBrokenIndex(t *testing.T) {
+	dir := t.TempDir()
+	c, err := cache.NewAt(dir)
+	require.NoError(t, err)
+
+	// An index that can't be written doesn't keep the files from being stored and used
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "index.json"), 0777))
+	_, err = c.Put("a", strings.NewReader("aaaa"), cache.Meta{})
+	require.NoError(t, err)
+	data, err := c.ReadFile("a")
+	require.NoError(t, err)
+	require.Equal(t, "aaaa", string(data))
+}
+
+func TestCachePrune(t *testing.T) {
+	c, err := cache.NewAt(t.TempDir())
+	require.NoError(t, err)
+	c.MaxSize = 10
+
+	_, err = c.Put("a", strings.NewReader("aaaa"), cache.Meta{})
+	require.NoError(t, err)
+	time.Sleep(10 * time.Millisecond)
+	_, err = c.Put("b", strings.NewReader("bbbb"), cache.Meta{})
+	require.NoError(t, err)
+	time.Sleep(10 * time.Millisecond)
+
+	// Using an entry makes it the most recently used
+	_, err = c.ReadFile("a")
+	require.NoError(t, err)
+
+	// Going over the maximum size removes the least recently used entry
+	_, err = c.Put("c", strings.NewReader("cccc"), cache.Meta{})
+	require.NoError(t, err)
+	require.True(t, c.Has("a"))
+	require.False(t, c.Has("b"))
+	require.True(t, c.Has("c"))
+
+	// Pruning shrinks the cache to a size
+	removed, err := c.Prune(4)
+	require.NoError(t, err)
+	require.Len(t, removed, 1)
+	require.Equal(t, "a", removed[0].Key)
+}
+
+func TestCacheLoad(t *testing.T) {
+	c, err := cache.NewAt(t.TempDir())
+	require.NoError(t, err)
+
+	// Concurrent loads fetch the entry once
+	var fetches atomic.Int32
+	fetch := func(ctx context.Context) (io.ReadCloser, error) {
+		fetches.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		return io.NopCloser(strings.NewReader("server")), nil
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f, err := c.Load(context.Background(), "paper-1.21.4.jar", cache.Meta{Kind: "server"}, fetch)
+			require.NoError(t, err)
+			defer f.Close()
+			data, err := io.ReadAll(f)
+			require.NoError(t, err)
+			require.Equal(t, "server", string(data))
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, int32(1), fetches.Load())
+
+	// Cancelling the first load doesn't fail the others waiting for the fetch
+	started := make(chan struct{})
+	release := make(chan struct{})
+	slowFetch := func(ctx context.Context) (io.ReadCloser, error) {
+		close(started)
+		<-release
+		return io.NopCloser(strings.NewReader("slow")), ctx.Err()
+	}
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	firstErr := make(chan error)
+	go func() {
+		_, err := c.Load(firstCtx, "slow.jar", cache.Meta{}, slowFetch)
+		firstErr <- err
+	}()
+	<-started
+	secondFile := make(chan *os.File)
+	go func() {
+		f, err := c.Load(context.Background(), "slow.jar", cache.Meta{}, slowFetch)
+		require.NoError(t, err)
+		secondFile <- f
+	}()
+	cancelFirst()
+	require.ErrorIs(t, <-firstErr, context.Canceled)
+	close(release)
+	f := <-secondFile
+	data, err := io.ReadAll(f)
+	require.NoError(t, err)
+	require.Equal(t, "slow", string(data))
+	require.NoError(t, f.Close())
+	require.NoError(t, c.Remove("slow.jar"))
+
+	// Failed fetches aren't cached
+	_, err = c.Load(context.Background(), "broken.jar", cache.Meta{}, func(ctx context.Context) (io.ReadCloser, error) {
+		return nil, errors.New("offline")
+	})
+	require.ErrorContains(t, err, "offline")
+	require.False(t, c.Has("broken.jar"))
+
+	// Lock files aren't entries
+	entries, err := c.List()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
+
+func TestCacheExportImport(t *testing.T) {
+	from, err := cache.NewAt(t.TempDir())
+	require.NoError(t, err)
+	_, err = from.Put("paper-1.21.4.jar", strings.NewReader("server"), cache.Meta{Kind: "server", Version: "1.21.4"})
+	require.NoError(t, err)
+	_, err = from.Put("templates/a.zip", strings.NewReader("template"), cache.Meta{Kind: "template"})
+	require.NoError(t, err)
+
+	var archive bytes.Buffer
+	exported, err := from.Export(&archive)
+	require.NoError(t, err)
+	require.Len(t, exported, 2)
+
+	// The entries are imported with their metadata
+	to, err := cache.NewAt(t.TempDir())
+	require.NoError(t, err)
+	imported, err := to.Import(bytes.NewReader(archive.Bytes()))
+	require.NoError(t, err)
+	require.Len(t, imported, 2)
+	entry, err := to.Stat("paper-1.21.4.jar")
+	require.NoError(t, err)
+	require.Equal(t, "1.21.4", entry.Version)
+	require.NoError(t, to.Verify(*entry))
+	data, err := to.ReadFile("templates/a.zip")
+	require.NoError(t, err)
+	require.Equal(t, "template", string(data))
+
+	// A corrupt entry doesn't replace the existing one
+	require.NoError(t, os.WriteFile(from.Path("paper-1.21.4.jar"), []byte("broken"), 0666))
+	archive.Reset()
+	_, err = from.Export(&archive)
+	require.NoError(t, err)
+	_, err = to.Import(bytes.NewReader(archive.Bytes()))
+	require.ErrorIs(t, err, cache.ErrCorrupt)
+	data, err = to.ReadFile("paper-1.21.4.jar")
+	require.NoError(t, err)
+	require.Equal(t, "server", string(data))
+}
+
+func TestCacheImportPrune(t *testing.T) {
+	from, err := cache.NewAt(t.TempDir())
+	require.NoError(t, err)
+	_, err = from.Put("paper-1.21.4.jar", strings.NewReader("server"), cache.Meta{Kind: "server"})
+	require.NoError(t, err)
+	_, err = from.Put("templates/a.zip", strings.NewReader("template"), cache.Meta{Kind: "template"})
+	require.NoError(t, err)
+	var archive bytes.Buffer
+	_, err = from.Export(&archive)
+	require.NoError(t, err)
+
+	// Going over the maximum size removes the existing entries, but none of the imported ones
+	to, err := cache.NewAt(t.TempDir())
+	require.NoError(t, err)
+	to.MaxSize = 10
+	_, err = to.Put("old", strings.NewReader("old"), cache.Meta{})
+	require.NoError(t, err)
+	imported, err := to.Import(&archive)
+	require.NoError(t, err)
+	require.Len(t, imported, 2)
+	require.False(t, to.Has("old"))
+	require.True(t, to.Has("paper-1.21.4.jar"))
+	require.True(t, to.Has("templates/a.zip"))
+}
+
+func TestFetch(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	ctx := context.Background()
+	_, err := cache.Existing()
+	require.True(t, errors.Is(err, os.ErrNotExist))
+
+	// Without a cached copy, a failed fetch fails
+	failed := func(ctx context.Context) ([]byte, cache.Meta, error) {
+		return nil, cache.Meta{}, errors.New("offline")
+	}
+	_, _, err = cache.Fetch(ctx, "versions/a.json", "the versions", failed)
+	require.ErrorContains(t, err, "offline")
+
+	// A successful fetch is cached
+	data, meta, err := cache.Fetch(ctx, "versions/a.json", "the versions", func(ctx context.Context) ([]byte, cache.Meta, error) {
+		return []byte("v1"), cache.Meta{Kind: "versions", Version: "1"}, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, "v1", string(data))
+	require.Equal(t, "1", meta.Version)
+
+	// A failed fetch falls back to the cached copy
+	data, meta, err = cache.Fetch(ctx, "versions/a.json", "the versions", failed)
+	require.NoError(t, err)
+	require.Equal(t, "v1", string(data))
+	require.Equal(t, "1", meta.Version)
+
+	c, err := cache.Existing()
+	require.NoError(t, err)
+	require.True(t, c.Has("versions/a.json"))
+}
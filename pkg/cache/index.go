@@ -0,0 +1,281 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// indexFilename is the name of the index file in the cache directory.
+const indexFilename = "index.json"
+
+// ErrCorrupt is returned when the contents of a cache entry don't match its checksum.
+var ErrCorrupt = errors.New("cache entry is corrupt")
+
+// ErrNoChecksum is returned when verifying a cache entry without a recorded checksum, e.g.
+// one stored by an older version of the CLI.
+var ErrNoChecksum = errors.New("cache entry has no checksum")
+
+// Entry is a file in the cache.
+type Entry struct {
+	Key string `json:"key"`
+	Meta
+	// Size is the size of the file, in bytes.
+	Size int64 `json:"size"`
+	// SHA256 is the hex-encoded SHA-256 checksum of the file, when it was stored.
+	SHA256   string    `json:"sha256,omitempty"`
+	Created  time.Time `json:"created"`
+	LastUsed time.Time `json:"lastUsed"`
+}
+
+// readIndex reads the index of the cache. A missing or unreadable index is empty, since
+// it's rebuilt from the files as they're used.
+func (c *Cache) readIndex() map[string]*Entry {
+	index := make(map[string]*Entry)
+	data, err := os.ReadFile(filepath.Join(c.dir, indexFilename))
+	if err != nil {
+		return index
+	}
+	var entries []*Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return index
+	}
+	for _, entry := range entries {
+		index[entry.Key] = entry
+	}
+	return index
+}
+
+// indexLockTimeout is how long updating the index waits for another process to release
+// its lock. The index is only locked while it's read and written, so a process holding it
+// longer is likely stuck.
+const indexLockTimeout = 10 * time.Second
+
+// updateIndex reads the index of the cache, applies a change to it, and writes it back.
+// The index is locked meanwhile, so concurrent updates aren't lost.
+func (c *Cache) updateIndex(update func(index map[string]*Entry)) error {
+	ctx, cancel := context.WithTimeout(context.Background(), indexLockTimeout)
+	defer cancel()
+	lock, err := c.lock(ctx, indexFilename)
+	if err != nil {
+		return fmt.Errorf("locking cache index: %w", err)
+	}
+	defer lock.Unlock()
+
+	index := c.readIndex()
+	update(index)
+
+	entries := make([]*Entry, 0, len(index))
+	for _, entry := range index {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Replace the index in one step, so it's never read half-written
+	tempFile, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("writing cache index: %w", err)
+	}
+	defer os.Remove(tempFile.Name())
+	if _, err := tempFile.Write(data); err != nil {
+		tempFile.Close()
+		return fmt.Errorf("writing cache index: %w", err)
+	}
+	if err := tempFile.Close(); err != nil {
+		return fmt.Errorf("writing cache index: %w", err)
+	}
+	return os.Rename(tempFile.Name(), filepath.Join(c.dir, indexFilename))
+}
+
+// touch records that an entry was used, by setting the modification time of its file, so
+// using an entry doesn't rewrite the index. It's best-effort: the entry is still usable if
+// the time can't be set, e.g. in a read-only cache, so errors are ignored.
+func (c *Cache) touch(key string) {
+	now := time.Now()
+	_ = os.Chtimes(c.Path(key), now, now)
+}
+
+// fileEntry returns the entry of a file, from the index if it's there. The size and the
+// last use come from the file, since they change without updating the index.
+func fileEntry(index map[string]*Entry, key string, stat fs.FileInfo) *Entry {
+	entry, ok := index[key]
+	if !ok {
+		entry = newEntry(key, stat)
+	}
+	entry.Size = stat.Size()
+	if stat.ModTime().After(entry.LastUsed) {
+		entry.LastUsed = stat.ModTime()
+	}
+	return entry
+}
+
+// newEntry creates the entry of a file missing from the index, e.g. stored by an older
+// version of the CLI.
+func newEntry(key string, stat fs.FileInfo) *Entry {
+	return &Entry{
+		Key:      key,
+		Size:     stat.Size(),
+		Created:  stat.ModTime(),
+		LastUsed: stat.ModTime(),
+	}
+}
+
+// List returns the entries of the cache, in order of their keys.
+func (c *Cache) List() ([]Entry, error) {
+	index := c.readIndex()
+	var entries []Entry
+	err := filepath.WalkDir(c.dir, func(filename string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// Skip the index, temporary files and partial downloads
+		if filename != c.dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || strings.HasSuffix(d.Name(), ".part") {
+			return nil
+		}
+		rel, err := filepath.Rel(c.dir, filename)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if key == indexFilename {
+			return nil
+		}
+
+		// Files stored by older versions of the CLI aren't in the index
+		stat, err := d.Info()
+		if err != nil {
+			return err
+		}
+		entries = append(entries, *fileEntry(index, key, stat))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing cache: %w", err)
+	}
+	return entries, nil
+}
+
+// Verify checks the contents of an entry against its recorded checksum. The error is
+// ErrCorrupt if they don't match, or ErrNoChecksum if there's no recorded checksum. If the
+// file of the entry is gone, the entry is removed from the index, and the error is
+// os.ErrNotExist.
+func (c *Cache) Verify(entry Entry) error {
+	f, err := os.Open(c.Path(entry.Key))
+	if errors.Is(err, os.ErrNotExist) {
+		if indexErr := c.updateIndex(func(index map[string]*Entry) { delete(index, entry.Key) }); indexErr != nil {
+			return indexErr
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if entry.SHA256 == "" {
+		f.Close()
+		return ErrNoChecksum
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return err
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); sum != entry.SHA256 {
+		return fmt.Errorf("%w: checksum is %s, expected %s", ErrCorrupt, sum, entry.SHA256)
+	}
+	return nil
+}
+
+// Tidy removes the entries of the index whose file is gone, e.g. removed by hand, and
+// returns their keys in order.
+func (c *Cache) Tidy() ([]string, error) {
+	var missing []string
+	err := c.updateIndex(func(index map[string]*Entry) {
+		for key := range index {
+			if !c.Has(key) {
+				missing = append(missing, key)
+				delete(index, key)
+			}
+		}
+	})
+	sort.Strings(missing)
+	return missing, err
+}
+
+// Clean removes the entries matching a filter, and returns them.
+func (c *Cache) Clean(match func(entry Entry) bool) ([]Entry, error) {
+	entries, err := c.List()
+	if err != nil {
+		return nil, err
+	}
+	var removed []Entry
+	for _, entry := range entries {
+		if !match(entry) {
+			continue
+		}
+		if err := c.Remove(entry.Key); err != nil {
+			return removed, err
+		}
+		removed = append(removed, entry)
+	}
+	return removed, nil
+}
+
+// Prune removes the least recently used entries until the cache is no larger than a
+// size, and returns them.
+func (c *Cache) Prune(maxSize int64) ([]Entry, error) {
+	return c.prune(maxSize)
+}
+
+// prune removes the least recently used entries, except for the entries with the keys to
+// keep, until the cache is no larger than a size.
+func (c *Cache) prune(maxSize int64, keep ...string) ([]Entry, error) {
+	entries, err := c.List()
+	if err != nil {
+		return nil, err
+	}
+	var size int64
+	for _, entry := range entries {
+		size += entry.Size
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].LastUsed.Before(entries[j].LastUsed)
+	})
+	var removed []Entry
+	for _, entry := range entries {
+		if size <= maxSize {
+			break
+		}
+		if slices.Contains(keep, entry.Key) {
+			continue
+		}
+		if err := c.Remove(entry.Key); err != nil {
+			return removed, err
+		}
+		size -= entry.Size
+		removed = append(removed, entry)
+	}
+	return removed, nil
+}
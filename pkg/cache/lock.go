@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// lockDir is the directory of the lock files, in the cache directory. Lock files are never
+// removed, since removing a lock file another process is waiting on would let two processes
+// hold the lock.
+const lockDir = ".locks"
+
+// lockInterval is how often a lock held by another process is tried again.
+const lockInterval = 100 * time.Millisecond
+
+// fileLock is an exclusive lock on a file, held across processes.
+type fileLock struct {
+	f *os.File
+}
+
+// lock acquires the lock with a name, waiting until it's released if another process, or
+// another goroutine, holds it.
+func (c *Cache) lock(ctx context.Context, name string) (*fileLock, error) {
+	sum := sha256.Sum256([]byte(name))
+	filename := filepath.Join(c.dir, lockDir, hex.EncodeToString(sum[:8])+".lock")
+	if err := os.MkdirAll(filepath.Dir(filename), 0777); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		locked, err := tryLock(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("locking cache entry %s: %w", name, err)
+		}
+		if locked {
+			return &fileLock{f}, nil
+		}
+		select {
+		case <-ctx.Done():
+			f.Close()
+			return nil, ctx.Err()
+		case <-time.After(lockInterval):
+		}
+	}
+}
+
+// Unlock releases the lock.
+func (l *fileLock) Unlock() error {
+	err := unlock(l.f)
+	if closeErr := l.f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
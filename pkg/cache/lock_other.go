@@ -0,0 +1,16 @@
+//go:build !windows && (!unix || aix || solaris)
+
+package cache
+
+import "os"
+
+// tryLock doesn't lock files on this platform, so only concurrent fetches within a
+// process are de-duplicated.
+func tryLock(f *os.File) (bool, error) {
+	return true, nil
+}
+
+// unlock releases the lock on a file.
+func unlock(f *os.File) error {
+	return nil
+}
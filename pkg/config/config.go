@@ -0,0 +1,137 @@
+// Package config reads the user configuration of the CLI, from a JSON file in the user's
+// config directory and from environment variables.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/customrealms/cli/pkg/project"
+)
+
+// PathEnv is the environment variable with the path of the config file, replacing the
+// default one.
+const PathEnv = "CRX_CONFIG"
+
+// Names of the mirrors, used as the keys of the config file, and as the directories of
+// each mirror under a single base mirror.
+const (
+	// MirrorPaperMC mirrors the PaperMC download API, https://fill.papermc.io.
+	MirrorPaperMC = "papermc"
+	// MirrorRuntime mirrors the releases of the runtime JAR,
+	// https://github.com/customrealms/bukkit-runtime/releases.
+	MirrorRuntime = "runtime"
+	// MirrorGitHub mirrors the archives of GitHub repositories, https://github.com.
+	MirrorGitHub = "github"
+)
+
+// mirrorEnv are the environment variables overriding each mirror of the config file.
+var mirrorEnv = map[string]string{
+	MirrorPaperMC: "CRX_PAPERMC_MIRROR",
+	MirrorRuntime: "CRX_RUNTIME_MIRROR",
+	MirrorGitHub:  "CRX_GITHUB_MIRROR",
+}
+
+// Config is the user configuration of the CLI.
+type Config struct {
+	// Mirror is the base URL of a mirror of every upstream server, with each one in a
+	// directory named after it, e.g. "<mirror>/papermc".
+	Mirror string `json:"mirror,omitempty"`
+	// Mirrors are the base URLs of the mirrors of single upstream servers, by name. They
+	// take precedence over Mirror.
+	Mirrors map[string]string `json:"mirrors,omitempty"`
+	// CacheMaxSize is the maximum size of the download cache. When it's exceeded, the
+	// least recently used files are removed. Zero means there's no limit.
+	CacheMaxSize project.Size `json:"cacheMaxSize,omitempty"`
+	// Offline turns off downloads, so only the cached files are used.
+	Offline bool `json:"offline,omitempty"`
+}
+
+// DefaultPath returns the path of the config file: the one set with PathEnv, or
+// "crx/config.json" in the user's config directory.
+func DefaultPath() (string, error) {
+	if path := os.Getenv(PathEnv); path != "" {
+		return path, nil
+	}
+	userConfigDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("finding user config directory: %w", err)
+	}
+	return filepath.Join(userConfigDir, "crx", "config.json"), nil
+}
+
+// Load reads the config file at its default path, and applies the environment variables
+// on top of it. It's not an error if there's no config file.
+func Load() (*Config, error) {
+	path, err := DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	return LoadFile(path)
+}
+
+// LoadFile reads a config file, and applies the environment variables on top of it. It's
+// not an error if the file doesn't exist.
+func LoadFile(path string) (*Config, error) {
+	var config Config
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("reading config file: %w", err)
+	default:
+		if err := json.Unmarshal(data, &config); err != nil {
+			return nil, fmt.Errorf("decoding config file %s: %w", path, err)
+		}
+	}
+	for name := range config.Mirrors {
+		if _, ok := mirrorEnv[name]; !ok {
+			return nil, fmt.Errorf("config file %s: unknown mirror %q", path, name)
+		}
+	}
+
+	// Apply the environment variables
+	if config.Mirrors == nil {
+		config.Mirrors = make(map[string]string)
+	}
+	if mirror := os.Getenv("CRX_MIRROR"); mirror != "" {
+		config.Mirror = mirror
+	}
+	for name, env := range mirrorEnv {
+		if mirror := os.Getenv(env); mirror != "" {
+			config.Mirrors[name] = mirror
+		}
+	}
+	if maxSize := os.Getenv("CRX_CACHE_MAX_SIZE"); maxSize != "" {
+		size, err := project.ParseSize(maxSize)
+		if err != nil {
+			return nil, fmt.Errorf("CRX_CACHE_MAX_SIZE: %w", err)
+		}
+		config.CacheMaxSize = size
+	}
+	if offline := os.Getenv("CRX_OFFLINE"); offline != "" {
+		value, err := strconv.ParseBool(offline)
+		if err != nil {
+			return nil, fmt.Errorf("CRX_OFFLINE: invalid value %q", offline)
+		}
+		config.Offline = value
+	}
+	return &config, nil
+}
+
+// MirrorURL returns the base URL of the mirror of an upstream server, without a trailing
+// slash. If there's no mirror for it, it returns an empty string.
+func (c *Config) MirrorURL(name string) string {
+	if mirror := c.Mirrors[name]; mirror != "" {
+		return strings.TrimRight(mirror, "/")
+	}
+	if c.Mirror != "" {
+		return strings.TrimRight(c.Mirror, "/") + "/" + name
+	}
+	return ""
+}
@@ -0,0 +1,67 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/customrealms/cli/pkg/config"
+	"github.com/customrealms/cli/pkg/project"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadFile(t *testing.T) {
+	for _, env := range []string{"CRX_MIRROR", "CRX_PAPERMC_MIRROR", "CRX_RUNTIME_MIRROR", "CRX_GITHUB_MIRROR", "CRX_CACHE_MAX_SIZE", "CRX_OFFLINE"} {
+		t.Setenv(env, "")
+	}
+	dir := t.TempDir()
+
+	// Without a config file, there are no mirrors
+	c, err := config.LoadFile(filepath.Join(dir, "missing.json"))
+	require.NoError(t, err)
+	require.Equal(t, "", c.MirrorURL(config.MirrorPaperMC))
+
+	// Single mirrors take precedence over the base mirror
+	path := filepath.Join(dir, "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"mirror": "https://mirror.example.com/crx/",
+		"mirrors": {"github": "file:///srv/github"}
+	}`), 0666))
+	c, err = config.LoadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "https://mirror.example.com/crx/papermc", c.MirrorURL(config.MirrorPaperMC))
+	require.Equal(t, "file:///srv/github", c.MirrorURL(config.MirrorGitHub))
+
+	// Environment variables override the config file
+	t.Setenv("CRX_MIRROR", "http://localhost:8080")
+	t.Setenv("CRX_GITHUB_MIRROR", "http://localhost:9090/gh/")
+	c, err = config.LoadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "http://localhost:8080/runtime", c.MirrorURL(config.MirrorRuntime))
+	require.Equal(t, "http://localhost:9090/gh", c.MirrorURL(config.MirrorGitHub))
+
+	// The cache size has a unit
+	require.NoError(t, os.WriteFile(path, []byte(`{"cacheMaxSize": "2GB"}`), 0666))
+	c, err = config.LoadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, project.Size(2<<30), c.CacheMaxSize)
+	t.Setenv("CRX_CACHE_MAX_SIZE", "500MB")
+	c, err = config.LoadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, project.Size(500<<20), c.CacheMaxSize)
+
+	// Offline mode is a boolean
+	t.Setenv("CRX_OFFLINE", "1")
+	c, err = config.LoadFile(path)
+	require.NoError(t, err)
+	require.True(t, c.Offline)
+	t.Setenv("CRX_OFFLINE", "maybe")
+	_, err = config.LoadFile(path)
+	require.ErrorContains(t, err, "CRX_OFFLINE")
+	t.Setenv("CRX_OFFLINE", "")
+
+	// Unknown mirrors are rejected
+	require.NoError(t, os.WriteFile(path, []byte(`{"mirrors": {"maven": "https://example.com"}}`), 0666))
+	_, err = config.LoadFile(path)
+	require.ErrorContains(t, err, `unknown mirror "maven"`)
+}
@@ -0,0 +1,223 @@
+// Package diff compares and merges text files line by line.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OpKind is the kind of an edit operation.
+type OpKind int
+
+const (
+	// Equal keeps a line of both texts.
+	Equal OpKind = iota
+	// Delete removes a line of the first text.
+	Delete
+	// Insert adds a line of the second text.
+	Insert
+)
+
+// Op is an edit operation on a single line.
+type Op struct {
+	Kind OpKind
+	// A is the index of the line in the first text, for Equal and Delete operations.
+	A int
+	// B is the index of the line in the second text, for Equal and Insert operations.
+	B int
+}
+
+// maxCells bounds the size of the table used to compare texts. Larger texts are compared
+// as if their changed regions had no lines in common.
+const maxCells = 16 << 20
+
+// SplitLines splits a text into lines, keeping the line endings so the lines can be joined
+// back into the same text.
+func SplitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Lines computes the edit operations turning the lines a into the lines b, based on their
+// longest common subsequence.
+func Lines(a, b []string) []Op {
+	// Skip the common prefix and suffix
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []Op
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, Op{Kind: Equal, A: i, B: i})
+	}
+	ops = append(ops, lcsOps(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix], prefix, prefix)...)
+	for i := 0; i < suffix; i++ {
+		ops = append(ops, Op{Kind: Equal, A: len(a) - suffix + i, B: len(b) - suffix + i})
+	}
+	return ops
+}
+
+// lcsOps computes the edit operations between two texts with no common prefix or suffix,
+// with the line indexes offset by offA and offB.
+func lcsOps(a, b []string, offA, offB int) []Op {
+	n, m := len(a), len(b)
+	var ops []Op
+
+	// Without a table, delete everything and insert everything
+	if n == 0 || m == 0 || n*m > maxCells {
+		for i := range a {
+			ops = append(ops, Op{Kind: Delete, A: offA + i})
+		}
+		for j := range b {
+			ops = append(ops, Op{Kind: Insert, B: offB + j})
+		}
+		return ops
+	}
+
+	// lengths[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lengths := make([][]int32, n+1)
+	for i := range lengths {
+		lengths[i] = make([]int32, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	// Walk the table, preferring deletions before insertions
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, Op{Kind: Equal, A: offA + i, B: offB + j})
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			ops = append(ops, Op{Kind: Delete, A: offA + i})
+			i++
+		default:
+			ops = append(ops, Op{Kind: Insert, B: offB + j})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, Op{Kind: Delete, A: offA + i})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, Op{Kind: Insert, B: offB + j})
+	}
+	return ops
+}
+
+// Unified returns the differences between two texts in the unified diff format, with three
+// lines of context around each change. If the texts are equal, it returns an empty string.
+func Unified(nameA, nameB, a, b string) string {
+	linesA, linesB := SplitLines(a), SplitLines(b)
+	ops := Lines(linesA, linesB)
+
+	// Find the ranges of operations to print, with their context
+	const context = 3
+	var sb strings.Builder
+	for start := 0; start < len(ops); {
+		// Find the next change
+		for start < len(ops) && ops[start].Kind == Equal {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+
+		// Extend the hunk until there are more than two contexts of equal lines
+		end := start
+		for end < len(ops) {
+			if ops[end].Kind != Equal {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].Kind == Equal {
+				run++
+			}
+			if run == len(ops) || run-end > 2*context {
+				break
+			}
+			end = run
+		}
+		from := max(start-context, 0)
+		to := min(end+context, len(ops))
+
+		if sb.Len() == 0 {
+			fmt.Fprintf(&sb, "--- %s\n+++ %s\n", nameA, nameB)
+		}
+		writeHunk(&sb, ops[from:to], linesA, linesB)
+		start = to
+	}
+	return sb.String()
+}
+
+// writeHunk writes a hunk of a unified diff.
+func writeHunk(sb *strings.Builder, ops []Op, a, b []string) {
+	// Count the lines of each text in the hunk, and find where they start
+	startA, startB, countA, countB := -1, -1, 0, 0
+	for _, op := range ops {
+		if op.Kind != Insert {
+			if startA < 0 {
+				startA = op.A
+			}
+			countA++
+		}
+		if op.Kind != Delete {
+			if startB < 0 {
+				startB = op.B
+			}
+			countB++
+		}
+	}
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(startA, countA), hunkRange(startB, countB))
+	for _, op := range ops {
+		switch op.Kind {
+		case Equal:
+			writeLine(sb, ' ', a[op.A])
+		case Delete:
+			writeLine(sb, '-', a[op.A])
+		case Insert:
+			writeLine(sb, '+', b[op.B])
+		}
+	}
+}
+
+// hunkRange formats the start and length of a range of lines in a hunk header.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", max(start, 0))
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// writeLine writes a line of a hunk, noting when the line has no line ending.
+func writeLine(sb *strings.Builder, prefix byte, line string) {
+	sb.WriteByte(prefix)
+	sb.WriteString(line)
+	if !strings.HasSuffix(line, "\n") {
+		sb.WriteString("\n\\ No newline at end of file\n")
+	}
+}
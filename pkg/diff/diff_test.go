@@ -0,0 +1,69 @@
+package diff_test
+
+import (
+	"testing"
+
+	"github.com/customrealms/cli/pkg/diff"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnified(t *testing.T) {
+	a := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\n"
+	b := "one\ntwo\nTHREE\nfour\nfive\nsix\nseven\neight\nnine\nten\neleven\n"
+
+	require.Equal(t, "", diff.Unified("a", "b", a, a))
+	require.Equal(t, `--- a
++++ b
+@@ -1,6 +1,6 @@
+ one
+ two
+-three
++THREE
+ four
+ five
+ six
+@@ -8,3 +8,4 @@
+ eight
+ nine
+ ten
++eleven
+`, diff.Unified("a", "b", a, b))
+
+	require.Equal(t, `--- a
++++ b
+@@ -0,0 +1 @@
++new
+\ No newline at end of file
+`, diff.Unified("a", "b", "", "new"))
+}
+
+func TestMerge3(t *testing.T) {
+	base := "{\n  \"name\": \"plugin\",\n  \"version\": \"1.0.0\",\n  \"scripts\": {\n    \"build\": \"crx build\"\n  }\n}\n"
+
+	t.Run("both sides", func(t *testing.T) {
+		ours := "{\n  \"name\": \"plugin\",\n  \"version\": \"1.2.0\",\n  \"scripts\": {\n    \"build\": \"crx build\"\n  }\n}\n"
+		theirs := "{\n  \"name\": \"plugin\",\n  \"version\": \"1.0.0\",\n  \"scripts\": {\n    \"build\": \"crx build\",\n    \"dev\": \"crx run\"\n  }\n}\n"
+		merged, conflicts := diff.Merge3(base, ours, theirs)
+		require.Equal(t, 0, conflicts)
+		require.Equal(t, "{\n  \"name\": \"plugin\",\n  \"version\": \"1.2.0\",\n  \"scripts\": {\n    \"build\": \"crx build\",\n    \"dev\": \"crx run\"\n  }\n}\n", merged)
+	})
+
+	t.Run("same change", func(t *testing.T) {
+		changed := "changed\n" + base
+		merged, conflicts := diff.Merge3(base, changed, changed)
+		require.Equal(t, 0, conflicts)
+		require.Equal(t, changed, merged)
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		merged, conflicts := diff.Merge3("a\nb\nc\n", "a\nours\nc\n", "a\ntheirs\nc\n")
+		require.Equal(t, 1, conflicts)
+		require.Equal(t, "a\n<<<<<<< current\nours\n||||||| base\nb\n=======\ntheirs\n>>>>>>> template\nc\n", merged)
+	})
+
+	t.Run("empty base", func(t *testing.T) {
+		merged, conflicts := diff.Merge3("", "ours", "theirs\n")
+		require.Equal(t, 1, conflicts)
+		require.Equal(t, "<<<<<<< current\nours\n||||||| base\n=======\ntheirs\n>>>>>>> template\n", merged)
+	})
+}
@@ -0,0 +1,99 @@
+package diff
+
+import (
+	"slices"
+	"strings"
+)
+
+// Conflict markers written around the conflicting regions of a merge.
+const (
+	MarkerOurs   = "<<<<<<< current"
+	MarkerBase   = "||||||| base"
+	MarkerSep    = "======="
+	MarkerTheirs = ">>>>>>> template"
+)
+
+// Merge3 merges the changes made to the base text in ours and in theirs. Regions changed
+// on one side only take that side's changes, and regions changed identically on both sides
+// are kept once. Regions changed differently on both sides are conflicts, written with
+// conflict markers. It returns the merged text and the number of conflicts.
+func Merge3(base, ours, theirs string) (string, int) {
+	baseLines, ourLines, theirLines := SplitLines(base), SplitLines(ours), SplitLines(theirs)
+	matchOurs := matches(baseLines, ourLines)
+	matchTheirs := matches(baseLines, theirLines)
+
+	var sb strings.Builder
+	conflicts := 0
+	i, j, k := 0, 0, 0
+	for i < len(baseLines) || j < len(ourLines) || k < len(theirLines) {
+		// Copy the lines that are unchanged on both sides
+		if i < len(baseLines) && matchOurs[i] == j && matchTheirs[i] == k {
+			sb.WriteString(baseLines[i])
+			i, j, k = i+1, j+1, k+1
+			continue
+		}
+
+		// Find the next base line that is unchanged on both sides, which ends the
+		// changed region
+		nextI, nextJ, nextK := len(baseLines), len(ourLines), len(theirLines)
+		for n := i; n < len(baseLines); n++ {
+			if matchOurs[n] >= j && matchTheirs[n] >= k {
+				nextI, nextJ, nextK = n, matchOurs[n], matchTheirs[n]
+				break
+			}
+		}
+		baseChunk := baseLines[i:nextI]
+		ourChunk := ourLines[j:nextJ]
+		theirChunk := theirLines[k:nextK]
+
+		// Resolve the changed region
+		switch {
+		case slices.Equal(ourChunk, baseChunk):
+			writeLines(&sb, theirChunk)
+		case slices.Equal(theirChunk, baseChunk), slices.Equal(ourChunk, theirChunk):
+			writeLines(&sb, ourChunk)
+		default:
+			conflicts++
+			writeMarker(&sb, MarkerOurs)
+			writeLines(&sb, ourChunk)
+			writeMarker(&sb, MarkerBase)
+			writeLines(&sb, baseChunk)
+			writeMarker(&sb, MarkerSep)
+			writeLines(&sb, theirChunk)
+			writeMarker(&sb, MarkerTheirs)
+		}
+		i, j, k = nextI, nextJ, nextK
+	}
+	return sb.String(), conflicts
+}
+
+// matches maps each line of a to the index of the same line in b, or -1 if the line was
+// removed in b.
+func matches(a, b []string) []int {
+	m := make([]int, len(a))
+	for i := range m {
+		m[i] = -1
+	}
+	for _, op := range Lines(a, b) {
+		if op.Kind == Equal {
+			m[op.A] = op.B
+		}
+	}
+	return m
+}
+
+// writeLines writes lines of a merged text.
+func writeLines(sb *strings.Builder, lines []string) {
+	for _, line := range lines {
+		sb.WriteString(line)
+	}
+}
+
+// writeMarker writes a conflict marker on its own line.
+func writeMarker(sb *strings.Builder, marker string) {
+	if s := sb.String(); s != "" && !strings.HasSuffix(s, "\n") {
+		sb.WriteByte('\n')
+	}
+	sb.WriteString(marker)
+	sb.WriteByte('\n')
+}
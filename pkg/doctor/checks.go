@@ -0,0 +1,457 @@
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/customrealms/cli/pkg/build"
+	"github.com/customrealms/cli/pkg/cache"
+	"github.com/customrealms/cli/pkg/config"
+	"github.com/customrealms/cli/pkg/download"
+	"github.com/customrealms/cli/pkg/jdk"
+	"github.com/customrealms/cli/pkg/minecraft"
+	"github.com/customrealms/cli/pkg/project"
+)
+
+// minNodeVersion is the oldest Node.js major version known to work.
+const minNodeVersion = 18
+
+// networkTimeout is how long the network checks wait for each endpoint.
+const networkTimeout = 10 * time.Second
+
+// endpoint is a download server used by the CLI.
+type endpoint struct {
+	name string
+	url  string
+}
+
+// endpoints returns the download servers used by the CLI, or their mirrors.
+func endpoints() []endpoint {
+	return []endpoint{
+		{"PaperMC", download.Default.URL(minecraft.PaperMCURL + "/v3/projects/paper")},
+		{"GitHub", download.Default.URL(build.RuntimeURL + "/latest")},
+	}
+}
+
+// commandVersion runs a command with --version, and returns the first line of its output.
+func commandVersion(ctx context.Context, name string) (string, error) {
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, "--version")
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("running %s --version: %w", name, err)
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(out.String()), "\n")
+	return strings.TrimSpace(line), nil
+}
+
+func checkJava(ctx context.Context, options *Options) []Result {
+	minecraftVersion := options.MinecraftVersion
+	if minecraftVersion == "" {
+		minecraftVersion = minecraft.DefaultVersion
+	}
+	required := minecraft.MinJavaVersion(minecraftVersion)
+	result := Result{
+		Name: "Java",
+		Hint: fmt.Sprintf("Install Java %d or later, e.g. from https://adoptium.net.", required),
+	}
+
+	// Check the runtime chosen by the user, or find one like the dev server does
+	var runtime *jdk.Runtime
+	var err error
+	if options.Java != "" {
+		runtime, err = jdk.Open(ctx, options.Java)
+	} else {
+		runtime, err = jdk.Find(ctx, required)
+	}
+	if err != nil {
+		result.Status, result.Message = Fail, err.Error()
+		return []Result{result}
+	}
+	result.Message = fmt.Sprintf("%s, Minecraft %s needs Java %d or later", runtime, minecraftVersion, required)
+	if runtime.Major < required {
+		result.Status = Fail
+	}
+	return []Result{result}
+}
+
+func checkNode(ctx context.Context, options *Options) []Result {
+	result := Result{
+		Name: "Node.js",
+		Hint: fmt.Sprintf("Install Node.js %d or later from https://nodejs.org.", minNodeVersion),
+	}
+	version, err := commandVersion(ctx, "node")
+	if err != nil {
+		result.Status, result.Message = Fail, "node command not found"
+		return []Result{result}
+	}
+	result.Message = version
+	major, _, _ := strings.Cut(strings.TrimPrefix(version, "v"), ".")
+	if n, err := strconv.Atoi(major); err != nil || n < minNodeVersion {
+		result.Status = Warn
+		result.Message = fmt.Sprintf("%s, the CLI is tested with Node.js %d or later", version, minNodeVersion)
+	}
+	return []Result{result}
+}
+
+func checkPackageManager(ctx context.Context, options *Options) []Result {
+	result := Result{Name: "Package manager"}
+	pm, err := project.DetectPackageManager(options.Dir)
+	if err != nil {
+		result.Status, result.Message = Fail, err.Error()
+		return []Result{result}
+	}
+	version, err := commandVersion(ctx, string(pm))
+	if err != nil {
+		result.Status = Fail
+		result.Message = fmt.Sprintf("the project uses %s, but the %s command wasn't found", pm, pm)
+		result.Hint = fmt.Sprintf("Install %s, or use another package manager.", pm)
+		return []Result{result}
+	}
+	result.Message = fmt.Sprintf("%s %s", pm, version)
+	return []Result{result}
+}
+
+func checkGit(ctx context.Context, options *Options) []Result {
+	result := Result{Name: "Git"}
+	version, err := commandVersion(ctx, "git")
+	if err != nil {
+		result.Status, result.Message = Warn, "git command not found"
+		result.Hint = "crx init won't create Git repositories, and the ${git.*} placeholders of plugin.yml are empty."
+		return []Result{result}
+	}
+	result.Message = version
+	return []Result{result}
+}
+
+func checkConfig(ctx context.Context, options *Options) []Result {
+	result := Result{Name: "Config"}
+	path, err := config.DefaultPath()
+	if err != nil {
+		result.Status, result.Message = Fail, err.Error()
+		return []Result{result}
+	}
+	result.Message = path
+	if _, err := config.LoadFile(path); err != nil {
+		result.Status, result.Message = Fail, err.Error()
+		result.Hint = fmt.Sprintf("Fix the config file, or the environment variables overriding it. Set %s to use another file.", config.PathEnv)
+	}
+	return []Result{result}
+}
+
+func checkCache(ctx context.Context, options *Options) []Result {
+	result := Result{Name: "Cache"}
+	c, err := cache.New()
+	if err != nil {
+		result.Status, result.Message = Fail, err.Error()
+		return []Result{result}
+	}
+
+	// Check that files can be written to the cache
+	f, err := os.CreateTemp(c.Dir(), ".doctor-*")
+	if err != nil {
+		result.Status = Fail
+		result.Message = fmt.Sprintf("%s isn't writable: %s", c.Dir(), err)
+		result.Hint = "Fix the permissions of the cache directory, or remove it."
+		return []Result{result}
+	}
+	f.Close()
+	os.Remove(f.Name())
+
+	// Measure the cache
+	entries, err := c.List()
+	if err != nil {
+		result.Status, result.Message = Warn, err.Error()
+		return []Result{result}
+	}
+	var size int64
+	for _, entry := range entries {
+		size += entry.Size
+	}
+	result.Message = fmt.Sprintf("%s (%d files, %s)", c.Dir(), len(entries), project.Size(size))
+	return []Result{result}
+}
+
+func checkNetwork(ctx context.Context, options *Options) []Result {
+	if options.Offline {
+		return nil
+	}
+	client := options.Client
+	if client == nil {
+		client = download.Default.HTTPClient
+	}
+
+	var results []Result
+	for _, endpoint := range endpoints() {
+		result := Result{
+			Name:    endpoint.name,
+			Message: fmt.Sprintf("%s is reachable", endpoint.url),
+			Hint:    "Downloads will fail, except for the files already in the cache.",
+		}
+		if err := checkEndpoint(ctx, client, endpoint.url); err != nil {
+			result.Status, result.Message = Warn, err.Error()
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// checkEndpoint checks that an HTTP server responds without a server error.
+func checkEndpoint(ctx context.Context, client *http.Client, url string) error {
+	ctx, cancel := context.WithTimeout(ctx, networkTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unreachable: %w", err)
+	}
+	res.Body.Close()
+	if res.StatusCode >= 500 {
+		return fmt.Errorf("%s responded with %s", url, res.Status)
+	}
+	return nil
+}
+
+// findProjects returns the plugin projects to check: the project in the directory, or every
+// plugin package if it's the root of a workspace. If there's no project, it returns nil.
+func findProjects(dir string) ([]project.Project, error) {
+	p := project.New(dir)
+	packageJSON, err := p.PackageJSON()
+	if err != nil || packageJSON == nil {
+		return nil, err
+	}
+	ws, err := project.FindWorkspace(dir)
+	if err != nil {
+		return nil, err
+	}
+	if abs, err := filepath.Abs(dir); err == nil && ws != nil && ws.Dir == abs {
+		return ws.PluginPackages()
+	}
+	return []project.Project{p}, nil
+}
+
+// projectName returns the package name of a project, or its directory name.
+func projectName(p project.Project) string {
+	if packageJSON, err := p.PackageJSON(); err == nil && packageJSON != nil && packageJSON.Name != "" {
+		return packageJSON.Name
+	}
+	return filepath.Base(p.Dir())
+}
+
+func checkProject(ctx context.Context, options *Options) []Result {
+	projects, err := findProjects(options.Dir)
+	if err != nil {
+		return []Result{{Name: "Project", Status: Fail, Message: err.Error()}}
+	}
+	if projects == nil {
+		return []Result{{
+			Name:    "Project",
+			Status:  Warn,
+			Message: fmt.Sprintf("no package.json in %s", options.Dir),
+			Hint:    "Run crx doctor in a plugin project to check it, or create one with crx init.",
+		}}
+	}
+
+	var results []Result
+	for _, p := range projects {
+		result := Result{Name: projectName(p)}
+		if message, err := checkPluginProject(ctx, p); err != nil {
+			result.Status, result.Message = Fail, err.Error()
+		} else {
+			result.Message = message
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// checkPluginProject checks the configuration, plugin descriptor and entrypoints of a
+// project, and describes it.
+func checkPluginProject(ctx context.Context, p project.Project) (string, error) {
+	config, err := p.Config()
+	if err != nil {
+		return "", err
+	}
+	if _, err := p.PluginYML(ctx); err != nil {
+		return "", err
+	}
+	descriptor, err := p.PluginYMLFile()
+	if err != nil {
+		return "", err
+	}
+
+	// Check that the entrypoints exist
+	entrypoints := []string{build.DefaultEntrypoint}
+	if len(config.Targets) > 0 {
+		entrypoints = nil
+		for _, target := range config.Targets {
+			entrypoints = append(entrypoints, target.Entrypoint)
+		}
+	}
+	for _, entrypoint := range entrypoints {
+		if _, err := os.Stat(filepath.Join(p.Dir(), filepath.FromSlash(entrypoint))); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return "", fmt.Errorf("entrypoint %s doesn't exist", entrypoint)
+			}
+			return "", err
+		}
+	}
+
+	// Describe the project
+	description := "generated plugin.yml"
+	if descriptor != "" {
+		rel, _ := filepath.Rel(p.Dir(), descriptor)
+		description = filepath.ToSlash(rel)
+	}
+	if len(config.Targets) > 0 {
+		description += fmt.Sprintf(", %d targets", len(config.Targets))
+	}
+	return description, nil
+}
+
+// isTypesPackage reports whether a dependency provides the TypeScript types of plugins.
+func isTypesPackage(name string) bool {
+	return strings.HasPrefix(name, "@customrealms/") || name == "typescript"
+}
+
+func checkDependencies(ctx context.Context, options *Options) []Result {
+	projects, err := findProjects(options.Dir)
+	if err != nil || projects == nil {
+		// The project check already reports it
+		return nil
+	}
+	pm, _ := project.DetectPackageManager(options.Dir)
+
+	var results []Result
+	for _, p := range projects {
+		packageJSON, err := p.PackageJSON()
+		if err != nil {
+			continue
+		}
+		name := projectName(p)
+
+		// Find the dependencies providing types, in order
+		ranges := make(map[string]string)
+		var dependencies []string
+		for _, deps := range []map[string]string{packageJSON.Dependencies, packageJSON.DevDependencies} {
+			for dep, versionRange := range deps {
+				if !isTypesPackage(dep) {
+					continue
+				}
+				if _, ok := ranges[dep]; !ok {
+					dependencies = append(dependencies, dep)
+				}
+				ranges[dep] = versionRange
+			}
+		}
+		sort.Strings(dependencies)
+		if len(dependencies) == 0 {
+			results = append(results, Result{
+				Name:    name,
+				Status:  Warn,
+				Message: "no @customrealms packages in the dependencies",
+				Hint:    "Add the types of the plugin API to the dependencies, as in the projects created by crx init.",
+			})
+			continue
+		}
+
+		// Compare them to the installed versions
+		for _, dep := range dependencies {
+			versionRange := ranges[dep]
+			result := Result{Name: dep}
+			installed, err := installedVersion(p.Dir(), dep)
+			switch {
+			case err != nil:
+				result.Status, result.Message = Warn, err.Error()
+			case installed == "":
+				result.Status = Warn
+				result.Message = fmt.Sprintf("%s isn't installed in %s", versionRange, name)
+				result.Hint = fmt.Sprintf("Run %s install.", pm)
+			case !matchesRange(versionRange, installed):
+				result.Status = Warn
+				result.Message = fmt.Sprintf("%s is installed in %s, but package.json requires %s", installed, name, versionRange)
+				result.Hint = fmt.Sprintf("Run %s install.", pm)
+			default:
+				result.Message = fmt.Sprintf("%s installed in %s", installed, name)
+			}
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// installedVersion finds the version of a package installed for a project, looking in the
+// node_modules directories of the project and its parents like Node.js does. If the
+// package isn't installed, it returns an empty string.
+func installedVersion(dir, name string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		data, err := os.ReadFile(filepath.Join(dir, "node_modules", filepath.FromSlash(name), "package.json"))
+		if err == nil {
+			var packageJSON project.PackageJSON
+			if err := json.Unmarshal(data, &packageJSON); err != nil {
+				return "", fmt.Errorf("decoding package.json of %s: %w", name, err)
+			}
+			return packageJSON.Version, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// matchesRange reports whether an installed version matches the version range of a
+// dependency. Only simple ranges like "1.2.3", "^1.2.3" and "~1.2.3" are checked, by their
+// major version (and minor version for "~" and 0.x versions). Other ranges always match.
+func matchesRange(versionRange, installed string) bool {
+	operator := ""
+	switch {
+	case strings.HasPrefix(versionRange, "^"), strings.HasPrefix(versionRange, "~"):
+		operator, versionRange = versionRange[:1], versionRange[1:]
+	case strings.HasPrefix(versionRange, "="):
+		versionRange = versionRange[1:]
+	}
+	wanted := strings.Split(strings.TrimPrefix(versionRange, "v"), ".")
+	got := strings.Split(strings.TrimPrefix(installed, "v"), ".")
+	if len(wanted) < 2 || len(got) < 2 {
+		return true
+	}
+	for _, part := range append(wanted[:2:2], got[:2]...) {
+		if _, err := strconv.Atoi(part); err != nil {
+			return true
+		}
+	}
+	if operator == "" {
+		return strings.TrimPrefix(versionRange, "v") == strings.TrimPrefix(installed, "v")
+	}
+	if wanted[0] != got[0] {
+		return false
+	}
+	if operator == "~" || wanted[0] == "0" {
+		return wanted[1] == got[1]
+	}
+	return true
+}
@@ -0,0 +1,153 @@
+// Package doctor checks that the machine and the project are set up to build plugins and
+// run them in a dev server.
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/customrealms/cli/pkg/errdefs"
+)
+
+// Status is the outcome of a check.
+type Status int
+
+const (
+	// Pass means everything is fine.
+	Pass Status = iota
+	// Warn means something may not work as expected.
+	Warn
+	// Fail means something doesn't work.
+	Fail
+)
+
+func (s Status) String() string {
+	switch s {
+	case Pass:
+		return "ok"
+	case Warn:
+		return "warn"
+	default:
+		return "fail"
+	}
+}
+
+// Result is the result of a check.
+type Result struct {
+	// Name is what was checked, e.g. "Java".
+	Name    string
+	Status  Status
+	Message string
+	// Hint tells how to fix a warning or a failure.
+	Hint string
+	// kind marks the error a failure is reported as with its kind, or is nil for a plain
+	// error. It's set by Run, from the check.
+	kind func(error) error
+}
+
+// Check checks one part of the environment, and returns one or more results.
+type Check func(ctx context.Context, options *Options) []Result
+
+// Options configure the checks.
+type Options struct {
+	// Dir is the project directory.
+	Dir string
+	// MinecraftVersion is the Minecraft version of the dev server, which decides the Java
+	// version needed.
+	MinecraftVersion string
+	// Java is the java command, or the Java installation directory, of the dev server. If
+	// it's empty, the check looks for a recent enough runtime, like the dev server does.
+	Java string
+	// Offline skips the network checks.
+	Offline bool
+	// Client sends the requests of the network checks. If it's nil, the client of
+	// download.Default is used, so proxies are configured the same way.
+	Client *http.Client
+}
+
+// Checks are the checks run by Run, in the order of the report. Kind marks the error their
+// failures are reported as with an errdefs kind, so the exit code tells missing tools and
+// network problems apart from problems with the project. If it's nil, failures are plain
+// errors.
+var Checks = []struct {
+	Check Check
+	Kind  func(error) error
+}{
+	{checkJava, errdefs.Prerequisite},
+	{checkNode, errdefs.Prerequisite},
+	{checkPackageManager, errdefs.Prerequisite},
+	{checkGit, errdefs.Prerequisite},
+	{checkConfig, errdefs.Config},
+	{checkCache, nil},
+	{checkNetwork, errdefs.Network},
+	{checkProject, nil},
+	{checkDependencies, nil},
+}
+
+// Report is the list of results of all the checks.
+type Report []Result
+
+// Run runs all the checks concurrently, and returns their results in order.
+func Run(ctx context.Context, options *Options) Report {
+	results := make([][]Result, len(Checks))
+	var wg sync.WaitGroup
+	for i, check := range Checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = check.Check(ctx, options)
+			for j := range results[i] {
+				results[i][j].kind = check.Kind
+			}
+		}()
+	}
+	wg.Wait()
+
+	var report Report
+	for _, r := range results {
+		report = append(report, r...)
+	}
+	return report
+}
+
+// Count returns the number of results with a status.
+func (r Report) Count(status Status) int {
+	count := 0
+	for _, result := range r {
+		if result.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// Err returns an error if any check failed, or nil. The error has the kind of the first
+// failed check.
+func (r Report) Err() error {
+	for _, result := range r {
+		if result.Status != Fail {
+			continue
+		}
+		err := fmt.Errorf("%d checks failed", r.Count(Fail))
+		if result.kind != nil {
+			err = result.kind(err)
+		}
+		return err
+	}
+	return nil
+}
+
+// Print writes the report, with a summary at the end.
+func (r Report) Print(w io.Writer) {
+	for _, result := range r {
+		fmt.Fprintf(w, "[%-4s] %s: %s\n", result.Status, result.Name, result.Message)
+		if result.Hint != "" && result.Status != Pass {
+			fmt.Fprintf(w, "       %s\n", result.Hint)
+		}
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%d ok, %d warnings, %d failed\n", r.Count(Pass), r.Count(Warn), r.Count(Fail))
+}
@@ -0,0 +1,79 @@
+package doctor_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/customrealms/cli/pkg/doctor"
+	"github.com/customrealms/cli/pkg/errdefs"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestFile(t *testing.T, dir, filename, contents string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, filename)), 0777))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, filename), []byte(contents), 0666))
+}
+
+// findResult finds the result of a check by name.
+func findResult(t *testing.T, report doctor.Report, name string) doctor.Result {
+	t.Helper()
+	for _, result := range report {
+		if result.Name == name {
+			return result
+		}
+	}
+	t.Fatalf("no result named %q in the report", name)
+	return doctor.Result{}
+}
+
+func TestRun(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	t.Run("project", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTestFile(t, dir, "package.json", `{
+	"name": "my-plugin",
+	"devDependencies": {"@customrealms/core": "^0.4.0", "typescript": "^5.4.0", "esbuild": "^0.20.0"}
+}`)
+		writeTestFile(t, dir, "src/main.ts", "")
+		writeTestFile(t, dir, "plugin.yml", "name: MyPlugin\n")
+		writeTestFile(t, dir, "node_modules/@customrealms/core/package.json", `{"name": "@customrealms/core", "version": "0.5.1"}`)
+
+		report := doctor.Run(context.Background(), &doctor.Options{Dir: dir, Offline: true})
+		require.Equal(t, doctor.Result{Name: "my-plugin", Status: doctor.Pass, Message: "plugin.yml"}, findResult(t, report, "my-plugin"))
+		require.Equal(t, doctor.Warn, findResult(t, report, "@customrealms/core").Status)
+		require.Contains(t, findResult(t, report, "@customrealms/core").Message, "requires ^0.4.0")
+		require.Contains(t, findResult(t, report, "typescript").Message, "isn't installed")
+		require.Equal(t, doctor.Pass, findResult(t, report, "Cache").Status)
+	})
+
+	t.Run("missing entrypoint", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTestFile(t, dir, "package.json", `{"name": "my-plugins", "crx": {"targets": [{"name": "a", "entrypoint": "src/a.ts"}]}}`)
+
+		report := doctor.Run(context.Background(), &doctor.Options{Dir: dir, Offline: true})
+		result := findResult(t, report, "my-plugins")
+		require.Equal(t, doctor.Fail, result.Status)
+		require.Equal(t, "entrypoint src/a.ts doesn't exist", result.Message)
+
+		// Project problems aren't missing prerequisites
+		err := doctor.Report{result}.Err()
+		require.EqualError(t, err, "1 checks failed")
+		require.False(t, errors.Is(err, errdefs.ErrPrerequisite))
+	})
+
+	t.Run("no project", func(t *testing.T) {
+		report := doctor.Run(context.Background(), &doctor.Options{Dir: t.TempDir(), Offline: true})
+		require.Equal(t, doctor.Warn, findResult(t, report, "Project").Status)
+
+		var buf bytes.Buffer
+		report.Print(&buf)
+		require.Contains(t, buf.String(), "[warn] Project: no package.json in ")
+		require.NoError(t, doctor.Report{findResult(t, report, "Project")}.Err())
+	})
+}
@@ -0,0 +1,361 @@
+// Package download downloads files over HTTP for every part of the CLI, with status checks,
+// timeouts, retries, resumable downloads and progress output.
+package download
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/customrealms/cli/pkg/errdefs"
+)
+
+// Default is the client used by the CLI. It shows the progress of downloads when the
+// standard error is a terminal.
+var Default = New()
+
+// Client downloads files over HTTP. Failed requests are retried with an exponential
+// backoff, and every error is marked as a network error (see errdefs.ErrNetwork).
+type Client struct {
+	// HTTPClient sends the requests.
+	HTTPClient *http.Client
+	// Retries is the number of times a failed download is retried.
+	Retries int
+	// Backoff is the delay before the first retry. It doubles for each retry after that.
+	Backoff time.Duration
+	// Progress is where the progress bar of downloads is drawn. If it's nil, there's no
+	// progress bar.
+	Progress io.Writer
+	// Offline makes every download fail with ErrOffline, without sending requests, so the
+	// cached files are used right away.
+	Offline bool
+	// Mirrors are the base URLs of mirrors, by the base URL of the upstream server they
+	// replace. Requests for URLs under an upstream server are sent to its mirror instead.
+	Mirrors map[string]string
+}
+
+// ErrOffline is returned by the downloads of an offline client.
+var ErrOffline = errors.New("offline mode is on")
+
+// New creates a client with the default settings. Requests go through the proxy set with
+// the HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables. "file://" URLs are
+// supported too, and read local files.
+func New() *Client {
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+		IdleConnTimeout:       90 * time.Second,
+	}
+	// Local mirrors are given as "file://" URLs
+	transport.RegisterProtocol("file", fileTransport{})
+	client := &Client{
+		// There's no overall timeout, since large files take a while to download on slow
+		// connections. Stalled connections are caught by the transport timeouts, and by
+		// the context.
+		HTTPClient: &http.Client{Transport: transport},
+		Retries:    3,
+		Backoff:    500 * time.Millisecond,
+	}
+	if IsTerminal(os.Stderr) {
+		client.Progress = os.Stderr
+	}
+	return client
+}
+
+// fileTransport reads the local files of "file://" URLs. The path of the URL is converted
+// to a file path, so that URLs like "file:///C:/mirror/paper.jar" work on Windows too.
+type fileTransport struct{}
+
+func (fileTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	filename := filepath.FromSlash(req.URL.Path)
+	if len(filename) > 1 && filepath.VolumeName(filename[1:]) != "" {
+		// The path of "file:///C:/mirror" is "/C:/mirror", for the directory C:\mirror
+		filename = filename[1:]
+	}
+	dir, name := filepath.Split(filename)
+	return http.NewFileTransport(http.Dir(dir)).RoundTrip(withPath(req, "/"+name))
+}
+
+// withPath returns a copy of a request with another URL path.
+func withPath(req *http.Request, urlPath string) *http.Request {
+	req = req.Clone(req.Context())
+	req.URL.Path = urlPath
+	req.URL.RawPath = ""
+	return req
+}
+
+// IsTerminal reports whether a file is an interactive terminal.
+func IsTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
+
+// StatusError is returned when a server responds with an unexpected status.
+type StatusError struct {
+	URL        string
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("downloading %s: unexpected status %s", e.URL, e.Status)
+}
+
+// temporary reports whether a failed request may succeed if it's retried.
+func temporary(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500 || statusErr.StatusCode == http.StatusTooManyRequests
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
+
+// retry calls a function until it succeeds, it fails with an error that isn't temporary,
+// or the retries are exhausted.
+func (c *Client) retry(ctx context.Context, fn func() error) error {
+	if c.Offline {
+		return errdefs.Network(ErrOffline)
+	}
+	backoff := c.Backoff
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		if attempt >= c.Retries || !temporary(err) {
+			return errdefs.Network(err)
+		}
+		select {
+		case <-ctx.Done():
+			return errdefs.Network(ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// URL returns the URL a request for a URL is sent to: the same path on the mirror of its
+// upstream server, or the URL itself if there's no mirror for it. When upstream base URLs
+// overlap, e.g. GitHub and a repository on GitHub, the longest one matching wins.
+func (c *Client) URL(url string) string {
+	var upstream string
+	for base := range c.Mirrors {
+		if rest, ok := strings.CutPrefix(url, base); ok && (rest == "" || strings.HasPrefix(rest, "/")) && len(base) > len(upstream) {
+			upstream = base
+		}
+	}
+	if upstream == "" {
+		return url
+	}
+	return c.Mirrors[upstream] + strings.TrimPrefix(url, upstream)
+}
+
+// do sends a GET request, and checks the status of the response. Extra headers are set
+// on the request.
+func (c *Client) do(ctx context.Context, url string, header http.Header, statuses ...int) (*http.Response, error) {
+	url = c.URL(url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	res, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if len(statuses) == 0 {
+		statuses = []int{http.StatusOK}
+	}
+	for _, status := range statuses {
+		if res.StatusCode == status {
+			return res, nil
+		}
+	}
+	res.Body.Close()
+	return nil, &StatusError{URL: url, StatusCode: res.StatusCode, Status: res.Status}
+}
+
+// Get sends a GET request, retrying until the server responds with 200 OK. Reading the
+// body of the response isn't retried, and shows the progress bar.
+func (c *Client) Get(ctx context.Context, url string) (*http.Response, error) {
+	var res *http.Response
+	err := c.retry(ctx, func() error {
+		var err error
+		res, err = c.do(ctx, url, nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	res.Body = c.withProgress(res.Body, url, 0, res.ContentLength)
+	return res, nil
+}
+
+// Bytes downloads the contents of a URL.
+func (c *Client) Bytes(ctx context.Context, url string) ([]byte, error) {
+	var data []byte
+	err := c.retry(ctx, func() error {
+		res, err := c.do(ctx, url, nil)
+		if err != nil {
+			return err
+		}
+		body := c.withProgress(res.Body, url, 0, res.ContentLength)
+		defer body.Close()
+		data, err = io.ReadAll(body)
+		return err
+	})
+	return data, err
+}
+
+// JSON downloads a JSON document from a URL, and decodes it into a value.
+func (c *Client) JSON(ctx context.Context, url string, v any) error {
+	data, err := c.Bytes(ctx, url)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("decoding %s: %w", url, err)
+	}
+	return nil
+}
+
+// File downloads the contents of a URL to a file. The download goes to a temporary ".part"
+// file next to it first, so concurrent downloads of the same file don't write to each other's
+// partial file. If the download is interrupted, the retries resume it from there when the
+// server supports range requests, and the file hasn't changed on the server since.
+func (c *Client) File(ctx context.Context, url, filename string) error {
+	if c.Offline {
+		return errdefs.Network(ErrOffline)
+	}
+	partFile, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*.part")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(partFile.Name())
+	defer partFile.Close()
+
+	var validator string
+	err = c.retry(ctx, func() error {
+		return c.resume(ctx, url, partFile, &validator)
+	})
+	if err != nil {
+		return err
+	}
+	if err := partFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(partFile.Name(), filename)
+}
+
+// resume downloads the rest of a URL to a partial file. The validator is the ETag or the
+// Last-Modified time of the file on the server, sent in the If-Range header so that the
+// server sends the whole file again if it changed. It's set from the response.
+func (c *Client) resume(ctx context.Context, url string, file *os.File, validator *string) error {
+	offset, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	// Ask for the rest of the file, if it's still the same
+	header := http.Header{}
+	if offset > 0 && *validator != "" {
+		header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+		header.Set("If-Range", *validator)
+	}
+	res, err := c.do(ctx, url, header, http.StatusOK, http.StatusPartialContent, http.StatusRequestedRangeNotSatisfiable)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if etag := res.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		*validator = etag
+	} else {
+		*validator = res.Header.Get("Last-Modified")
+	}
+
+	switch res.StatusCode {
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The partial file is already complete, or isn't a prefix of the file anymore
+		if total, ok := rangeTotal(res.Header.Get("Content-Range")); ok && total == offset {
+			return nil
+		}
+		if err := file.Truncate(0); err != nil {
+			return err
+		}
+		return fmt.Errorf("downloading %s: %w", url, io.ErrUnexpectedEOF)
+
+	case http.StatusOK:
+		// The server sent the whole file
+		if err := file.Truncate(0); err != nil {
+			return err
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		offset = 0
+	}
+
+	// Write the rest of the file
+	total := int64(-1)
+	if res.ContentLength >= 0 {
+		total = offset + res.ContentLength
+	}
+	body := c.withProgress(res.Body, url, offset, total)
+	defer body.Close()
+	_, err = io.Copy(file, body)
+	return err
+}
+
+// rangeTotal parses the total size of a file from a Content-Range header like
+// "bytes */1234".
+func rangeTotal(contentRange string) (int64, bool) {
+	_, total, ok := strings.Cut(contentRange, "/")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(total, 10, 64)
+	return n, err == nil
+}
+
+// withProgress wraps the body of a response to draw the progress bar of the download, if
+// the client has one.
+func (c *Client) withProgress(body io.ReadCloser, rawURL string, done, total int64) io.ReadCloser {
+	if c.Progress == nil {
+		return body
+	}
+	name := rawURL
+	if u, err := url.Parse(rawURL); err == nil && path.Base(u.Path) != "/" && path.Base(u.Path) != "." {
+		name = path.Base(u.Path)
+	}
+	return &progressReader{
+		ReadCloser: body,
+		bar:        newProgressBar(c.Progress, name, total),
+		done:       done,
+	}
+}
@@ -0,0 +1,154 @@
+package download_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/customrealms/cli/pkg/download"
+	"github.com/customrealms/cli/pkg/errdefs"
+	"github.com/stretchr/testify/require"
+)
+
+func testClient() *download.Client {
+	client := download.New()
+	client.Backoff = 0
+	client.Progress = nil
+	return client
+}
+
+func TestBytesRetry(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+
+	data, err := testClient().Bytes(context.Background(), srv.URL)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(data))
+	require.Equal(t, 3, requests)
+}
+
+func TestBytesNotFound(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	_, err := testClient().Bytes(context.Background(), srv.URL)
+	var statusErr *download.StatusError
+	require.ErrorAs(t, err, &statusErr)
+	require.Equal(t, http.StatusNotFound, statusErr.StatusCode)
+	require.ErrorIs(t, err, errdefs.ErrNetwork)
+	require.Equal(t, 1, requests, "client errors aren't retried")
+}
+
+func TestFileResume(t *testing.T) {
+	content := strings.Repeat("0123456789", 100)
+	etag := `"v1"`
+	var ranges, ifRanges []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		ifRanges = append(ifRanges, r.Header.Get("If-Range"))
+		w.Header().Set("ETag", etag)
+		if len(ranges) == 1 {
+			// Cut the first response short
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.Write([]byte(content[:400]))
+			return
+		}
+		http.ServeContent(w, r, "file", time.Time{}, strings.NewReader(content))
+	}))
+	defer srv.Close()
+
+	filename := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, testClient().File(context.Background(), srv.URL, filename))
+	data, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	require.Equal(t, content, string(data))
+	require.Equal(t, []string{"", "bytes=400-"}, ranges)
+	require.Equal(t, []string{"", etag}, ifRanges)
+
+	// The partial file isn't resumed if the file changed on the server
+	ranges, ifRanges = nil, nil
+	content = strings.Repeat("abcdefghij", 100)
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		if len(ranges) == 1 {
+			w.Header().Set("ETag", etag)
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.Write([]byte(content[:400]))
+			return
+		}
+		w.Header().Set("ETag", `"v2"`)
+		http.ServeContent(w, r, "file", time.Time{}, strings.NewReader(content))
+	})
+	require.NoError(t, testClient().File(context.Background(), srv.URL, filename))
+	data, err = os.ReadFile(filename)
+	require.NoError(t, err)
+	require.Equal(t, content, string(data))
+
+	// No partial files are left behind
+	entries, err := os.ReadDir(filepath.Dir(filename))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
+
+func TestFileURL(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "paper.jar"), []byte("jar"), 0666))
+
+	// Windows paths like C:/mirror get a leading slash in the URL
+	urlPath := filepath.ToSlash(filepath.Join(dir, "paper.jar"))
+	if !strings.HasPrefix(urlPath, "/") {
+		urlPath = "/" + urlPath
+	}
+	data, err := testClient().Bytes(context.Background(), "file://"+urlPath)
+	require.NoError(t, err)
+	require.Equal(t, "jar", string(data))
+}
+
+func TestURL(t *testing.T) {
+	// The mirrors of GitHub and of the runtime releases on GitHub, as set by CRX_MIRROR
+	client := testClient()
+	client.Mirrors = map[string]string{
+		"https://github.com": "https://mirror.example.com/github",
+		"https://github.com/customrealms/bukkit-runtime/releases": "https://mirror.example.com/runtime",
+	}
+	for range 10 {
+		require.Equal(t, "https://mirror.example.com/runtime/download/v1/runtime.js",
+			client.URL("https://github.com/customrealms/bukkit-runtime/releases/download/v1/runtime.js"))
+		require.Equal(t, "https://mirror.example.com/github/acme/t/archive/main.zip",
+			client.URL("https://github.com/acme/t/archive/main.zip"))
+	}
+	require.Equal(t, "https://mirror.example.com/github", client.URL("https://github.com"))
+	require.Equal(t, "https://github.company.com/x", client.URL("https://github.company.com/x"))
+	require.Equal(t, "https://fill.papermc.io/v3", client.URL("https://fill.papermc.io/v3"))
+}
+
+func TestOffline(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("offline clients don't send requests")
+	}))
+	defer srv.Close()
+
+	client := testClient()
+	client.Offline = true
+	_, err := client.Bytes(context.Background(), srv.URL)
+	require.ErrorIs(t, err, download.ErrOffline)
+	require.ErrorIs(t, err, errdefs.ErrNetwork)
+}
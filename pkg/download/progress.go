@@ -0,0 +1,88 @@
+package download
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/customrealms/cli/pkg/project"
+)
+
+// progressWidth is the width of the progress bar, in characters.
+const progressWidth = 30
+
+// progressInterval is the minimum time between two redraws of the progress bar.
+const progressInterval = 100 * time.Millisecond
+
+// progressBar draws the progress of a download on a single terminal line.
+type progressBar struct {
+	w     io.Writer
+	name  string
+	total int64
+	drawn time.Time
+}
+
+func newProgressBar(w io.Writer, name string, total int64) *progressBar {
+	return &progressBar{w: w, name: name, total: total}
+}
+
+// draw redraws the progress bar, at most every progressInterval unless force is set.
+func (b *progressBar) draw(done int64, force bool) {
+	now := time.Now()
+	if !force && now.Sub(b.drawn) < progressInterval {
+		return
+	}
+	b.drawn = now
+
+	if b.total <= 0 {
+		fmt.Fprintf(b.w, "\r%s %s", b.name, project.Size(done))
+		return
+	}
+	filled := int(done * progressWidth / b.total)
+	if filled > progressWidth {
+		filled = progressWidth
+	}
+	fmt.Fprintf(b.w, "\r%s [%s%s] %3d%% %s/%s",
+		b.name,
+		strings.Repeat("=", filled),
+		strings.Repeat(" ", progressWidth-filled),
+		done*100/b.total,
+		project.Size(done),
+		project.Size(b.total),
+	)
+}
+
+// finish draws the final state of the progress bar, and ends its line.
+func (b *progressBar) finish(done int64) {
+	b.draw(done, true)
+	fmt.Fprintln(b.w)
+}
+
+// progressReader draws a progress bar as its contents are read.
+type progressReader struct {
+	io.ReadCloser
+	bar      *progressBar
+	done     int64
+	finished bool
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.done += int64(n)
+	if err == io.EOF && !r.finished {
+		r.finished = true
+		r.bar.finish(r.done)
+	} else {
+		r.bar.draw(r.done, false)
+	}
+	return n, err
+}
+
+func (r *progressReader) Close() error {
+	if !r.finished && r.done > 0 {
+		r.finished = true
+		r.bar.finish(r.done)
+	}
+	return r.ReadCloser.Close()
+}
@@ -0,0 +1,75 @@
+// Package errdefs defines the kinds of errors reported by the CLI. The kind of an error
+// decides the exit code of the CLI, so scripts can react to it.
+package errdefs
+
+import "errors"
+
+var (
+	// ErrPrerequisite is the kind of errors caused by a missing or unsuitable tool, like
+	// Java, Node.js or the package manager.
+	ErrPrerequisite = errors.New("missing prerequisite")
+	// ErrNetwork is the kind of errors caused by a failed download.
+	ErrNetwork = errors.New("network error")
+	// ErrTemplate is the kind of errors caused by an invalid project template.
+	ErrTemplate = errors.New("template error")
+	// ErrBuild is the kind of errors caused by a failed plugin build.
+	ErrBuild = errors.New("build error")
+	// ErrConfig is the kind of errors caused by an invalid user configuration.
+	ErrConfig = errors.New("config error")
+	// ErrTestFailed is the kind of errors reporting failed plugin tests, as opposed to
+	// tests that couldn't run.
+	ErrTestFailed = errors.New("tests failed")
+)
+
+// kindError is an error marked with a kind. Its message is the one of the error, and
+// errors.Is matches both the error and the kind.
+type kindError struct {
+	err  error
+	kind error
+}
+
+func (e *kindError) Error() string {
+	return e.err.Error()
+}
+
+func (e *kindError) Unwrap() []error {
+	return []error{e.err, e.kind}
+}
+
+// mark marks an error with a kind. Nil errors stay nil.
+func mark(err, kind error) error {
+	if err == nil || errors.Is(err, kind) {
+		return err
+	}
+	return &kindError{err: err, kind: kind}
+}
+
+// Prerequisite marks an error as caused by a missing or unsuitable tool.
+func Prerequisite(err error) error {
+	return mark(err, ErrPrerequisite)
+}
+
+// Network marks an error as caused by a failed download.
+func Network(err error) error {
+	return mark(err, ErrNetwork)
+}
+
+// Template marks an error as caused by an invalid project template.
+func Template(err error) error {
+	return mark(err, ErrTemplate)
+}
+
+// Build marks an error as caused by a failed plugin build.
+func Build(err error) error {
+	return mark(err, ErrBuild)
+}
+
+// Config marks an error as caused by an invalid user configuration.
+func Config(err error) error {
+	return mark(err, ErrConfig)
+}
+
+// TestFailed marks an error as reporting failed plugin tests.
+func TestFailed(err error) error {
+	return mark(err, ErrTestFailed)
+}
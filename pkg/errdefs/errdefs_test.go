@@ -0,0 +1,26 @@
+package errdefs_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/customrealms/cli/pkg/errdefs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMark(t *testing.T) {
+	err := errdefs.Network(fmt.Errorf("downloading template: %w", os.ErrDeadlineExceeded))
+	require.EqualError(t, err, "downloading template: i/o timeout")
+	require.ErrorIs(t, err, errdefs.ErrNetwork)
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	require.False(t, errors.Is(err, errdefs.ErrTemplate))
+
+	// Wrapped errors keep their kinds
+	err = errdefs.Template(fmt.Errorf("loading template: %w", err))
+	require.ErrorIs(t, err, errdefs.ErrTemplate)
+	require.ErrorIs(t, err, errdefs.ErrNetwork)
+
+	require.NoError(t, errdefs.Build(nil))
+}
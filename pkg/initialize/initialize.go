@@ -3,70 +3,306 @@ package initialize
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 
+	"github.com/customrealms/cli/pkg/errdefs"
 	"github.com/customrealms/cli/pkg/initialize/template"
+	"github.com/customrealms/cli/pkg/project"
 )
 
 type InitAction struct {
 	Name     string
 	Dir      string
 	Template template.Template
+	// Source is where the template comes from, recorded in the project so "crx upgrade"
+	// can apply later versions of the template. If it's empty, nothing is recorded.
+	Source string
+	// Vars are values of the template variables given by the user.
+	Vars map[string]string
+	// Prompter asks the user for the values of the other template variables. If it's nil,
+	// they use their default values.
+	Prompter template.Prompter
+	// PackageManager installs the project dependencies. If it's empty, it's detected from
+	// the directories above the project, and defaults to npm.
+	PackageManager project.PackageManager
+	// Existing decides what happens to the files already in the project directory.
+	Existing template.ExistingFiles
+	// DryRun prints what would be done, without writing anything or running the hooks.
+	DryRun bool
 }
 
 func (a *InitAction) Run(ctx context.Context) error {
 
-	// Check if NPM is installed on the machine
-	if _, err := exec.LookPath("npm"); err != nil {
-		fmt.Println("Couldn't find 'npm' command on your machine. Make sure NodeJS is installed.")
-		fmt.Println("Visit https://nodejs.org and download the most recent version.")
-		return nil
+	// Detect the package manager
+	if a.PackageManager == "" {
+		pm, err := project.DetectPackageManager(filepath.Dir(a.Dir))
+		if err != nil {
+			return err
+		}
+		a.PackageManager = pm
 	}
 
 	// If the template is nil, use the default template
 	if a.Template == nil {
-		tmpl, err := template.NewFromGitHub("customrealms", "cli-default-template", "master")
+		tmpl, err := template.NewFromSource(ctx, template.DefaultSource)
 		if err != nil {
-			return nil
+			return fmt.Errorf("loading default template: %w", err)
 		}
 		a.Template = tmpl
+		a.Source = template.DefaultSource
 	}
 
-	// Install the template in the directory
-	err := template.Install(
-		a.Template,
-		a.Dir,
-		&template.Options{
-			Name: a.Name,
-		},
-	)
+	// Resolve the values of the template variables
+	manifest, err := template.ReadManifest(a.Template)
 	if err != nil {
 		return err
 	}
+	options := &template.Options{Name: a.Name}
+	if options.Vars, err = manifest.ResolveVariables(options, a.Vars, a.Prompter); err != nil {
+		return err
+	}
 
-	// Run npm install
-	cmd := exec.CommandContext(ctx, "npm", "install")
-	cmd.Dir = a.Dir
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
+	// Find the steps to run after installing the template
+	hooks, err := manifest.PostInstallHooks(options)
+	if err != nil {
 		return err
 	}
 
-	// If Git is installed on the machine
-	if _, err := exec.LookPath("git"); err == nil {
+	// Render the template files, and decide what to do with each of them
+	files, err := template.Render(a.Template, options)
+	if err != nil {
+		return err
+	}
+	plan, err := template.PlanInstall(a.Dir, files, a.Existing)
+	if err != nil {
+		return err
+	}
+	var record *template.Record
+	if a.Source != "" {
+		record = &template.Record{
+			Source:  a.Source,
+			Version: a.Template.Version(),
+			Name:    options.Name,
+			Vars:    options.Vars,
+		}
+	}
+	if a.DryRun {
+		printPlan(os.Stdout, a.Dir, plan, record != nil, hooks)
+		return nil
+	}
 
-		// Initialize the git repo
-		cmd = exec.CommandContext(ctx, "git", "init")
-		cmd.Dir = a.Dir
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		if err := cmd.Run(); err != nil {
+	// Check if the package manager is installed on the machine, if it's needed
+	if needsPackageManager(hooks) {
+		if _, err := exec.LookPath(string(a.PackageManager)); err != nil {
+			err := fmt.Errorf("couldn't find '%s' command on your machine, make sure it's installed", a.PackageManager)
+			if a.PackageManager == project.NPM {
+				err = fmt.Errorf("%w: visit https://nodejs.org and download the most recent version", err)
+			}
+			return errdefs.Prerequisite(err)
+		}
+	}
+
+	// Install the template, and undo everything if any step fails, so the directory is
+	// left as it was
+	t, err := template.Begin(a.Dir)
+	if err != nil {
+		return err
+	}
+	if err := a.install(ctx, t, plan, files, record, hooks); err != nil {
+		fmt.Println("Undoing the changes to the project directory")
+		if rollbackErr := t.Rollback(); rollbackErr != nil {
+			return fmt.Errorf("%w (undoing the changes failed: %s)", err, rollbackErr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// install writes the template files and the template record, and runs the hooks.
+func (a *InitAction) install(
+	ctx context.Context,
+	t *template.Transaction,
+	plan []template.PlannedFile,
+	files map[string][]byte,
+	record *template.Record,
+	hooks []template.Hook,
+) error {
+
+	// Check if the project is already in a Git repository, before anything is written
+	_, err := os.Stat(filepath.Join(a.Dir, ".git"))
+	inRepository := err == nil
+
+	// Install the template in the directory
+	if err := t.Install(plan, files); err != nil {
+		return err
+	}
+
+	// Record the template the project was created from
+	if record != nil {
+		data, err := record.Bytes()
+		if err != nil {
+			return err
+		}
+		if err := t.WriteFile(template.RecordFilename, data); err != nil {
+			return fmt.Errorf("recording template: %w", err)
+		}
+		base, err := template.EncodeBase(files)
+		if err != nil {
+			return err
+		}
+		if err := t.WriteFile(template.BaseFilename, base); err != nil {
+			return fmt.Errorf("recording template: %w", err)
+		}
+	}
+
+	// Find the workspace the project is a package of. The dependencies of workspace
+	// packages are installed from the root of the workspace.
+	ws, err := project.FindWorkspace(filepath.Dir(a.Dir))
+	if err != nil {
+		return err
+	}
+	installDir := a.Dir
+	member := false
+	if ws != nil {
+		if member, err = ws.Contains(a.Dir); err != nil {
 			return err
 		}
+		if member {
+			installDir = ws.Dir
+		} else {
+			fmt.Printf("The project is inside the workspace %s, but isn't one of its packages.\n", ws.Dir)
+			fmt.Println("Add it to the workspace packages to share the workspace dependencies.")
+		}
+	}
 
+	// Workspace packages share the repository of the workspace, but a project that's only
+	// inside the workspace directory gets its own
+	r := hookRunner{
+		project:        project.New(a.Dir),
+		installProject: project.New(installDir),
+		packageManager: a.PackageManager,
+		skipGit:        member || inRepository,
+	}
+
+	// Guard what the steps may change besides the template files, so it's restored if a
+	// step fails
+	for _, hook := range hooks {
+		switch {
+		case hook.Step == template.HookInstall:
+			err = t.Guard(installDir, project.InstallFiles()...)
+		case hook.Step == template.HookGitInit && !r.skipGit:
+			err = t.Guard(a.Dir, ".git")
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// Run the steps in order
+	for _, hook := range hooks {
+		if err := r.run(ctx, hook); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
+
+// printPlan prints what installing a template would do.
+func printPlan(w io.Writer, dir string, plan []template.PlannedFile, record bool, hooks []template.Hook) {
+	fmt.Fprintf(w, "Installing the template in %s would:\n", dir)
+	for _, file := range plan {
+		if file.Action == template.FileSkip {
+			fmt.Fprintf(w, "  %-9s %s (already exists)\n", file.Action, file.Path)
+		} else {
+			fmt.Fprintf(w, "  %-9s %s\n", file.Action, file.Path)
+		}
+	}
+	if record {
+		fmt.Fprintf(w, "  %-9s %s\n", "record", template.RecordFilename)
+		fmt.Fprintf(w, "  %-9s %s\n", "record", template.BaseFilename)
+	}
+	for _, hook := range hooks {
+		fmt.Fprintf(w, "  %-9s %s\n", "run", hook)
+	}
+}
+
+// needsPackageManager reports whether any of the steps runs the package manager.
+func needsPackageManager(hooks []template.Hook) bool {
+	for _, hook := range hooks {
+		switch hook.Step {
+		case template.HookInstall, template.HookFormat, template.HookScript:
+			return true
+		}
+	}
+	return false
+}
+
+// hookRunner runs the steps after installing a template.
+type hookRunner struct {
+	project        project.Project
+	installProject project.Project
+	packageManager project.PackageManager
+	// skipGit skips the Git steps, when the project is part of a workspace or already in
+	// a Git repository.
+	skipGit bool
+	// gitRepo is set once a Git repository is initialized.
+	gitRepo bool
+}
+
+func (r *hookRunner) run(ctx context.Context, hook template.Hook) error {
+	switch hook.Step {
+	case template.HookInstall:
+		return r.packageManager.Install(ctx, r.installProject)
+
+	case template.HookFormat:
+		return r.packageManager.Run(ctx, r.project, "format")
+
+	case template.HookScript:
+		return r.packageManager.Run(ctx, r.project, hook.Script)
+
+	case template.HookGitInit:
+		// Skip Git if the project already has a repository, or Git isn't installed
+		if r.skipGit {
+			return nil
+		}
+		if _, err := exec.LookPath("git"); err != nil {
+			fmt.Println("Couldn't find 'git' command on your machine, skipping the Git repository.")
+			return nil
+		}
+
+		// Initialize the git repo
+		if err := r.project.Exec(ctx, "git", "init"); err != nil {
+			return err
+		}
+		r.gitRepo = true
+		return nil
+
+	case template.HookGitCommit:
+		// Only commit to the repository created for the project
+		if !r.gitRepo {
+			return nil
+		}
+		message := hook.Message
+		if message == "" {
+			message = "Initial commit"
+		}
+		if err := r.project.Exec(ctx, "git", "add", "-A"); err != nil {
+			return fmt.Errorf("git add: %w", err)
+		}
+
+		// The project is complete even without the commit, e.g. if Git doesn't know the
+		// user's identity yet
+		if err := r.project.Exec(ctx, "git", "commit", "-q", "-m", message); err != nil {
+			fmt.Printf("Couldn't create the first commit: git commit: %s\n", err)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown hook step %q", hook.Step)
+	}
+}
@@ -0,0 +1,112 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/customrealms/cli/pkg/errdefs"
+)
+
+// HookStep is a kind of step run after installing a template.
+type HookStep string
+
+const (
+	// HookInstall installs the project dependencies with the package manager.
+	HookInstall HookStep = "install"
+	// HookFormat runs the "format" script of package.json.
+	HookFormat HookStep = "format"
+	// HookScript runs a script of package.json.
+	HookScript HookStep = "script"
+	// HookGitInit initializes a Git repository.
+	HookGitInit HookStep = "git-init"
+	// HookGitCommit commits all the project files.
+	HookGitCommit HookStep = "git-commit"
+)
+
+// hookPhases orders the steps, so that scripts run with the dependencies installed, and
+// the first commit includes everything they generated.
+var hookPhases = map[HookStep]int{
+	HookInstall:   0,
+	HookFormat:    1,
+	HookScript:    1,
+	HookGitInit:   2,
+	HookGitCommit: 3,
+}
+
+// DefaultHooks are the steps run for templates that don't declare any.
+var DefaultHooks = []Hook{{Step: HookInstall}, {Step: HookGitInit}}
+
+// Hook is a step run after installing a template. In the manifest, it's either the name of
+// a step (e.g. "install"), or an object.
+type Hook struct {
+	// Step is the kind of step.
+	Step HookStep `json:"step"`
+	// Script is the package.json script run by a "script" step.
+	Script string `json:"script,omitempty"`
+	// Message is the commit message of a "git-commit" step. It's rendered as a template.
+	Message string `json:"message,omitempty"`
+	// If is a condition on the template variables. The step only runs when it's true.
+	If string `json:"if,omitempty"`
+}
+
+// String describes the step, e.g. "script lint:fix".
+func (h Hook) String() string {
+	if h.Step == HookScript {
+		return fmt.Sprintf("%s %s", h.Step, h.Script)
+	}
+	return string(h.Step)
+}
+
+func (h *Hook) UnmarshalJSON(data []byte) error {
+	var step string
+	if err := json.Unmarshal(data, &step); err == nil {
+		*h = Hook{Step: HookStep(step)}
+		return nil
+	}
+	type hook Hook
+	return json.Unmarshal(data, (*hook)(h))
+}
+
+// PostInstallHooks returns the steps to run after installing the template, without the
+// ones whose condition is false. Steps are sorted by phase: installing the dependencies,
+// then running scripts, then initializing the Git repository, then committing. Steps of
+// the same phase keep the order of the manifest.
+func (m *Manifest) PostInstallHooks(options *Options) ([]Hook, error) {
+	hooks := m.Hooks
+	if len(hooks) == 0 {
+		hooks = DefaultHooks
+	}
+
+	var selected []Hook
+	for _, hook := range hooks {
+		if _, ok := hookPhases[hook.Step]; !ok {
+			return nil, errdefs.Template(fmt.Errorf("unknown hook step %q", hook.Step))
+		}
+		if hook.Step == HookScript && hook.Script == "" {
+			return nil, errdefs.Template(fmt.Errorf("%q hook is missing a script", hook.Step))
+		}
+		ok, err := evalCondition(hook.If, options)
+		if err != nil {
+			return nil, errdefs.Template(err)
+		}
+		if !ok {
+			continue
+		}
+
+		// Render the commit message
+		if hook.Message != "" {
+			var sb strings.Builder
+			if err := copyAndModifyTemplateFile(&sb, strings.NewReader(hook.Message), options); err != nil {
+				return nil, errdefs.Template(fmt.Errorf("rendering commit message: %w", err))
+			}
+			hook.Message = sb.String()
+		}
+		selected = append(selected, hook)
+	}
+	sort.SliceStable(selected, func(i, j int) bool {
+		return hookPhases[selected[i].Step] < hookPhases[selected[j].Step]
+	})
+	return selected, nil
+}
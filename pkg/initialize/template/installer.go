@@ -1,44 +1,58 @@
 package template
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
-	"path"
 	"strings"
 	tmpl "text/template"
+
+	"github.com/customrealms/cli/pkg/errdefs"
 )
 
 const ManifestFilename = "manifest.json"
 
 type Options struct {
 	Name string
+	// Vars holds the values of the variables declared in the template manifest.
+	Vars map[string]any
 }
 
 type Manifest struct {
 	Files  map[string]bool   `json:"files"`
 	Rename map[string]string `json:"rename"`
+	// Include adds the files matching glob patterns, optionally depending on conditions.
+	Include []FileRule `json:"include"`
+	// Exclude removes the files matching glob patterns, optionally depending on conditions.
+	Exclude []FileRule `json:"exclude"`
+	// Variables are the values asked to the user when installing the template, in order.
+	Variables []Variable `json:"variables"`
+	// Hooks are the steps to run after installing the template files. If it's empty, the
+	// dependencies are installed and a Git repository is initialized.
+	Hooks []Hook `json:"hooks"`
 }
 
-func readManifest(tmpl Template) (*Manifest, error) {
+// ReadManifest reads the manifest file of a template.
+func ReadManifest(tmpl Template) (*Manifest, error) {
 
 	// Read the manifest file
 	manifestFile, err := tmpl.Open(ManifestFilename)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open %s in template: %s", ManifestFilename, err)
+		return nil, errdefs.Template(fmt.Errorf("failed to open %s in template: %s", ManifestFilename, err))
 	}
 	defer manifestFile.Close()
 	manifestBytes, err := io.ReadAll(manifestFile)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read %s in template: %s", ManifestFilename, err)
+		return nil, errdefs.Template(fmt.Errorf("failed to read %s in template: %s", ManifestFilename, err))
 	}
 
 	// Parse the manifest file as a manifest type
 	var manifest Manifest
 	if err := json.Unmarshal(manifestBytes, &manifest); err != nil {
-		return nil, fmt.Errorf("invalid %s format: %s", ManifestFilename, err)
+		return nil, errdefs.Template(fmt.Errorf("invalid %s format: %s", ManifestFilename, err))
 	}
 
 	// Return the manifest object
@@ -66,133 +80,115 @@ func isDirEmpty(dir string) (bool, error) {
 
 }
 
-func setupDir(dir string) error {
+// Install installs a template in a directory, which must be empty except for dotfiles.
+// If installing fails, the changes to the directory are undone.
+func Install(tmpl Template, dir string, options *Options) error {
 
-	// Perform a stat on the directory to check its details
-	stat, err := os.Stat(dir)
-	if err != nil && !errors.Is(err, os.ErrNotExist) {
+	// Render the template files
+	files, err := Render(tmpl, options)
+	if err != nil {
 		return err
 	}
 
-	// If the directory doesn't exist, create it
-	if errors.Is(err, os.ErrNotExist) {
-		if err := os.MkdirAll(dir, 0777); err != nil {
-			return fmt.Errorf("failed to create project directory: %s", err)
-		}
-		return nil
-	}
-
-	// If the directory exists, but is a file
-	if !stat.IsDir() {
-		return fmt.Errorf("project directory already exists, but is a file")
+	// Decide what to do with each file
+	plan, err := PlanInstall(dir, files, ExistingFail)
+	if err != nil {
+		return err
 	}
 
-	// If the project directory is not empty
-	empty, err := isDirEmpty(dir)
+	// Populate the project directory with the files
+	t, err := Begin(dir)
 	if err != nil {
 		return err
 	}
-	if !empty {
-		return fmt.Errorf("project directory is not empty")
+	if err := t.Install(plan, files); err != nil {
+		return errors.Join(err, t.Rollback())
 	}
 
-	// No error if we get here
+	// Return without error
 	return nil
 
 }
 
-func Install(tmpl Template, dir string, options *Options) error {
+// Render renders the files of a template in memory, mapped from their path in the project
+// directory to their contents.
+func Render(tmpl Template, options *Options) (map[string][]byte, error) {
 
 	// Read the manifest
-	manifest, err := readManifest(tmpl)
+	manifest, err := ReadManifest(tmpl)
 	if err != nil {
-		return err
-	}
-
-	// Create the project directory
-	if err := setupDir(dir); err != nil {
-		return err
+		return nil, err
 	}
 
-	// Populate the project directory using the manifest
-	if err := populateDir(tmpl, dir, manifest, options); err != nil {
-		return err
+	// Find the files to render
+	filenames, err := manifest.templateFiles(tmpl, options)
+	if err != nil {
+		return nil, errdefs.Template(err)
 	}
 
-	// Return without error
-	return nil
-
-}
-
-func populateDir(
-	tmpl Template,
-	dir string,
-	manifest *Manifest,
-	options *Options,
-) error {
-
-	// Loop through the files in the manifest
-	for filename, parse := range manifest.Files {
-		if err := populateDirWithFile(tmpl, filename, parse, dir, manifest, options); err != nil {
-			return err
+	// Loop through the files in order
+	files := make(map[string][]byte, len(filenames))
+	for _, filename := range sortedKeys(filenames) {
+		to, data, err := renderFile(tmpl, filename, filenames[filename], manifest, options)
+		if err != nil {
+			return nil, errdefs.Template(err)
 		}
+		files[to] = data
 	}
 
 	// No errors
-	return nil
+	return files, nil
 
 }
 
-func populateDirWithFile(
+// renderFile renders a template file, and returns its path in the project directory with
+// its contents.
+func renderFile(
 	tmpl Template,
 	filename string,
 	parse bool,
-	dir string,
 	manifest *Manifest,
 	options *Options,
-) error {
+) (string, []byte, error) {
 
 	// Read the file from the template
 	from, err := tmpl.Open(filename)
 	if err != nil {
-		return err
+		return "", nil, err
 	}
 	defer from.Close()
 
 	// Determine the new name for the file
-	to := filename
-	if manifest.Rename != nil {
-		if renameTo, ok := manifest.Rename[filename]; ok {
-			to = renameTo
-		}
-	}
-
-	// Make sure the directory exists for the file
-	toDir := path.Join(dir, path.Dir(to))
-	if err := os.MkdirAll(toDir, 0777); err != nil {
-		return fmt.Errorf("failed to create %q from template: %s", toDir, err)
-	}
-
-	// Copy the file contents
-	toFile, err := os.Create(path.Join(dir, to))
+	to, err := manifest.destination(filename, options)
 	if err != nil {
-		return fmt.Errorf("failed to create %q from template: %s", to, err)
+		return "", nil, err
 	}
-	defer toFile.Close()
 
-	// Copy the contents from one to the other
+	// Copy the contents, rendering them if needed
+	var buf bytes.Buffer
 	if parse {
-		err = copyAndModifyTemplateFile(toFile, from, options)
+		err = copyAndModifyTemplateFile(&buf, from, options)
 	} else {
-		_, err = io.Copy(toFile, from)
+		_, err = io.Copy(&buf, from)
 	}
 	if err != nil {
-		return err
+		return "", nil, fmt.Errorf("failed to render %q from template: %s", filename, err)
+	}
+	// Empty files are still files, unlike nil contents
+	data := buf.Bytes()
+	if data == nil {
+		data = []byte{}
 	}
+	return to, data, nil
 
-	// No errors with this file
-	return nil
+}
 
+// templateFuncs are the functions available to template files, in addition to the
+// builtin functions of text/template.
+var templateFuncs = tmpl.FuncMap{
+	"replace": strings.ReplaceAll,
+	"lower":   strings.ToLower,
+	"upper":   strings.ToUpper,
 }
 
 func copyAndModifyTemplateFile(to io.Writer, from io.Reader, options *Options) error {
@@ -203,8 +199,8 @@ func copyAndModifyTemplateFile(to io.Writer, from io.Reader, options *Options) e
 		return err
 	}
 
-	// Create template
-	t, err := tmpl.New("").Parse(string(fromBytes))
+	// Create template. Referring to a variable the manifest doesn't declare is an error.
+	t, err := tmpl.New("").Option("missingkey=error").Funcs(templateFuncs).Parse(string(fromBytes))
 	if err != nil {
 		return err
 	}
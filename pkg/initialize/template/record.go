@@ -0,0 +1,149 @@
+package template
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RecordFilename is the file recording the template a project was created from, relative
+// to the project directory.
+const RecordFilename = ".crx/template.json"
+
+// BaseFilename is the archive of the rendered template files a project was created from,
+// or last upgraded to, relative to the project directory. Upgrades merge the changes of the
+// template against these files, so they work for any template source.
+const BaseFilename = ".crx/base.zip"
+
+// Record describes the template a project was created from, so the template can be
+// rendered again to upgrade the project.
+type Record struct {
+	// Source is the template source, in any form accepted by NewFromSource.
+	Source string `json:"source"`
+	// Version is the exact version of the template, if it's known. See Template.Version.
+	Version string `json:"version,omitempty"`
+	// Name is the project name the template was rendered with.
+	Name string `json:"name"`
+	// Vars are the values of the template variables the template was rendered with.
+	Vars map[string]any `json:"vars,omitempty"`
+}
+
+// ReadRecord reads the template record of a project. If the project has none, it
+// returns nil.
+func ReadRecord(dir string) (*Record, error) {
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(RecordFilename)))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading %s: %w", RecordFilename, err)
+	}
+	var record Record
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", RecordFilename, err)
+	}
+	return &record, nil
+}
+
+// Bytes encodes the template record, as it's written to RecordFilename.
+func (r *Record) Bytes() ([]byte, error) {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// Write writes the template record to a project.
+func (r *Record) Write(dir string) error {
+	data, err := r.Bytes()
+	if err != nil {
+		return err
+	}
+	filename := filepath.Join(dir, filepath.FromSlash(RecordFilename))
+	if err := os.MkdirAll(filepath.Dir(filename), 0777); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, data, 0666)
+}
+
+// Options returns the options the template was rendered with.
+func (r *Record) Options() *Options {
+	return &Options{Name: r.Name, Vars: r.Vars}
+}
+
+// PinnedSource returns the source of the exact version of the template the project was
+// created from. Only GitHub templates with a known version can be pinned. For other sources,
+// it returns false.
+func (r *Record) PinnedSource() (string, bool) {
+	if r.Version == "" || !strings.HasPrefix(r.Source, "github:") {
+		return "", false
+	}
+	repo, _, _ := strings.Cut(r.Source, "#")
+	return repo + "#" + r.Version, true
+}
+
+// Values returns the values of the template variables as strings, like they're given
+// on the command line.
+func (r *Record) Values() map[string]string {
+	values := make(map[string]string, len(r.Vars))
+	for name, value := range r.Vars {
+		values[name] = fmt.Sprint(value)
+	}
+	return values
+}
+
+// EncodeBase encodes rendered template files, as they're written to BaseFilename.
+func EncodeBase(files map[string][]byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range sortedKeys(files) {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(files[name]); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ReadBase reads the rendered template files recorded in a project. If the project has
+// none, e.g. because it was created by an older version of the CLI, it returns nil.
+func ReadBase(dir string) (map[string][]byte, error) {
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(BaseFilename)))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading %s: %w", BaseFilename, err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", BaseFilename, err)
+	}
+	files := make(map[string][]byte, len(zr.File))
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", BaseFilename, err)
+		}
+		contents, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", BaseFilename, err)
+		}
+		files[f.Name] = contents
+	}
+	return files, nil
+}
@@ -0,0 +1,133 @@
+package template
+
+import (
+	"context"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/customrealms/cli/pkg/cache"
+	"github.com/customrealms/cli/pkg/download"
+	"github.com/customrealms/cli/pkg/errdefs"
+)
+
+// RegistryEnv is the environment variable setting the location of the template registry.
+const RegistryEnv = "CRX_TEMPLATE_REGISTRY"
+
+// registryCacheKey returns the cache key of the remote registry downloaded from a URL.
+func registryCacheKey(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return "templates/registry-" + hex.EncodeToString(sum[:16]) + ".json"
+}
+
+//go:embed registry.json
+var defaultRegistry []byte
+
+// Registry is an index of templates, so they can be found and used by name.
+type Registry struct {
+	Templates []RegistryEntry `json:"templates"`
+}
+
+// RegistryEntry describes a template in the registry.
+type RegistryEntry struct {
+	// Name is the short name of the template, used with "crx init --template <name>".
+	Name string `json:"name"`
+	// Description says what the template is for.
+	Description string `json:"description"`
+	// Source is where the template is, in any form accepted by NewFromSource.
+	Source string `json:"source"`
+	// Tags are keywords for searching.
+	Tags []string `json:"tags,omitempty"`
+}
+
+// LoadRegistry loads the template registry from a location, which is a local file path, a
+// "file://" URL or an "https://" URL. If the location is empty, it's the registry shipped
+// with the CLI. Remote registries are cached, and the cached copy is used when the download
+// fails.
+func LoadRegistry(ctx context.Context, location string) (*Registry, error) {
+	data, err := readRegistry(ctx, location)
+	if err != nil {
+		return nil, fmt.Errorf("loading template registry: %w", err)
+	}
+	var registry Registry
+	if err := json.Unmarshal(data, &registry); err != nil {
+		return nil, errdefs.Template(fmt.Errorf("decoding template registry: %w", err))
+	}
+	sort.Slice(registry.Templates, func(i, j int) bool {
+		return registry.Templates[i].Name < registry.Templates[j].Name
+	})
+	return &registry, nil
+}
+
+// readRegistry reads the contents of the registry at a location.
+func readRegistry(ctx context.Context, location string) ([]byte, error) {
+	switch {
+	case location == "":
+		return defaultRegistry, nil
+
+	case strings.HasPrefix(location, "https://"), strings.HasPrefix(location, "http://"):
+		data, _, err := cache.Fetch(ctx, registryCacheKey(location), "the template registry", func(ctx context.Context) ([]byte, cache.Meta, error) {
+			data, err := download.Default.Bytes(ctx, location)
+			return data, cache.Meta{Kind: "registry", Source: location}, err
+		})
+		return data, err
+
+	case strings.HasPrefix(location, "file://"):
+		u, err := url.Parse(location)
+		if err != nil {
+			return nil, err
+		}
+		return os.ReadFile(filepath.FromSlash(u.Path))
+
+	default:
+		return os.ReadFile(location)
+	}
+}
+
+// Lookup returns the template with a name, or nil if there isn't one.
+func (r *Registry) Lookup(name string) *RegistryEntry {
+	for i := range r.Templates {
+		if r.Templates[i].Name == name {
+			return &r.Templates[i]
+		}
+	}
+	return nil
+}
+
+// Search returns the templates whose name, description or tags contain the query, ignoring
+// case.
+func (r *Registry) Search(query string) []RegistryEntry {
+	query = strings.ToLower(query)
+	var results []RegistryEntry
+	for _, entry := range r.Templates {
+		fields := append([]string{entry.Name, entry.Description}, entry.Tags...)
+		for _, field := range fields {
+			if strings.Contains(strings.ToLower(field), query) {
+				results = append(results, entry)
+				break
+			}
+		}
+	}
+	return results
+}
+
+// Resolve returns the source of a template given to "crx init --template". Names of
+// templates in the registry resolve to their source, unless there's a local file or
+// directory with the same name. Anything else is returned as it is.
+func (r *Registry) Resolve(source string) string {
+	entry := r.Lookup(source)
+	if entry == nil {
+		return source
+	}
+	if _, err := os.Stat(source); err == nil {
+		return source
+	}
+	return entry.Source
+}
@@ -0,0 +1,152 @@
+package template
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/customrealms/cli/pkg/project"
+)
+
+// FileRule selects template files with a glob pattern, where "*" matches within a path
+// segment and "**" matches any number of segments (e.g. "src/**/*.ts").
+type FileRule struct {
+	// Pattern is the glob pattern of the files, relative to the template root.
+	Pattern string `json:"pattern"`
+	// Parse renders the files as templates.
+	Parse bool `json:"parse"`
+	// If is a condition on the template variables. The rule only applies when it's true.
+	// See evalCondition for the syntax.
+	If string `json:"if"`
+}
+
+// templateFiles returns the files to install from the template, mapped to whether they're
+// parsed. The files listed explicitly in the manifest are always included, files matching
+// the include rules are added, and files matching the exclude rules are removed.
+func (m *Manifest) templateFiles(tmpl Template, options *Options) (map[string]bool, error) {
+	files := make(map[string]bool)
+
+	// Add the files matching the include rules
+	if len(m.Include) > 0 {
+		names, err := tmpl.List()
+		if err != nil {
+			return nil, fmt.Errorf("listing template files: %w", err)
+		}
+		for _, rule := range m.Include {
+			ok, err := evalCondition(rule.If, options)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			for _, name := range names {
+				if name != ManifestFilename && project.MatchGlob(rule.Pattern, name) {
+					files[name] = rule.Parse
+				}
+			}
+		}
+	}
+
+	// Add the files listed explicitly
+	for name, parse := range m.Files {
+		files[name] = parse
+	}
+
+	// Remove the files matching the exclude rules
+	for _, rule := range m.Exclude {
+		ok, err := evalCondition(rule.If, options)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		for name := range files {
+			if project.MatchGlob(rule.Pattern, name) {
+				delete(files, name)
+			}
+		}
+	}
+	return files, nil
+}
+
+// destination returns the path a template file is installed to. Rename rules match either
+// the file itself or one of its parent directories, and the most specific one applies.
+// The renamed path is rendered as a template, e.g. "src/{{replace .Vars.package \".\" \"/\"}}".
+func (m *Manifest) destination(name string, options *Options) (string, error) {
+	// Find the most specific rename rule
+	var from string
+	for key := range m.Rename {
+		key = strings.TrimSuffix(key, "/")
+		if (name == key || strings.HasPrefix(name, key+"/")) && len(key) > len(from) {
+			from = key
+		}
+	}
+	if from == "" {
+		return name, nil
+	}
+	to := m.Rename[from]
+	if to == "" {
+		to = m.Rename[from+"/"]
+	}
+
+	// Render the new name
+	var sb strings.Builder
+	if err := copyAndModifyTemplateFile(&sb, strings.NewReader(to), options); err != nil {
+		return "", fmt.Errorf("renaming %q: %w", from, err)
+	}
+	renamed := path.Join(sb.String(), strings.TrimPrefix(name, from))
+	if renamed == "." || strings.HasPrefix(renamed, "../") || path.IsAbs(renamed) {
+		return "", fmt.Errorf("renaming %q: %q is outside the project directory", name, renamed)
+	}
+	return renamed, nil
+}
+
+// evalCondition evaluates a condition on the template variables. A condition is either the
+// name of a variable, optionally negated with "!" (e.g. "eslint", "!eslint"), or a template
+// like {{eq .Vars.license "MIT"}}. It's true unless the value is empty, false or zero. An
+// empty condition is always true.
+func evalCondition(condition string, options *Options) (bool, error) {
+	if condition == "" {
+		return true, nil
+	}
+
+	// Evaluate a variable
+	if !strings.Contains(condition, "{{") {
+		name, negate := strings.CutPrefix(condition, "!")
+		value, ok := options.Vars[name]
+		if !ok {
+			return false, fmt.Errorf("condition %q refers to unknown template variable %q", condition, name)
+		}
+		return truthy(fmt.Sprint(value)) != negate, nil
+	}
+
+	// Evaluate a template
+	var sb strings.Builder
+	if err := copyAndModifyTemplateFile(&sb, strings.NewReader(condition), options); err != nil {
+		return false, fmt.Errorf("evaluating condition %q: %w", condition, err)
+	}
+	return truthy(sb.String()), nil
+}
+
+// truthy reports whether the string form of a value is true.
+func truthy(value string) bool {
+	switch strings.TrimSpace(value) {
+	case "", "false", "0":
+		return false
+	default:
+		return true
+	}
+}
+
+// sortedKeys returns the keys of a map in order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,86 @@
+package template
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/customrealms/cli/pkg/errdefs"
+)
+
+// DefaultSource is the template used when none is given.
+const DefaultSource = "github:customrealms/cli-default-template#master"
+
+// NewFromSource creates a template from a source string, which is one of:
+//
+//   - a local directory or zip file path
+//   - "github:<org>/<repo>" or "github:<org>/<repo>#<ref>", where the ref is a branch, a
+//     tag or a commit, and defaults to the repository's default branch
+//   - an "https://" or "http://" URL of a zip file
+//   - a "file://" URL of a zip file or a directory
+func NewFromSource(ctx context.Context, source string) (Template, error) {
+	tmpl, err := newFromSource(ctx, source)
+	if err != nil {
+		return nil, errdefs.Template(err)
+	}
+	return tmpl, nil
+}
+
+func newFromSource(ctx context.Context, source string) (Template, error) {
+	// Download remote archives
+	archiveURL, err := ArchiveURL(source)
+	if err != nil {
+		return nil, err
+	}
+	if archiveURL != "" {
+		return NewFromURL(ctx, archiveURL)
+	}
+
+	// Open local files
+	if strings.HasPrefix(source, "file://") {
+		u, err := url.Parse(source)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template URL %q: %w", source, err)
+		}
+		return newFromPath(filepath.FromSlash(u.Path))
+	}
+	return newFromPath(source)
+}
+
+// ArchiveURL returns the URL of the zip archive of a remote template source. For local
+// sources, it returns an empty string.
+func ArchiveURL(source string) (string, error) {
+	switch {
+	case strings.HasPrefix(source, "github:"):
+		repoPath, ref, _ := strings.Cut(strings.TrimPrefix(source, "github:"), "#")
+		org, repo, ok := strings.Cut(repoPath, "/")
+		if !ok || org == "" || repo == "" || strings.Contains(repo, "/") {
+			return "", fmt.Errorf("invalid GitHub template %q, expected github:<org>/<repo>#<ref>", source)
+		}
+		if ref == "" {
+			ref = "HEAD"
+		}
+		return githubUrl(org, repo, ref), nil
+
+	case strings.HasPrefix(source, "https://"), strings.HasPrefix(source, "http://"):
+		return source, nil
+
+	default:
+		return "", nil
+	}
+}
+
+// newFromPath creates a template from a local directory or zip file.
+func newFromPath(filename string) (Template, error) {
+	stat, err := os.Stat(filename)
+	if err != nil {
+		return nil, fmt.Errorf("opening template: %w", err)
+	}
+	if stat.IsDir() {
+		return NewFromFS(os.DirFS(filename)), nil
+	}
+	return NewFromZipFile(filename)
+}
@@ -4,21 +4,58 @@ import (
 	"io"
 	"io/fs"
 	"path"
+	"strings"
 )
 
 type Template interface {
 	Open(name string) (io.ReadCloser, error)
+	// List returns the paths of all the files in the template, in order.
+	List() ([]string, error)
+	// Version identifies the exact version of the template, like the commit of a GitHub
+	// archive. If it's unknown, it returns an empty string.
+	Version() string
 }
 
 type templateFS struct {
-	Dir string
-	FS  fs.FS
+	Dir     string
+	FS      fs.FS
+	version string
+}
+
+func (t *templateFS) Version() string {
+	return t.version
 }
 
 func (t *templateFS) Open(name string) (io.ReadCloser, error) {
 	return t.FS.Open(path.Join(t.Dir, name))
 }
 
+func (t *templateFS) List() ([]string, error) {
+	root := t.Dir
+	if root == "" {
+		root = "."
+	}
+	var names []string
+	err := fs.WalkDir(t.FS, root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel := strings.TrimPrefix(name, root+"/")
+		if root == "." {
+			rel = name
+		}
+		names = append(names, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
 func NewFromFS(fileSystem fs.FS) Template {
 	return &templateFS{FS: fileSystem}
 }
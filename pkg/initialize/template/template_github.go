@@ -1,53 +1,54 @@
 package template
 
 import (
-	"archive/zip"
-	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
-	"io"
-	"net/http"
+
+	"github.com/customrealms/cli/pkg/cache"
+	"github.com/customrealms/cli/pkg/download"
 )
 
-func githubUrl(org, repo, branch string) string {
-	return fmt.Sprintf("https://github.com/%s/%s/archive/refs/heads/%s.zip", org, repo, branch)
-}
+// GitHubURL is the base URL GitHub repository archives are downloaded from. It can be
+// replaced with a mirror with the Mirrors of the download client, see the "Mirrors" section
+// of the README.
+const GitHubURL = "https://github.com"
 
-func downloadUrl(url string) (io.ReadCloser, error) {
-	res, err := http.Get(url)
-	if err != nil {
-		return nil, err
-	}
-	return res.Body, nil
+func githubUrl(org, repo, ref string) string {
+	return fmt.Sprintf("%s/%s/%s/archive/%s.zip", GitHubURL, org, repo, ref)
 }
 
-func NewFromGitHub(org, repo, branch string) (Template, error) {
-
-	// Get the github url
-	url := githubUrl(org, repo, branch)
-
-	// Download the zip file
-	body, err := downloadUrl(url)
-	if err != nil {
-		return nil, err
-	}
-	defer body.Close()
-
-	// Read the full body to bytes
-	zipBytes, err := io.ReadAll(body)
-	if err != nil {
-		return nil, err
-	}
+// ArchiveCacheKey returns the cache key of the template archive downloaded from a URL.
+// Archives downloaded from a GitHub mirror have the same key as from GitHub, since the URL
+// is the one of GitHub, so the cache can be moved to machines using another mirror, or none.
+func ArchiveCacheKey(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return "templates/" + hex.EncodeToString(sum[:16]) + ".zip"
+}
 
-	// Read the bytes
-	zipReader, err := zip.NewReader(bytes.NewReader(zipBytes), int64(len(zipBytes)))
+// NewFromURL creates a template from a zip archive downloaded from a URL. Archives are
+// cached, and the cached copy is used when the download fails, e.g. when offline.
+func NewFromURL(ctx context.Context, url string) (Template, error) {
+	zipBytes, _, err := cache.Fetch(ctx, ArchiveCacheKey(url), "the template", func(ctx context.Context) ([]byte, cache.Meta, error) {
+		data, err := download.Default.Bytes(ctx, url)
+		if err != nil {
+			return nil, cache.Meta{}, err
+		}
+		// Only cache valid archives
+		if _, err := NewFromZip(data); err != nil {
+			return nil, cache.Meta{}, err
+		}
+		return data, cache.Meta{Kind: "template", Source: url}, nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	return NewFromZip(zipBytes)
+}
 
-	// Define the base directory in the zip file
-	baseDir := fmt.Sprintf("%s-%s", repo, branch)
-
-	// Return the template with the file system
-	return NewFromFSDir(zipReader, baseDir), nil
-
+// NewFromGitHub creates a template from a GitHub repository, at a branch, a tag or a
+// commit.
+func NewFromGitHub(ctx context.Context, org, repo, ref string) (Template, error) {
+	return NewFromURL(ctx, githubUrl(org, repo, ref))
 }
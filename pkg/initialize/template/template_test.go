@@ -0,0 +1,404 @@
+package template_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/customrealms/cli/pkg/download"
+	"github.com/customrealms/cli/pkg/initialize/template"
+	"github.com/stretchr/testify/require"
+)
+
+// testZip creates a zip archive with the given files.
+func testZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, contents := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(contents))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+// readTemplateFile reads a file from a template.
+func readTemplateFile(t *testing.T, tmpl template.Template, name string) string {
+	t.Helper()
+	f, err := tmpl.Open(name)
+	require.NoError(t, err)
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestNewFromZip(t *testing.T) {
+	t.Run("top-level dir", func(t *testing.T) {
+		tmpl, err := template.NewFromZip(testZip(t, map[string]string{
+			"my-template-v1.2.0/manifest.json":          `{"files": {"package.json": true}}`,
+			"my-template-v1.2.0/package.json":           `{"name": "{{.Name}}"}`,
+			"my-template-v1.2.0/examples/manifest.json": `{}`,
+		}))
+		require.NoError(t, err)
+		require.Equal(t, `{"name": "{{.Name}}"}`, readTemplateFile(t, tmpl, "package.json"))
+	})
+
+	t.Run("root", func(t *testing.T) {
+		tmpl, err := template.NewFromZip(testZip(t, map[string]string{
+			"manifest.json": `{"files": {}}`,
+		}))
+		require.NoError(t, err)
+		require.Equal(t, `{"files": {}}`, readTemplateFile(t, tmpl, "manifest.json"))
+	})
+
+	t.Run("missing manifest", func(t *testing.T) {
+		_, err := template.NewFromZip(testZip(t, map[string]string{"a/package.json": "{}"}))
+		require.ErrorContains(t, err, "doesn't contain a manifest.json file")
+	})
+}
+
+func TestNewFromSource(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "manifest.json"), []byte(`{"files": {}}`), 0666))
+	zipFile := filepath.Join(t.TempDir(), "template.zip")
+	require.NoError(t, os.WriteFile(zipFile, testZip(t, map[string]string{"t/manifest.json": `{"files": {}}`}), 0666))
+
+	t.Run("directory", func(t *testing.T) {
+		tmpl, err := template.NewFromSource(context.Background(), dir)
+		require.NoError(t, err)
+		require.Equal(t, `{"files": {}}`, readTemplateFile(t, tmpl, "manifest.json"))
+	})
+
+	t.Run("file url", func(t *testing.T) {
+		tmpl, err := template.NewFromSource(context.Background(), "file://"+filepath.ToSlash(zipFile))
+		require.NoError(t, err)
+		require.Equal(t, `{"files": {}}`, readTemplateFile(t, tmpl, "manifest.json"))
+	})
+
+	t.Run("invalid github", func(t *testing.T) {
+		_, err := template.NewFromSource(context.Background(), "github:customrealms")
+		require.ErrorContains(t, err, "invalid GitHub template")
+	})
+}
+
+// testPrompter answers prompts from a list, and records the validation errors it's shown.
+type testPrompter struct {
+	answers []string
+	invalid []string
+}
+
+func (p *testPrompter) Prompt(v *template.Variable, defaultValue string, invalid error) (string, error) {
+	if invalid != nil {
+		p.invalid = append(p.invalid, invalid.Error())
+	}
+	answer := p.answers[0]
+	p.answers = p.answers[1:]
+	return answer, nil
+}
+
+func TestResolveVariables(t *testing.T) {
+	manifest := &template.Manifest{
+		Variables: []template.Variable{
+			{Name: "author", Required: true},
+			{Name: "package", Default: "com.example.{{.Name}}", Pattern: `^[a-z][a-z0-9.]*$`},
+			{Name: "mc", Type: template.VariableChoice, Choices: []string{"1.20.4", "1.21.1"}, Default: "1.21.1"},
+			{Name: "workflow", Type: template.VariableBool, Default: true},
+			{Name: "port", Type: template.VariableInt, Default: float64(25565)},
+		},
+	}
+	options := &template.Options{Name: "myplugin"}
+
+	t.Run("flags and defaults", func(t *testing.T) {
+		vars, err := manifest.ResolveVariables(options, map[string]string{"author": "Steve", "workflow": "false"}, nil)
+		require.NoError(t, err)
+		require.Equal(t, map[string]any{
+			"author":   "Steve",
+			"package":  "com.example.myplugin",
+			"mc":       "1.21.1",
+			"workflow": false,
+			"port":     25565,
+		}, vars)
+	})
+
+	t.Run("prompts", func(t *testing.T) {
+		prompter := &testPrompter{answers: []string{"Alex", "Not A Package", "com.alex", "1.19", "1.20.4", "", ""}}
+		vars, err := manifest.ResolveVariables(options, nil, prompter)
+		require.NoError(t, err)
+		require.Equal(t, "com.alex", vars["package"])
+		require.Equal(t, "1.20.4", vars["mc"])
+		require.Len(t, prompter.invalid, 2)
+	})
+
+	t.Run("missing required", func(t *testing.T) {
+		_, err := manifest.ResolveVariables(options, nil, nil)
+		require.ErrorContains(t, err, `template variable "author" is required`)
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		_, err := manifest.ResolveVariables(options, map[string]string{"nope": "1"}, nil)
+		require.ErrorContains(t, err, `unknown template variable "nope"`)
+	})
+}
+
+func TestInstallRules(t *testing.T) {
+	tmpl := template.NewFromFS(fstest.MapFS{
+		"manifest.json": {Data: []byte(`{
+			"files": {"README.md": false},
+			"include": [
+				{"pattern": "src/**/*.ts", "parse": true},
+				{"pattern": ".github/**", "if": "workflow"},
+				{"pattern": ".eslintrc.json", "if": "{{eq .Vars.lint \"eslint\"}}"}
+			],
+			"exclude": [
+				{"pattern": "src/**/*.test.ts", "if": "!tests"}
+			],
+			"rename": {
+				"src/package": "src/{{replace .Vars.package \".\" \"/\"}}"
+			}
+		}`)},
+		"README.md":                     {Data: []byte("# {{.Name}}")},
+		"src/main.ts":                   {Data: []byte("// {{.Name}}")},
+		"src/package/util.ts":           {Data: []byte("// {{.Vars.package}}")},
+		"src/package/util.test.ts":      {Data: []byte("")},
+		".github/workflows/build.yml":   {Data: []byte("")},
+		".eslintrc.json":                {Data: []byte("{}")},
+		"unlisted.txt":                  {Data: []byte("")},
+		"src/package/nested/helpers.ts": {Data: []byte("")},
+	})
+
+	dir := filepath.Join(t.TempDir(), "project")
+	err := template.Install(tmpl, dir, &template.Options{
+		Name: "myplugin",
+		Vars: map[string]any{"package": "com.example", "workflow": false, "lint": "eslint", "tests": false},
+	})
+	require.NoError(t, err)
+
+	var files []string
+	require.NoError(t, filepath.WalkDir(dir, func(name string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			rel, _ := filepath.Rel(dir, name)
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return err
+	}))
+	require.Equal(t, []string{
+		".eslintrc.json",
+		"README.md",
+		"src/com/example/nested/helpers.ts",
+		"src/com/example/util.ts",
+		"src/main.ts",
+	}, files)
+
+	data, err := os.ReadFile(filepath.Join(dir, "src", "com", "example", "util.ts"))
+	require.NoError(t, err)
+	require.Equal(t, "// com.example", string(data))
+	data, err = os.ReadFile(filepath.Join(dir, "README.md"))
+	require.NoError(t, err)
+	require.Equal(t, "# {{.Name}}", string(data))
+}
+
+func TestPostInstallHooks(t *testing.T) {
+	var manifest template.Manifest
+	require.NoError(t, json.Unmarshal([]byte(`{"hooks": [
+		{"step": "git-commit", "message": "Initial commit"},
+		"git-init",
+		{"step": "script", "script": "lint:fix", "if": "lint"},
+		"install",
+		"format"
+	]}`), &manifest))
+
+	steps := func(hooks []template.Hook) []template.HookStep {
+		var steps []template.HookStep
+		for _, hook := range hooks {
+			steps = append(steps, hook.Step)
+		}
+		return steps
+	}
+
+	hooks, err := manifest.PostInstallHooks(&template.Options{Vars: map[string]any{"lint": true}})
+	require.NoError(t, err)
+	require.Equal(t, []template.HookStep{"install", "script", "format", "git-init", "git-commit"}, steps(hooks))
+
+	hooks, err = manifest.PostInstallHooks(&template.Options{Vars: map[string]any{"lint": false}})
+	require.NoError(t, err)
+	require.Equal(t, []template.HookStep{"install", "format", "git-init", "git-commit"}, steps(hooks))
+
+	hooks, err = (&template.Manifest{}).PostInstallHooks(&template.Options{})
+	require.NoError(t, err)
+	require.Equal(t, template.DefaultHooks, hooks)
+}
+
+func TestRegistry(t *testing.T) {
+	registryFile := filepath.Join(t.TempDir(), "registry.json")
+	require.NoError(t, os.WriteFile(registryFile, []byte(`{"templates": [
+		{"name": "minigame", "description": "Minigame with arenas", "source": "github:acme/minigame#v2", "tags": ["games"]},
+		{"name": "economy", "description": "Shops and currencies", "source": "https://example.com/economy.zip"}
+	]}`), 0666))
+
+	registry, err := template.LoadRegistry(context.Background(), registryFile)
+	require.NoError(t, err)
+	require.Equal(t, "economy", registry.Templates[0].Name)
+	require.Equal(t, "github:acme/minigame#v2", registry.Resolve("minigame"))
+	require.Equal(t, "github:other/repo", registry.Resolve("github:other/repo"))
+	require.Len(t, registry.Search("GAME"), 1)
+	require.Empty(t, registry.Search("nothing"))
+
+	defaultRegistry, err := template.LoadRegistry(context.Background(), "")
+	require.NoError(t, err)
+	require.NotNil(t, defaultRegistry.Lookup("default"))
+}
+
+func TestRegistryCache(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	backoff := download.Default.Backoff
+	download.Default.Backoff = 0
+	t.Cleanup(func() { download.Default.Backoff = backoff })
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"templates": [{"name": %q, "source": "github:acme/t"}]}`, strings.Trim(r.URL.Path, "/"))
+	}))
+
+	// Each registry is cached separately
+	for _, name := range []string{"first", "second"} {
+		_, err := template.LoadRegistry(context.Background(), srv.URL+"/"+name)
+		require.NoError(t, err)
+	}
+
+	// The cached copy of each registry is used when offline
+	srv.Close()
+	for _, name := range []string{"first", "second"} {
+		registry, err := template.LoadRegistry(context.Background(), srv.URL+"/"+name)
+		require.NoError(t, err)
+		require.NotNil(t, registry.Lookup(name))
+	}
+}
+
+func TestNewFromURLCache(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	backoff := download.Default.Backoff
+	download.Default.Backoff = 0
+	t.Cleanup(func() { download.Default.Backoff = backoff })
+	archive := testZip(t, map[string]string{"t-main/manifest.json": `{"files": {}}`})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(archive)
+	}))
+
+	// The first download is cached
+	_, err := template.NewFromURL(context.Background(), srv.URL+"/t.zip")
+	require.NoError(t, err)
+
+	// The cached copy is used when offline
+	srv.Close()
+	tmpl, err := template.NewFromURL(context.Background(), srv.URL+"/t.zip")
+	require.NoError(t, err)
+	require.Equal(t, `{"files": {}}`, readTemplateFile(t, tmpl, "manifest.json"))
+
+	// Templates that were never downloaded fail
+	_, err = template.NewFromURL(context.Background(), srv.URL+"/other.zip")
+	require.Error(t, err)
+}
+
+func TestPlanInstall(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("mine"), 0666))
+	files := map[string][]byte{"README.md": []byte("theirs"), "src/main.ts": []byte("")}
+
+	_, err := template.PlanInstall(dir, files, template.ExistingFail)
+	require.ErrorIs(t, err, template.ErrDirNotEmpty)
+
+	plan, err := template.PlanInstall(dir, files, template.ExistingKeep)
+	require.NoError(t, err)
+	require.Equal(t, []template.PlannedFile{
+		{Path: "README.md", Action: template.FileSkip},
+		{Path: "src/main.ts", Action: template.FileCreate},
+	}, plan)
+
+	plan, err = template.PlanInstall(dir, files, template.ExistingOverwrite)
+	require.NoError(t, err)
+	require.Equal(t, template.FileOverwrite, plan[0].Action)
+
+	plan, err = template.PlanInstall(filepath.Join(dir, "new"), files, template.ExistingFail)
+	require.NoError(t, err)
+	require.Len(t, plan, 2)
+}
+
+func TestTransactionRollback(t *testing.T) {
+	t.Run("existing directory", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "src"), 0777))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("mine"), 0666))
+
+		tx, err := template.Begin(dir)
+		require.NoError(t, err)
+		require.NoError(t, tx.WriteFile("README.md", []byte("theirs")))
+		require.NoError(t, tx.WriteFile("src/commands/main.ts", []byte("code")))
+		require.NoError(t, tx.WriteFile("package.json", []byte("{}")))
+		require.NoError(t, tx.Guard(dir, "node_modules"))
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "node_modules", "a"), 0777))
+
+		// Files created meanwhile by someone else are kept
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("notes"), 0666))
+
+		require.NoError(t, tx.Rollback())
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		require.Len(t, entries, 3)
+		data, err := os.ReadFile(filepath.Join(dir, "README.md"))
+		require.NoError(t, err)
+		require.Equal(t, "mine", string(data))
+		require.NoDirExists(t, filepath.Join(dir, "src", "commands"))
+		require.NoDirExists(t, filepath.Join(dir, "node_modules"))
+		require.FileExists(t, filepath.Join(dir, "notes.txt"))
+	})
+
+	t.Run("new directory", func(t *testing.T) {
+		root := t.TempDir()
+		dir := filepath.Join(root, "plugins", "project")
+		tx, err := template.Begin(dir)
+		require.NoError(t, err)
+		require.NoError(t, tx.WriteFile("package.json", []byte("{}")))
+
+		require.NoError(t, tx.Rollback())
+		require.NoDirExists(t, filepath.Join(root, "plugins"))
+	})
+
+	t.Run("workspace root", func(t *testing.T) {
+		root := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(root, "package-lock.json"), []byte("old"), 0666))
+		require.NoError(t, os.MkdirAll(filepath.Join(root, "node_modules", "a"), 0777))
+		tx, err := template.Begin(filepath.Join(root, "packages", "project"))
+		require.NoError(t, err)
+		require.NoError(t, tx.WriteFile("package.json", []byte("{}")))
+
+		// The install changes the lockfile and adds packages to the workspace root
+		require.NoError(t, tx.Guard(root, "package-lock.json", "yarn.lock", "node_modules"))
+		require.NoError(t, os.WriteFile(filepath.Join(root, "package-lock.json"), []byte("new"), 0666))
+		require.NoError(t, os.MkdirAll(filepath.Join(root, "node_modules", "a", "lib"), 0777))
+		require.NoError(t, os.MkdirAll(filepath.Join(root, "node_modules", "b"), 0777))
+
+		require.NoError(t, tx.Rollback())
+		data, err := os.ReadFile(filepath.Join(root, "package-lock.json"))
+		require.NoError(t, err)
+		require.Equal(t, "old", string(data))
+		require.DirExists(t, filepath.Join(root, "node_modules", "a"))
+		require.NoDirExists(t, filepath.Join(root, "node_modules", "a", "lib"))
+		require.NoDirExists(t, filepath.Join(root, "node_modules", "b"))
+		require.NoDirExists(t, filepath.Join(root, "packages"))
+	})
+}
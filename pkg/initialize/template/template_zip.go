@@ -0,0 +1,72 @@
+package template
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// NewFromZip creates a template from the contents of a zip archive. The template is the
+// directory containing the shallowest manifest file in the archive, so archives with a
+// top-level directory (like GitHub's "<repo>-<ref>") work without knowing its name.
+// The version of templates from GitHub archives is the commit of the archive.
+func NewFromZip(data []byte) (Template, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("reading template zip: %w", err)
+	}
+	baseDir, err := findBaseDir(zr)
+	if err != nil {
+		return nil, err
+	}
+	// GitHub archives have the commit they were created from as their comment
+	tmpl := &templateFS{Dir: baseDir, FS: zr}
+	if commitRegexp.MatchString(zr.Comment) {
+		tmpl.version = zr.Comment
+	}
+	return tmpl, nil
+}
+
+// commitRegexp matches the hash of a Git commit.
+var commitRegexp = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// NewFromZipFile creates a template from a zip archive on disk.
+func NewFromZipFile(filename string) (Template, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return NewFromZip(data)
+}
+
+// findBaseDir returns the directory containing the shallowest manifest file in the archive.
+func findBaseDir(zr *zip.Reader) (string, error) {
+	found := false
+	var baseDir string
+	for _, f := range zr.File {
+		if path.Base(f.Name) != ManifestFilename || f.FileInfo().IsDir() {
+			continue
+		}
+		dir := path.Dir(f.Name)
+		if !found || depth(dir) < depth(baseDir) {
+			baseDir = dir
+			found = true
+		}
+	}
+	if !found {
+		return "", fmt.Errorf("template zip doesn't contain a %s file", ManifestFilename)
+	}
+	return baseDir, nil
+}
+
+// depth returns the number of directories in a slash-separated path.
+func depth(dir string) int {
+	if dir == "." {
+		return 0
+	}
+	return strings.Count(dir, "/") + 1
+}
@@ -0,0 +1,309 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ErrDirNotEmpty is returned when installing a template in a directory that already has
+// files, without saying what to do with them.
+var ErrDirNotEmpty = errors.New("project directory is not empty")
+
+// ExistingFiles decides what happens to the files already in the project directory.
+type ExistingFiles int
+
+const (
+	// ExistingFail refuses to install in a directory with files other than dotfiles.
+	ExistingFail ExistingFiles = iota
+	// ExistingKeep installs the template files that don't exist yet, and keeps the
+	// existing ones.
+	ExistingKeep
+	// ExistingOverwrite installs all the template files, overwriting the existing ones.
+	ExistingOverwrite
+)
+
+// FileAction is what installing a template does to a file.
+type FileAction string
+
+const (
+	FileCreate    FileAction = "create"
+	FileOverwrite FileAction = "overwrite"
+	FileSkip      FileAction = "skip"
+)
+
+// PlannedFile is a file written, or skipped, when installing a template.
+type PlannedFile struct {
+	// Path is the slash-separated path of the file in the project directory.
+	Path   string
+	Action FileAction
+}
+
+// PlanInstall decides what to do with each rendered file of a template, depending on the
+// files already in the project directory. Files are returned in order of their path.
+func PlanInstall(dir string, files map[string][]byte, existing ExistingFiles) ([]PlannedFile, error) {
+
+	// Check the project directory
+	stat, err := os.Stat(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil && !stat.IsDir() {
+		return nil, fmt.Errorf("project directory already exists, but is a file")
+	}
+	if existing == ExistingFail {
+		empty, err := isDirEmpty(dir)
+		if err != nil {
+			return nil, err
+		}
+		if !empty {
+			return nil, ErrDirNotEmpty
+		}
+	}
+
+	// Find what to do with each file
+	plan := make([]PlannedFile, 0, len(files))
+	for _, name := range sortedKeys(files) {
+		stat, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name)))
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			plan = append(plan, PlannedFile{Path: name, Action: FileCreate})
+		case err != nil:
+			return nil, err
+		case stat.IsDir():
+			return nil, fmt.Errorf("can't create %q from template, it's an existing directory", name)
+		case existing == ExistingKeep:
+			plan = append(plan, PlannedFile{Path: name, Action: FileSkip})
+		default:
+			plan = append(plan, PlannedFile{Path: name, Action: FileOverwrite})
+		}
+	}
+	return plan, nil
+
+}
+
+// Transaction writes files to a project directory, and can undo everything it did: the
+// files it wrote, and the files it guarded before other steps changed them, e.g. installing
+// the dependencies. Nothing else in the directory is touched, so files created meanwhile by
+// someone else are kept.
+type Transaction struct {
+	dir string
+	// createdDir is the topmost directory created by the transaction for the project
+	// directory, if it didn't exist.
+	createdDir string
+	// created are the files and directories created by the transaction, in order.
+	created []string
+	// backups are the previous contents of the files overwritten by the transaction.
+	backups map[string][]byte
+	// snapshots are the paths within the guarded directories that existed when they were
+	// guarded, relative to each directory.
+	snapshots map[string]map[string]bool
+}
+
+// Begin begins a transaction in a project directory, creating the directory if it doesn't
+// exist.
+func Begin(dir string) (*Transaction, error) {
+	t := &Transaction{
+		dir:       dir,
+		backups:   make(map[string][]byte),
+		snapshots: make(map[string]map[string]bool),
+	}
+
+	// Create the directory if it doesn't exist, remembering the topmost directory created
+	stat, err := os.Stat(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		created, err := topmostMissingDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(dir, 0777); err != nil {
+			return nil, fmt.Errorf("failed to create project directory: %s", err)
+		}
+		t.createdDir = created
+		return t, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !stat.IsDir() {
+		return nil, fmt.Errorf("project directory already exists, but is a file")
+	}
+	return t, nil
+}
+
+// topmostMissingDir returns the topmost directory above and including dir that doesn't
+// exist, which is the first one MkdirAll creates.
+func topmostMissingDir(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	missing := dir
+	for {
+		above := filepath.Dir(missing)
+		if above == missing {
+			return missing, nil
+		}
+		if _, err := os.Stat(above); err == nil {
+			return missing, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+		missing = above
+	}
+}
+
+// Install writes the planned files to the project directory.
+func (t *Transaction) Install(plan []PlannedFile, files map[string][]byte) error {
+	for _, file := range plan {
+		if file.Action == FileSkip {
+			continue
+		}
+		if err := t.WriteFile(file.Path, files[file.Path]); err != nil {
+			return fmt.Errorf("failed to create %q from template: %s", file.Path, err)
+		}
+	}
+	return nil
+}
+
+// WriteFile writes a file to the project directory, given its slash-separated path.
+func (t *Transaction) WriteFile(name string, data []byte) error {
+	filename := filepath.Join(t.dir, filepath.FromSlash(name))
+
+	// Create the missing directories above the file, remembering the topmost one
+	parent := filepath.Dir(filename)
+	if _, err := os.Stat(parent); errors.Is(err, os.ErrNotExist) {
+		created, err := topmostMissingDir(parent)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(parent, 0777); err != nil {
+			return err
+		}
+		t.created = append(t.created, created)
+	}
+
+	// Back up the file if it exists, so it can be restored
+	if err := t.backUp(filename); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, data, 0666)
+}
+
+// Guard records the state of files in a directory before a step that may change them, e.g.
+// the lockfile and the node_modules directory before installing the dependencies, so they
+// can be restored. Existing files are backed up. Missing files and directories are removed
+// on rollback. Within existing directories, the files and directories created after Guard
+// are removed on rollback, but the existing files aren't restored.
+func (t *Transaction) Guard(dir string, names ...string) error {
+	for _, name := range names {
+		filename := filepath.Join(dir, name)
+		stat, err := os.Lstat(filename)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			t.created = append(t.created, filename)
+		case err != nil:
+			return err
+		case stat.IsDir():
+			if _, ok := t.snapshots[filename]; ok {
+				continue
+			}
+			snapshot, err := listTree(filename)
+			if err != nil {
+				return err
+			}
+			t.snapshots[filename] = snapshot
+		default:
+			if err := t.backUp(filename); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// backUp saves the contents of a file the first time the transaction changes it. Files
+// that don't exist yet are removed on rollback instead.
+func (t *Transaction) backUp(filename string) error {
+	if _, ok := t.backups[filename]; ok {
+		return nil
+	}
+	previous, err := os.ReadFile(filename)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		t.created = append(t.created, filename)
+	case err != nil:
+		return err
+	default:
+		t.backups[filename] = previous
+	}
+	return nil
+}
+
+// listTree returns the paths of the files and directories in a directory, relative to it.
+func listTree(dir string) (map[string]bool, error) {
+	paths := make(map[string]bool)
+	err := filepath.WalkDir(dir, func(filename string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, filename)
+		if err != nil {
+			return err
+		}
+		paths[rel] = true
+		return nil
+	})
+	return paths, err
+}
+
+// Rollback undoes the changes of the transaction. If it created the project directory,
+// the directory is removed entirely.
+func (t *Transaction) Rollback() error {
+	var errs []error
+	for filename, data := range t.backups {
+		if err := os.WriteFile(filename, data, 0666); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for i := len(t.created) - 1; i >= 0; i-- {
+		if err := os.RemoveAll(t.created[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// Remove what was added to the guarded directories
+	for dir, snapshot := range t.snapshots {
+		err := filepath.WalkDir(dir, func(filename string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(dir, filename)
+			if err != nil {
+				return err
+			}
+			if snapshot[rel] {
+				return nil
+			}
+			if err := os.RemoveAll(filename); err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+
+	if t.createdDir != "" {
+		if err := os.RemoveAll(t.createdDir); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
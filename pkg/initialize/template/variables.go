@@ -0,0 +1,209 @@
+package template
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// VariableType is the type of the value of a template variable.
+type VariableType string
+
+const (
+	VariableString VariableType = "string"
+	VariableBool   VariableType = "bool"
+	VariableInt    VariableType = "int"
+	VariableChoice VariableType = "choice"
+)
+
+// Variable is a value asked to the user when installing a template, and available to the
+// template files as {{.Vars.<name>}}.
+type Variable struct {
+	// Name identifies the variable in the template files and on the command line.
+	Name string `json:"name"`
+	// Type is the type of the value. It defaults to "string".
+	Type VariableType `json:"type"`
+	// Prompt is the question asked to the user. It defaults to the name.
+	Prompt string `json:"prompt"`
+	// Default is the value used when the user doesn't give one. String defaults are
+	// templates themselves, so they can refer to the project name and the variables
+	// declared before them, like "com.example.{{.Name}}".
+	Default any `json:"default"`
+	// Choices are the allowed values of a "choice" variable.
+	Choices []string `json:"choices"`
+	// Pattern is a regular expression the value of a "string" variable must match.
+	Pattern string `json:"pattern"`
+	// Required rejects empty "string" values.
+	Required bool `json:"required"`
+}
+
+// Prompter asks the user for the values of template variables.
+type Prompter interface {
+	// Prompt asks for the value of a variable. An empty answer selects the default value.
+	// If the previous answer was invalid, invalid holds the reason.
+	Prompt(v *Variable, defaultValue string, invalid error) (string, error)
+}
+
+// maxPromptAttempts is the number of invalid answers to a prompt before giving up.
+const maxPromptAttempts = 5
+
+// ResolveVariables returns the values of the variables declared in the manifest. Values are
+// taken from the given values first, then asked to the prompter, and otherwise default to
+// the variable's default value. If the prompter is nil, it runs non-interactively.
+func (m *Manifest) ResolveVariables(
+	options *Options,
+	values map[string]string,
+	prompter Prompter,
+) (map[string]any, error) {
+	// Check that the given values are all declared
+	for name := range values {
+		if !slices.ContainsFunc(m.Variables, func(v Variable) bool { return v.Name == name }) {
+			return nil, fmt.Errorf("unknown template variable %q", name)
+		}
+	}
+
+	resolved := make(map[string]any, len(m.Variables))
+	for i := range m.Variables {
+		v := &m.Variables[i]
+
+		// Render the default value using the variables resolved so far
+		defaultValue, err := v.defaultValue(&Options{Name: options.Name, Vars: resolved})
+		if err != nil {
+			return nil, err
+		}
+
+		var value any
+		if raw, ok := values[v.Name]; ok {
+			// Use the value given by the user
+			if value, err = v.Parse(raw); err != nil {
+				return nil, err
+			}
+		} else if prompter != nil {
+			// Ask the user until the answer is valid
+			var invalid error
+			for attempt := 0; ; attempt++ {
+				if attempt == maxPromptAttempts {
+					return nil, invalid
+				}
+				answer, err := prompter.Prompt(v, defaultValue, invalid)
+				if err != nil {
+					return nil, err
+				}
+				if answer == "" {
+					answer = defaultValue
+				}
+				if value, invalid = v.Parse(answer); invalid == nil {
+					break
+				}
+			}
+		} else {
+			// Use the default value
+			if value, err = v.Parse(defaultValue); err != nil {
+				return nil, fmt.Errorf("%w, set it with --var %s=<value>", err, v.Name)
+			}
+		}
+		resolved[v.Name] = value
+	}
+	return resolved, nil
+}
+
+// defaultValue renders the default value of the variable as a string.
+func (v *Variable) defaultValue(options *Options) (string, error) {
+	switch def := v.Default.(type) {
+	case nil:
+		return "", nil
+	case string:
+		var sb strings.Builder
+		if err := copyAndModifyTemplateFile(&sb, strings.NewReader(def), options); err != nil {
+			return "", fmt.Errorf("rendering default value of template variable %q: %w", v.Name, err)
+		}
+		return sb.String(), nil
+	default:
+		return fmt.Sprint(def), nil
+	}
+}
+
+// Parse parses and validates a value of the variable.
+func (v *Variable) Parse(raw string) (any, error) {
+	switch v.Type {
+	case VariableString, "":
+		if v.Required && raw == "" {
+			return nil, fmt.Errorf("template variable %q is required", v.Name)
+		}
+		if v.Pattern != "" {
+			re, err := regexp.Compile(v.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern of template variable %q: %w", v.Name, err)
+			}
+			if !re.MatchString(raw) {
+				return nil, fmt.Errorf("template variable %q must match %s", v.Name, v.Pattern)
+			}
+		}
+		return raw, nil
+	case VariableBool:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("template variable %q must be true or false", v.Name)
+		}
+		return value, nil
+	case VariableInt:
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("template variable %q must be an integer", v.Name)
+		}
+		return value, nil
+	case VariableChoice:
+		if !slices.Contains(v.Choices, raw) {
+			return nil, fmt.Errorf("template variable %q must be one of: %s", v.Name, strings.Join(v.Choices, ", "))
+		}
+		return raw, nil
+	default:
+		return nil, fmt.Errorf("template variable %q has unknown type %q", v.Name, v.Type)
+	}
+}
+
+// linePrompter asks for variables one line at a time.
+type linePrompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// NewLinePrompter creates a prompter that writes questions to out and reads one answer per
+// line from in.
+func NewLinePrompter(in io.Reader, out io.Writer) Prompter {
+	return &linePrompter{in: bufio.NewReader(in), out: out}
+}
+
+func (p *linePrompter) Prompt(v *Variable, defaultValue string, invalid error) (string, error) {
+	if invalid != nil {
+		fmt.Fprintf(p.out, "Invalid value: %s\n", invalid)
+	}
+
+	// Write the question, with the choices and the default value
+	question := v.Prompt
+	if question == "" {
+		question = v.Name
+	}
+	switch v.Type {
+	case VariableChoice:
+		question += fmt.Sprintf(" (%s)", strings.Join(v.Choices, "/"))
+	case VariableBool:
+		question += " (true/false)"
+	}
+	if defaultValue != "" {
+		question += fmt.Sprintf(" [%s]", defaultValue)
+	}
+	fmt.Fprintf(p.out, "%s: ", question)
+
+	// Read the answer
+	answer, err := p.in.ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && answer != "") {
+		return "", fmt.Errorf("reading answer: %w", err)
+	}
+	return strings.TrimSpace(answer), nil
+}
@@ -0,0 +1,337 @@
+package initialize
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/customrealms/cli/pkg/diff"
+	"github.com/customrealms/cli/pkg/initialize/template"
+)
+
+// ChangeKind is the kind of change an upgrade makes to a project file.
+type ChangeKind string
+
+const (
+	// ChangeAdd adds a file the template didn't have before.
+	ChangeAdd ChangeKind = "add"
+	// ChangeUpdate replaces a file the user didn't change with the template's new version.
+	ChangeUpdate ChangeKind = "update"
+	// ChangeMerge merges the changes of the user and of the template.
+	ChangeMerge ChangeKind = "merge"
+	// ChangeConflict merges the changes of the user and of the template, with conflicts.
+	ChangeConflict ChangeKind = "conflict"
+	// ChangeDelete deletes a file the template doesn't have anymore.
+	ChangeDelete ChangeKind = "delete"
+	// ChangeSkip leaves a file the template changed as it is, because the user deleted or
+	// rewrote it. Skipped changes aren't written.
+	ChangeSkip ChangeKind = "skip"
+)
+
+// Change is a change an upgrade makes to a project file.
+type Change struct {
+	Kind ChangeKind
+	// Path is the slash-separated path of the file in the project directory.
+	Path string
+	// Current is the current contents of the file, or nil if it doesn't exist.
+	Current []byte
+	// Result is the contents after the upgrade, or nil if the file is deleted.
+	Result []byte
+	// Conflicts is the number of conflicts in a merged file.
+	Conflicts int
+	// Reason explains skipped changes.
+	Reason string
+}
+
+// Diff returns the unified diff of the change.
+func (c *Change) Diff() string {
+	return diff.Unified("a/"+c.Path, "b/"+c.Path, string(c.Current), string(c.Result))
+}
+
+// UpgradeAction applies the changes made to a template since a project was created from
+// it. It renders the template version the project was created from (the base) and the new
+// version with the same options, and merges the changes between them into the project
+// files.
+type UpgradeAction struct {
+	Dir string
+	// Source replaces the template source the project was created from, e.g. to move to a
+	// new tag. If it's empty, the recorded source is used.
+	Source string
+	// Vars are values of template variables, for variables added to the template since the
+	// project was created, or to change the values of existing ones.
+	Vars map[string]string
+	// Prompter asks for the values of variables added to the template. If it's nil, they
+	// use their default values.
+	Prompter template.Prompter
+}
+
+// Upgrade is a planned upgrade, which is written to the project with Apply.
+type Upgrade struct {
+	dir    string
+	record *template.Record
+	// base is the encoded template files of the new version, recorded as the base of the
+	// next upgrade.
+	base    []byte
+	Changes []Change
+}
+
+// Plan computes the changes of the upgrade, without writing anything.
+func (a *UpgradeAction) Plan(ctx context.Context) (*Upgrade, error) {
+	// Read the template the project was created from
+	record, err := template.ReadRecord(a.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, fmt.Errorf("project has no %s file, it wasn't created by crx init", template.RecordFilename)
+	}
+
+	// Read the template files the project was created from
+	baseFiles, err := a.baseFiles(ctx, record)
+	if err != nil {
+		return nil, err
+	}
+
+	// Render the new template version
+	source := a.Source
+	if source == "" {
+		source = record.Source
+	}
+	newTemplate, err := template.NewFromSource(ctx, source)
+	if err != nil {
+		return nil, fmt.Errorf("loading template: %w", err)
+	}
+	values := record.Values()
+	for name, value := range a.Vars {
+		values[name] = value
+	}
+	newOptions, err := renderOptions(newTemplate, record.Name, values, a.Prompter)
+	if err != nil {
+		return nil, err
+	}
+	newFiles, err := template.Render(newTemplate, newOptions)
+	if err != nil {
+		return nil, fmt.Errorf("rendering template: %w", err)
+	}
+
+	// Compute the change to each file
+	base, err := template.EncodeBase(newFiles)
+	if err != nil {
+		return nil, err
+	}
+	upgrade := &Upgrade{
+		dir:  a.Dir,
+		base: base,
+		record: &template.Record{
+			Source:  source,
+			Version: newTemplate.Version(),
+			Name:    record.Name,
+			Vars:    newOptions.Vars,
+		},
+	}
+	paths := make(map[string]bool)
+	for name := range baseFiles {
+		paths[name] = true
+	}
+	for name := range newFiles {
+		paths[name] = true
+	}
+	for name := range paths {
+		current, err := os.ReadFile(filepath.Join(a.Dir, filepath.FromSlash(name)))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if change := planChange(name, baseFiles[name], newFiles[name], current); change != nil {
+			upgrade.Changes = append(upgrade.Changes, *change)
+		}
+	}
+	sort.Slice(upgrade.Changes, func(i, j int) bool {
+		return upgrade.Changes[i].Path < upgrade.Changes[j].Path
+	})
+	return upgrade, nil
+}
+
+// baseFiles returns the template files the project was created from, or last upgraded to.
+// Projects created by older versions of the CLI don't record them, so the template is
+// rendered again at the version the project was created from, if it can be pinned.
+func (a *UpgradeAction) baseFiles(ctx context.Context, record *template.Record) (map[string][]byte, error) {
+	baseFiles, err := template.ReadBase(a.Dir)
+	if err != nil || baseFiles != nil {
+		return baseFiles, err
+	}
+	source, ok := record.PinnedSource()
+	if !ok {
+		return nil, fmt.Errorf("project has no %s file, and its template %s can't be pinned to the version it was created from", template.BaseFilename, record.Source)
+	}
+	baseTemplate, err := template.NewFromSource(ctx, source)
+	if err != nil {
+		return nil, fmt.Errorf("loading base template: %w", err)
+	}
+	baseOptions, err := renderOptions(baseTemplate, record.Name, record.Values(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("base template: %w", err)
+	}
+	baseFiles, err = template.Render(baseTemplate, baseOptions)
+	if err != nil {
+		return nil, fmt.Errorf("rendering base template: %w", err)
+	}
+	return baseFiles, nil
+}
+
+// renderOptions resolves the options to render a template with. Values of variables the
+// template doesn't declare anymore are dropped.
+func renderOptions(
+	tmpl template.Template,
+	name string,
+	values map[string]string,
+	prompter template.Prompter,
+) (*template.Options, error) {
+	manifest, err := template.ReadManifest(tmpl)
+	if err != nil {
+		return nil, err
+	}
+	declared := make(map[string]string)
+	for _, v := range manifest.Variables {
+		if value, ok := values[v.Name]; ok {
+			declared[v.Name] = value
+		}
+	}
+	options := &template.Options{Name: name}
+	if options.Vars, err = manifest.ResolveVariables(options, declared, prompter); err != nil {
+		return nil, err
+	}
+	return options, nil
+}
+
+// planChange computes the change to a file, given its base and new versions from the
+// template and its current contents. Nil contents mean the file doesn't exist. If the file
+// doesn't change, it returns nil.
+func planChange(name string, base, next, current []byte) *Change {
+	change := &Change{Path: name, Current: current}
+	switch {
+	case bytes.Equal(base, next) && (base == nil) == (next == nil):
+		// The template didn't change the file
+		return nil
+
+	case next == nil:
+		// The template removed the file. Keep it if the user changed it.
+		if current == nil {
+			return nil
+		}
+		if !bytes.Equal(current, base) {
+			change.Kind, change.Result = ChangeSkip, current
+			change.Reason = "removed from the template, but changed in the project"
+			return change
+		}
+		change.Kind = ChangeDelete
+		return change
+
+	case current == nil && base != nil:
+		// The user deleted the file
+		change.Kind = ChangeSkip
+		change.Reason = "changed in the template, but deleted in the project"
+		return change
+
+	case current == nil:
+		change.Kind, change.Result = ChangeAdd, next
+		return change
+
+	case bytes.Equal(current, next):
+		// The project already has the new version
+		return nil
+
+	case bytes.Equal(current, base):
+		change.Kind, change.Result = ChangeUpdate, next
+		return change
+
+	case isBinary(base) || isBinary(next) || isBinary(current):
+		change.Kind, change.Result = ChangeSkip, current
+		change.Reason = "binary file changed in both the template and the project"
+		return change
+
+	default:
+		merged, conflicts := diff.Merge3(string(base), string(current), string(next))
+		change.Result, change.Conflicts = []byte(merged), conflicts
+		change.Kind = ChangeMerge
+		if conflicts > 0 {
+			change.Kind = ChangeConflict
+		}
+		return change
+	}
+}
+
+// isBinary reports whether file contents look binary rather than text.
+func isBinary(data []byte) bool {
+	return bytes.IndexByte(data, 0) >= 0
+}
+
+// Conflicts returns the number of files with conflicts.
+func (u *Upgrade) Conflicts() int {
+	count := 0
+	for _, change := range u.Changes {
+		if change.Kind == ChangeConflict {
+			count++
+		}
+	}
+	return count
+}
+
+// Print writes a summary of the changes, with the diff of each file.
+func (u *Upgrade) Print(w io.Writer, diffs bool) {
+	for _, change := range u.Changes {
+		switch change.Kind {
+		case ChangeSkip:
+			fmt.Fprintf(w, "%-9s %s (%s)\n", change.Kind, change.Path, change.Reason)
+		case ChangeConflict:
+			fmt.Fprintf(w, "%-9s %s (%d conflicts)\n", change.Kind, change.Path, change.Conflicts)
+		default:
+			fmt.Fprintf(w, "%-9s %s\n", change.Kind, change.Path)
+		}
+	}
+	if !diffs {
+		return
+	}
+	for _, change := range u.Changes {
+		if change.Kind == ChangeSkip {
+			continue
+		}
+		fmt.Fprintln(w)
+		fmt.Fprint(w, change.Diff())
+	}
+}
+
+// Apply writes the changes to the project, and records the new template version and its
+// files.
+func (u *Upgrade) Apply() error {
+	for _, change := range u.Changes {
+		filename := filepath.Join(u.dir, filepath.FromSlash(change.Path))
+		switch change.Kind {
+		case ChangeSkip:
+			continue
+		case ChangeDelete:
+			if err := os.Remove(filename); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		default:
+			if err := os.MkdirAll(filepath.Dir(filename), 0777); err != nil {
+				return err
+			}
+			if err := os.WriteFile(filename, change.Result, 0666); err != nil {
+				return err
+			}
+		}
+	}
+	baseFilename := filepath.Join(u.dir, filepath.FromSlash(template.BaseFilename))
+	if err := os.MkdirAll(filepath.Dir(baseFilename), 0777); err != nil {
+		return err
+	}
+	if err := os.WriteFile(baseFilename, u.base, 0666); err != nil {
+		return err
+	}
+	return u.record.Write(u.dir)
+}
@@ -0,0 +1,119 @@
+package initialize_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/customrealms/cli/pkg/initialize"
+	"github.com/customrealms/cli/pkg/initialize/template"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, contents := range files {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(filename), 0777))
+		require.NoError(t, os.WriteFile(filename, []byte(contents), 0666))
+	}
+}
+
+func readTestFile(t *testing.T, dir, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestUpgrade(t *testing.T) {
+	oldDir, newDir, projectDir := t.TempDir(), t.TempDir(), t.TempDir()
+	writeTestFiles(t, oldDir, map[string]string{
+		"manifest.json": `{"files": {"package.json": true, "tsconfig.json": false, "src/main.ts": false, "old.txt": false, "notes.txt": false}}`,
+		"package.json":  "{\n  \"name\": \"{{.Name}}\",\n  \"version\": \"1.0.0\",\n  \"scripts\": {\n    \"build\": \"crx build\"\n  }\n}\n",
+		"tsconfig.json": "{\n  \"strict\": false\n}\n",
+		"src/main.ts":   "console.log(\"hi\")\n",
+		"old.txt":       "old\n",
+		"notes.txt":     "notes\n",
+	})
+	writeTestFiles(t, newDir, map[string]string{
+		"manifest.json": `{"files": {"package.json": true, "tsconfig.json": false, "src/main.ts": false, "new.txt": false}, "variables": [{"name": "author", "default": "Steve"}]}`,
+		"package.json":  "{\n  \"name\": \"{{.Name}}\",\n  \"version\": \"1.0.0\",\n  \"scripts\": {\n    \"build\": \"crx build\",\n    \"dev\": \"crx run\"\n  },\n  \"author\": \"{{.Vars.author}}\"\n}\n",
+		"tsconfig.json": "{\n  \"strict\": true\n}\n",
+		"src/main.ts":   "console.log(\"hello\")\n",
+		"new.txt":       "new\n",
+	})
+
+	// Create the project from the old template, and change some of its files
+	oldTemplate, err := template.NewFromSource(context.Background(), oldDir)
+	require.NoError(t, err)
+	require.NoError(t, template.Install(oldTemplate, projectDir, &template.Options{Name: "my-plugin"}))
+	require.NoError(t, (&template.Record{Source: oldDir, Name: "my-plugin"}).Write(projectDir))
+	baseFiles, err := template.Render(oldTemplate, &template.Options{Name: "my-plugin"})
+	require.NoError(t, err)
+	base, err := template.EncodeBase(baseFiles)
+	require.NoError(t, err)
+	writeTestFiles(t, projectDir, map[string]string{template.BaseFilename: string(base)})
+	writeTestFiles(t, projectDir, map[string]string{
+		"package.json": "{\n  \"name\": \"my-plugin\",\n  \"version\": \"2.0.0\",\n  \"scripts\": {\n    \"build\": \"crx build\"\n  }\n}\n",
+		"src/main.ts":  "console.log(\"mine\")\n",
+		"notes.txt":    "my notes\n",
+	})
+
+	action := initialize.UpgradeAction{Dir: projectDir, Source: newDir}
+	upgrade, err := action.Plan(context.Background())
+	require.NoError(t, err)
+
+	kinds := make(map[string]initialize.ChangeKind)
+	for _, change := range upgrade.Changes {
+		kinds[change.Path] = change.Kind
+	}
+	require.Equal(t, map[string]initialize.ChangeKind{
+		"new.txt":       initialize.ChangeAdd,
+		"notes.txt":     initialize.ChangeSkip,
+		"old.txt":       initialize.ChangeDelete,
+		"package.json":  initialize.ChangeMerge,
+		"src/main.ts":   initialize.ChangeConflict,
+		"tsconfig.json": initialize.ChangeUpdate,
+	}, kinds)
+	require.Equal(t, 1, upgrade.Conflicts())
+
+	require.NoError(t, upgrade.Apply())
+	require.Equal(t, "{\n  \"name\": \"my-plugin\",\n  \"version\": \"2.0.0\",\n  \"scripts\": {\n    \"build\": \"crx build\",\n    \"dev\": \"crx run\"\n  },\n  \"author\": \"Steve\"\n}\n", readTestFile(t, projectDir, "package.json"))
+	require.Equal(t, "{\n  \"strict\": true\n}\n", readTestFile(t, projectDir, "tsconfig.json"))
+	require.Equal(t, "my notes\n", readTestFile(t, projectDir, "notes.txt"))
+	require.Equal(t, "new\n", readTestFile(t, projectDir, "new.txt"))
+	require.Contains(t, readTestFile(t, projectDir, "src/main.ts"), "<<<<<<< current")
+	require.NoFileExists(t, filepath.Join(projectDir, "old.txt"))
+
+	record, err := template.ReadRecord(projectDir)
+	require.NoError(t, err)
+	require.Equal(t, newDir, record.Source)
+	require.Equal(t, map[string]any{"author": "Steve"}, record.Vars)
+
+	// The next upgrade merges against the recorded files, so changes to a local template
+	// are found even though it isn't versioned
+	writeTestFiles(t, newDir, map[string]string{"new.txt": "newer\n"})
+	upgrade, err = (&initialize.UpgradeAction{Dir: projectDir}).Plan(context.Background())
+	require.NoError(t, err)
+	require.Len(t, upgrade.Changes, 1)
+	require.Equal(t, "new.txt", upgrade.Changes[0].Path)
+	require.Equal(t, initialize.ChangeUpdate, upgrade.Changes[0].Kind)
+
+	// Without the recorded files, a template that can't be pinned can't be upgraded
+	require.NoError(t, os.Remove(filepath.Join(projectDir, filepath.FromSlash(template.BaseFilename))))
+	_, err = (&initialize.UpgradeAction{Dir: projectDir}).Plan(context.Background())
+	require.ErrorContains(t, err, "can't be pinned")
+}
+
+func TestRecordPinnedSource(t *testing.T) {
+	record := template.Record{Source: "github:my-org/my-template#main", Version: "0123456789abcdef0123456789abcdef01234567"}
+	source, ok := record.PinnedSource()
+	require.True(t, ok)
+	require.Equal(t, "github:my-org/my-template#0123456789abcdef0123456789abcdef01234567", source)
+
+	record = template.Record{Source: "../my-template", Version: "1.0.0"}
+	_, ok = record.PinnedSource()
+	require.False(t, ok)
+}
@@ -0,0 +1,176 @@
+package jarsign_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/customrealms/cli/pkg/jarsign"
+	"github.com/customrealms/cli/pkg/manifest"
+	"github.com/stretchr/testify/require"
+)
+
+func testSigner(t *testing.T, key crypto.Signer) *jarsign.Signer {
+	t.Helper()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "Test Signer"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &jarsign.Signer{
+		Name:  "TEST",
+		Key:   key,
+		Chain: []*x509.Certificate{cert},
+	}
+}
+
+// signedJar creates a signed JAR file with the given files. The files are modified after
+// signing by the tamper function, if it's not nil.
+func signedJar(t *testing.T, signer *jarsign.Signer, files map[string]string, tamper func(map[string][]byte)) *zip.Reader {
+	t.Helper()
+	m := manifest.New()
+	contents := make(map[string][]byte)
+	for name, data := range files {
+		jarsign.AddDigest(m, name, []byte(data))
+		contents[name] = []byte(data)
+	}
+	contents[manifest.Filename] = m.Bytes()
+	signatureFiles, err := signer.Sign(m, "test")
+	require.NoError(t, err)
+	for _, file := range signatureFiles {
+		contents[file.Name] = file.Data
+	}
+	if tamper != nil {
+		tamper(contents)
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range contents {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	return zr
+}
+
+func TestSignAndVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	files := map[string]string{
+		"plugin.js":  "console.log('hello');",
+		"plugin.yml": "name: Test\n",
+	}
+
+	for name, key := range map[string]crypto.Signer{"rsa": rsaKey, "ecdsa": ecKey} {
+		t.Run(name, func(t *testing.T) {
+			signer := testSigner(t, key)
+
+			signatures, err := jarsign.Verify(signedJar(t, signer, files, nil))
+			require.NoError(t, err)
+			require.Len(t, signatures, 1)
+			require.Equal(t, "TEST", signatures[0].Name)
+			require.True(t, signatures[0].Chain[0].Equal(signer.Chain[0]))
+		})
+	}
+
+	signer := testSigner(t, rsaKey)
+
+	t.Run("modified entry", func(t *testing.T) {
+		_, err := jarsign.Verify(signedJar(t, signer, files, func(contents map[string][]byte) {
+			contents["plugin.js"] = []byte("console.log('evil');")
+		}))
+		require.ErrorContains(t, err, "plugin.js has been modified")
+	})
+
+	t.Run("added entry", func(t *testing.T) {
+		_, err := jarsign.Verify(signedJar(t, signer, files, func(contents map[string][]byte) {
+			contents["extra.js"] = []byte("console.log('evil');")
+		}))
+		require.ErrorContains(t, err, "extra.js is not covered")
+	})
+
+	t.Run("modified signature file", func(t *testing.T) {
+		_, err := jarsign.Verify(signedJar(t, signer, files, func(contents map[string][]byte) {
+			contents["META-INF/TEST.SF"] = append(contents["META-INF/TEST.SF"], "X-Extra: 1\r\n"...)
+		}))
+		require.ErrorContains(t, err, "invalid signature")
+	})
+
+	t.Run("modified main attributes", func(t *testing.T) {
+		_, err := jarsign.Verify(signedJar(t, signer, files, func(contents map[string][]byte) {
+			m, err := manifest.Parse(contents[manifest.Filename])
+			require.NoError(t, err)
+			m.Main.Set("Class-Path", "evil.jar")
+			contents[manifest.Filename] = m.Bytes()
+		}))
+		require.ErrorContains(t, err, "main attributes of the manifest have been modified")
+	})
+
+	t.Run("not signed", func(t *testing.T) {
+		_, err := jarsign.Verify(signedJar(t, signer, files, func(contents map[string][]byte) {
+			delete(contents, "META-INF/TEST.SF")
+			delete(contents, "META-INF/TEST.RSA")
+		}))
+		require.ErrorIs(t, err, jarsign.ErrNotSigned)
+	})
+}
+
+func TestIsSignatureFile(t *testing.T) {
+	require.True(t, jarsign.IsSignatureFile("META-INF/CRX.SF"))
+	require.True(t, jarsign.IsSignatureFile("META-INF/crx.rsa"))
+	require.True(t, jarsign.IsSignatureFile("META-INF/CRX.EC"))
+	require.True(t, jarsign.IsSignatureFile("META-INF/SIG-CRX"))
+	require.False(t, jarsign.IsSignatureFile("META-INF/MANIFEST.MF"))
+	require.False(t, jarsign.IsSignatureFile("META-INF/services/CRX.SF"))
+	require.False(t, jarsign.IsSignatureFile("CRX.SF"))
+}
+
+func TestIsSignatureBlockFile(t *testing.T) {
+	require.True(t, jarsign.IsSignatureBlockFile("META-INF/CRX.RSA"))
+	require.True(t, jarsign.IsSignatureBlockFile("META-INF/CRX.EC"))
+	require.False(t, jarsign.IsSignatureBlockFile("META-INF/CRX.SF"))
+	require.False(t, jarsign.IsSignatureBlockFile("plugin.js"))
+}
+
+func TestDeterministic(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	// Signing twice gives the same signature files only with RSA keys
+	for _, key := range []crypto.Signer{rsaKey, ecKey} {
+		signer := testSigner(t, key)
+		m := manifest.New()
+		jarsign.AddDigest(m, "plugin.js", []byte("console.log('hello');"))
+		first, err := signer.Sign(m, "test")
+		require.NoError(t, err)
+		second, err := signer.Sign(m, "test")
+		require.NoError(t, err)
+		require.Equal(t, signer.Deterministic(), first[1].Data != nil && bytes.Equal(first[1].Data, second[1].Data))
+	}
+	require.True(t, testSigner(t, rsaKey).Deterministic())
+	require.False(t, testSigner(t, ecKey).Deterministic())
+}
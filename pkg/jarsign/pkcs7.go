@@ -0,0 +1,240 @@
+package jarsign
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// This file implements the subset of PKCS#7 (RFC 2315) used by signed JAR files: a detached
+// SignedData structure holding the signer's certificate chain and a signature over the
+// signature file (.SF).
+
+var (
+	oidData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidSHA256        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidRSAEncryption = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidSHA256WithRSA = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	oidECDSAWithSHA  = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+)
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	ContentInfo      contentInfo
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	SignerInfos      []signerInfo  `asn1:"set"`
+}
+
+type issuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type signerInfo struct {
+	Version                   int
+	IssuerAndSerialNumber     issuerAndSerialNumber
+	DigestAlgorithm           pkix.AlgorithmIdentifier
+	AuthenticatedAttributes   asn1.RawValue `asn1:"optional,tag:0"`
+	DigestEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedDigest           []byte
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue `asn1:"set"`
+}
+
+// signPKCS7 creates a detached PKCS#7 SignedData structure signing the content.
+func signPKCS7(content []byte, key crypto.Signer, chain []*x509.Certificate) ([]byte, error) {
+	// Sign the SHA-256 digest of the content
+	digest := sha256.Sum256(content)
+	signature, err := key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("signing: %w", err)
+	}
+
+	// Determine the signature algorithm for the key type
+	var encryptionAlgorithm pkix.AlgorithmIdentifier
+	switch key.Public().(type) {
+	case *rsa.PublicKey:
+		encryptionAlgorithm = pkix.AlgorithmIdentifier{Algorithm: oidRSAEncryption, Parameters: asn1.NullRawValue}
+	case *ecdsa.PublicKey:
+		encryptionAlgorithm = pkix.AlgorithmIdentifier{Algorithm: oidECDSAWithSHA}
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", key.Public())
+	}
+
+	// Concatenate the certificates into the implicitly tagged set
+	var certs []byte
+	for _, cert := range chain {
+		certs = append(certs, cert.Raw...)
+	}
+
+	digestAlgorithm := pkix.AlgorithmIdentifier{Algorithm: oidSHA256, Parameters: asn1.NullRawValue}
+	sd := signedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{digestAlgorithm},
+		ContentInfo:      contentInfo{ContentType: oidData},
+		Certificates: asn1.RawValue{
+			Class:      asn1.ClassContextSpecific,
+			Tag:        0,
+			IsCompound: true,
+			Bytes:      certs,
+		},
+		SignerInfos: []signerInfo{{
+			Version: 1,
+			IssuerAndSerialNumber: issuerAndSerialNumber{
+				Issuer:       asn1.RawValue{FullBytes: chain[0].RawIssuer},
+				SerialNumber: chain[0].SerialNumber,
+			},
+			DigestAlgorithm:           digestAlgorithm,
+			DigestEncryptionAlgorithm: encryptionAlgorithm,
+			EncryptedDigest:           signature,
+		}},
+	}
+	sdBytes, err := asn1.Marshal(sd)
+	if err != nil {
+		return nil, err
+	}
+	// The struct tags are ignored when marshaling raw values, so the explicit tag is
+	// written by hand
+	return asn1.Marshal(contentInfo{
+		ContentType: oidSignedData,
+		Content: asn1.RawValue{
+			Class:      asn1.ClassContextSpecific,
+			Tag:        0,
+			IsCompound: true,
+			Bytes:      sdBytes,
+		},
+	})
+}
+
+// verifyPKCS7 verifies a detached PKCS#7 SignedData structure against the content, and
+// returns the certificate chain of the signer.
+func verifyPKCS7(data, content []byte) ([]*x509.Certificate, error) {
+	// Parse the outer content info
+	var ci contentInfo
+	if rest, err := asn1.Unmarshal(data, &ci); err != nil {
+		return nil, fmt.Errorf("parsing PKCS#7: %w", err)
+	} else if len(rest) > 0 {
+		return nil, errors.New("parsing PKCS#7: trailing data")
+	}
+	if !ci.ContentType.Equal(oidSignedData) {
+		return nil, fmt.Errorf("parsing PKCS#7: unsupported content type %s", ci.ContentType)
+	}
+
+	// Parse the signed data
+	var sd signedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return nil, fmt.Errorf("parsing PKCS#7 signed data: %w", err)
+	}
+	certs, err := x509.ParseCertificates(sd.Certificates.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing PKCS#7 certificates: %w", err)
+	}
+	if len(sd.SignerInfos) != 1 {
+		return nil, fmt.Errorf("expected exactly one signer, found %d", len(sd.SignerInfos))
+	}
+	si := sd.SignerInfos[0]
+	if !si.DigestAlgorithm.Algorithm.Equal(oidSHA256) {
+		return nil, fmt.Errorf("unsupported digest algorithm %s", si.DigestAlgorithm.Algorithm)
+	}
+
+	// Find the signer's certificate
+	var signerCert *x509.Certificate
+	for _, cert := range certs {
+		if bytes.Equal(cert.RawIssuer, si.IssuerAndSerialNumber.Issuer.FullBytes) &&
+			cert.SerialNumber.Cmp(si.IssuerAndSerialNumber.SerialNumber) == 0 {
+			signerCert = cert
+			break
+		}
+	}
+	if signerCert == nil {
+		return nil, errors.New("signer certificate not found")
+	}
+
+	// If there are authenticated attributes, they are signed instead of the content, and
+	// hold the digest of the content
+	signed := content
+	if len(si.AuthenticatedAttributes.Bytes) > 0 {
+		if err := checkMessageDigest(si.AuthenticatedAttributes.Bytes, content); err != nil {
+			return nil, err
+		}
+		// The attributes are signed with the SET OF tag instead of the implicit [0] tag
+		signed, err = asn1.Marshal(asn1.RawValue{
+			Class:      asn1.ClassUniversal,
+			Tag:        asn1.TagSet,
+			IsCompound: true,
+			Bytes:      si.AuthenticatedAttributes.Bytes,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Verify the signature
+	var algorithm x509.SignatureAlgorithm
+	switch {
+	case si.DigestEncryptionAlgorithm.Algorithm.Equal(oidRSAEncryption),
+		si.DigestEncryptionAlgorithm.Algorithm.Equal(oidSHA256WithRSA):
+		algorithm = x509.SHA256WithRSA
+	case si.DigestEncryptionAlgorithm.Algorithm.Equal(oidECDSAWithSHA):
+		algorithm = x509.ECDSAWithSHA256
+	default:
+		return nil, fmt.Errorf("unsupported signature algorithm %s", si.DigestEncryptionAlgorithm.Algorithm)
+	}
+	if err := signerCert.CheckSignature(algorithm, signed, si.EncryptedDigest); err != nil {
+		return nil, fmt.Errorf("invalid signature: %w", err)
+	}
+
+	// Return the signer's certificate first, followed by the rest
+	chain := []*x509.Certificate{signerCert}
+	for _, cert := range certs {
+		if cert != signerCert {
+			chain = append(chain, cert)
+		}
+	}
+	return chain, nil
+}
+
+// checkMessageDigest checks the message digest in a set of authenticated attributes against
+// the SHA-256 digest of the content.
+func checkMessageDigest(attrs, content []byte) error {
+	for len(attrs) > 0 {
+		var attr attribute
+		var err error
+		if attrs, err = asn1.Unmarshal(attrs, &attr); err != nil {
+			return fmt.Errorf("parsing authenticated attributes: %w", err)
+		}
+		if !attr.Type.Equal(oidMessageDigest) {
+			continue
+		}
+		var digest []byte
+		if _, err := asn1.Unmarshal(attr.Values.Bytes, &digest); err != nil {
+			return fmt.Errorf("parsing message digest: %w", err)
+		}
+		expected := sha256.Sum256(content)
+		if !bytes.Equal(digest, expected[:]) {
+			return errors.New("message digest doesn't match the signature file")
+		}
+		return nil
+	}
+	return errors.New("authenticated attributes are missing the message digest")
+}
@@ -0,0 +1,106 @@
+package jarsign
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"path"
+	"strings"
+
+	"github.com/customrealms/cli/pkg/manifest"
+)
+
+// DigestAttribute is the manifest attribute holding the digest of a JAR entry.
+const DigestAttribute = "SHA-256-Digest"
+
+// File is a file to be added to the JAR file.
+type File struct {
+	Name string
+	Data []byte
+}
+
+// IsSignatureFile returns true if the JAR entry is part of a JAR signature. Signature files
+// are stored directly in META-INF, and aren't covered by the manifest digests.
+func IsSignatureFile(name string) bool {
+	upper := strings.ToUpper(name)
+	dir, base := path.Split(upper)
+	if dir != "META-INF/" {
+		return false
+	}
+	switch path.Ext(base) {
+	case ".SF", ".RSA", ".DSA", ".EC":
+		return true
+	}
+	return strings.HasPrefix(base, "SIG-")
+}
+
+// AddDigest records the digest of a JAR entry in the manifest, in the section for the entry.
+func AddDigest(m *manifest.Manifest, name string, data []byte) {
+	digest := sha256Base64(data)
+	for i := range m.Sections {
+		if m.Sections[i].Name == name {
+			m.Sections[i].Attributes.Set(DigestAttribute, digest)
+			return
+		}
+	}
+	m.Sections = append(m.Sections, manifest.Section{
+		Name:       name,
+		Attributes: manifest.Attributes{{Name: DigestAttribute, Value: digest}},
+	})
+}
+
+// Sign creates the signature file (.SF) and signature block file (.RSA or .EC) for a
+// manifest. The manifest must already hold the digests of every entry, and must be written
+// to the JAR exactly as it's encoded by manifest.Bytes.
+func (s *Signer) Sign(m *manifest.Manifest, createdBy string) ([]File, error) {
+	// Create the signature file, which holds the digests of the manifest and its sections
+	sf := manifest.Manifest{
+		Main: manifest.Attributes{
+			{Name: "Signature-Version", Value: "1.0"},
+			{Name: "Created-By", Value: createdBy},
+			{Name: "SHA-256-Digest-Manifest", Value: sha256Base64(m.Bytes())},
+			{Name: "SHA-256-Digest-Manifest-Main-Attributes", Value: sha256Base64(m.MainBytes())},
+		},
+	}
+	for _, section := range m.Sections {
+		sf.Sections = append(sf.Sections, manifest.Section{
+			Name:       section.Name,
+			Attributes: manifest.Attributes{{Name: DigestAttribute, Value: sha256Base64(section.Bytes())}},
+		})
+	}
+	sfData := sf.Bytes()
+
+	// Sign the signature file
+	block, err := signPKCS7(sfData, s.Key, s.Chain)
+	if err != nil {
+		return nil, err
+	}
+	blockExt := ".RSA"
+	if _, ok := s.Key.Public().(*ecdsa.PublicKey); ok {
+		blockExt = ".EC"
+	}
+
+	return []File{
+		{Name: "META-INF/" + s.Name + ".SF", Data: sfData},
+		{Name: "META-INF/" + s.Name + blockExt, Data: block},
+	}, nil
+}
+
+// Deterministic reports whether signing the same manifest twice creates the same signature
+// files. RSA signatures are deterministic, while ECDSA signatures use a random nonce.
+func (s *Signer) Deterministic() bool {
+	_, ok := s.Key.Public().(*rsa.PublicKey)
+	return ok
+}
+
+// IsSignatureBlockFile returns true if a JAR entry is a signature block file, which holds
+// the signature itself, like "META-INF/CRX.RSA" or "META-INF/CRX.EC".
+func IsSignatureBlockFile(name string) bool {
+	return IsSignatureFile(name) && !strings.HasSuffix(strings.ToUpper(name), ".SF")
+}
+
+func sha256Base64(data []byte) string {
+	sum := sha256.Sum256(data)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,172 @@
+package jarsign
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// DefaultName is the default base name of the signature files in META-INF.
+const DefaultName = "CRX"
+
+// Signer holds the key and certificate chain used to sign JAR files.
+type Signer struct {
+	// Name is the base name of the signature files, e.g. "CRX" for "META-INF/CRX.SF".
+	Name string
+	// Key is the private key used to sign.
+	Key crypto.Signer
+	// Chain is the certificate chain for the key, starting with the signing certificate.
+	Chain []*x509.Certificate
+}
+
+// signatureNameRegexp matches valid signature file base names, as accepted by jarsigner.
+var signatureNameRegexp = regexp.MustCompile(`^[A-Z0-9_-]{1,8}$`)
+
+// LoadSigner loads a signing key and its certificate chain. The key file is either a PKCS#12
+// keystore (as created by "keytool -storetype pkcs12") protected by the password, or a PEM
+// file. PEM files may contain the certificate chain after the key, otherwise it's read from
+// the certificate file.
+func LoadSigner(name, keyFile, certFile, password string) (*Signer, error) {
+	// Validate the signature file name
+	if name == "" {
+		name = DefaultName
+	}
+	name = strings.ToUpper(name)
+	if !signatureNameRegexp.MatchString(name) {
+		return nil, fmt.Errorf("invalid signature name %q: must be 1-8 characters of A-Z, 0-9, '_' or '-'", name)
+	}
+
+	// Read the key file
+	keyData, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading key file: %w", err)
+	}
+
+	// Load the key and any certificates in the key file
+	var key any
+	var chain []*x509.Certificate
+	if isPEM(keyData) {
+		key, chain, err = parsePEM(keyData)
+	} else {
+		key, chain, err = parsePKCS12(keyData, password)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Load the certificate chain from the separate certificate file
+	if certFile != "" {
+		certData, err := os.ReadFile(certFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading certificate file: %w", err)
+		}
+		chain, err = ParseCertificates(certData)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(chain) == 0 {
+		return nil, errors.New("no certificate found for the signing key")
+	}
+
+	// Make sure the key is supported, and matches the certificate
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+	switch pub := signer.Public().(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		if !pub.(interface{ Equal(crypto.PublicKey) bool }).Equal(chain[0].PublicKey) {
+			return nil, errors.New("signing key doesn't match the certificate")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported key type %T: only RSA and ECDSA keys are supported", pub)
+	}
+
+	return &Signer{
+		Name:  name,
+		Key:   signer,
+		Chain: chain,
+	}, nil
+}
+
+// ParseCertificates parses the certificates in a PEM file.
+func ParseCertificates(data []byte) ([]*x509.Certificate, error) {
+	_, certs, err := parsePEM(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no certificates found")
+	}
+	return certs, nil
+}
+
+func isPEM(data []byte) bool {
+	block, _ := pem.Decode(data)
+	return block != nil
+}
+
+// parsePEM parses the private key and certificates in a PEM file.
+func parsePEM(data []byte) (any, []*x509.Certificate, error) {
+	var key any
+	var chain []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		switch block.Type {
+		case "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, nil, fmt.Errorf("parsing certificate: %w", err)
+			}
+			chain = append(chain, cert)
+		case "PRIVATE KEY", "RSA PRIVATE KEY", "EC PRIVATE KEY":
+			parsed, err := parsePrivateKey(block.Bytes)
+			if err != nil {
+				return nil, nil, err
+			}
+			key = parsed
+		case "ENCRYPTED PRIVATE KEY":
+			return nil, nil, errors.New("encrypted PEM keys are not supported, use a PKCS#12 keystore instead")
+		}
+	}
+	return key, chain, nil
+}
+
+func parsePrivateKey(der []byte) (any, error) {
+	if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key, nil
+	}
+	return nil, errors.New("parsing private key: unsupported format")
+}
+
+// parsePKCS12 parses the private key and certificate chain in a PKCS#12 keystore.
+func parsePKCS12(data []byte, password string) (any, []*x509.Certificate, error) {
+	key, cert, caCerts, err := pkcs12.DecodeChain(data, password)
+	if err != nil {
+		if errors.Is(err, pkcs12.ErrIncorrectPassword) {
+			return nil, nil, errors.New("incorrect keystore password")
+		}
+		return nil, nil, fmt.Errorf("reading PKCS#12 keystore (JKS keystores must be converted with 'keytool -importkeystore -deststoretype pkcs12'): %w", err)
+	}
+	return key, append([]*x509.Certificate{cert}, caCerts...), nil
+}
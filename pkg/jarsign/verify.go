@@ -0,0 +1,162 @@
+package jarsign
+
+import (
+	"archive/zip"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/customrealms/cli/pkg/manifest"
+)
+
+// ErrNotSigned is returned when verifying a JAR file without any signatures.
+var ErrNotSigned = errors.New("JAR file is not signed")
+
+// Signature is a verified signature of a JAR file.
+type Signature struct {
+	// Name is the base name of the signature files, e.g. "CRX" for "META-INF/CRX.SF".
+	Name string
+	// Chain is the certificate chain of the signer, starting with the signing certificate.
+	Chain []*x509.Certificate
+}
+
+// Verify checks the signatures of a JAR file, and that every entry matches the digests in the
+// signed manifest. It returns ErrNotSigned if the JAR file doesn't have any signatures.
+func Verify(zr *zip.Reader) ([]Signature, error) {
+	// Read all the entries of the JAR file
+	files := make(map[string][]byte)
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		data, err := readFile(f)
+		if err != nil {
+			return nil, err
+		}
+		files[f.Name] = data
+	}
+
+	// Parse the manifest
+	manifestData, ok := files[manifest.Filename]
+	if !ok {
+		return nil, ErrNotSigned
+	}
+	m, err := manifest.Parse(manifestData)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", manifest.Filename, err)
+	}
+
+	// Verify each of the signatures
+	var signatures []Signature
+	for name, data := range files {
+		if !IsSignatureFile(name) || path.Ext(name) != ".SF" {
+			continue
+		}
+		signature, err := verifySignature(files, m, manifestData, name, data)
+		if err != nil {
+			return nil, fmt.Errorf("verifying %s: %w", name, err)
+		}
+		signatures = append(signatures, *signature)
+	}
+	if len(signatures) == 0 {
+		return nil, ErrNotSigned
+	}
+	sort.Slice(signatures, func(i, j int) bool {
+		return signatures[i].Name < signatures[j].Name
+	})
+
+	// Check every entry against its digest in the manifest
+	for name, data := range files {
+		if name == manifest.Filename || IsSignatureFile(name) {
+			continue
+		}
+		section := findSection(m, name)
+		if section == nil {
+			return nil, fmt.Errorf("%s is not covered by the signature", name)
+		}
+		digest, ok := section.Attributes.Get(DigestAttribute)
+		if !ok {
+			return nil, fmt.Errorf("%s has no %s in the manifest", name, DigestAttribute)
+		}
+		if digest != sha256Base64(data) {
+			return nil, fmt.Errorf("%s has been modified since it was signed", name)
+		}
+	}
+	return signatures, nil
+}
+
+// verifySignature verifies a single signature file and its signature block.
+func verifySignature(files map[string][]byte, m *manifest.Manifest, manifestData []byte, sfName string, sfData []byte) (*Signature, error) {
+	// Find the signature block for the signature file
+	base := strings.TrimSuffix(sfName, path.Ext(sfName))
+	var block []byte
+	for _, ext := range []string{".RSA", ".EC", ".DSA"} {
+		if data, ok := files[base+ext]; ok {
+			block = data
+			break
+		}
+	}
+	if block == nil {
+		return nil, errors.New("signature block file is missing")
+	}
+
+	// Verify the signature over the signature file
+	chain, err := verifyPKCS7(block, sfData)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the digests of the manifest in the signature file. If the digest of the whole
+	// manifest matches, then all of its sections are covered. Otherwise, the main attributes
+	// and each section of the manifest must match their digests in the signature file.
+	sf, err := manifest.Parse(sfData)
+	if err != nil {
+		return nil, fmt.Errorf("parsing signature file: %w", err)
+	}
+	if digest, _ := sf.Main.Get("SHA-256-Digest-Manifest"); digest != sha256Base64(manifestData) {
+		if digest, _ := sf.Main.Get("SHA-256-Digest-Manifest-Main-Attributes"); digest != sha256Base64(m.MainBytes()) {
+			return nil, errors.New("main attributes of the manifest have been modified since they were signed")
+		}
+		for _, section := range m.Sections {
+			sfSection := findSection(sf, section.Name)
+			if sfSection == nil {
+				return nil, fmt.Errorf("%s is not covered by the signature", section.Name)
+			}
+			digest, _ := sfSection.Attributes.Get(DigestAttribute)
+			if digest != sha256Base64(section.Bytes()) {
+				return nil, fmt.Errorf("manifest section for %s has been modified since it was signed", section.Name)
+			}
+		}
+	}
+
+	return &Signature{
+		Name:  path.Base(base),
+		Chain: chain,
+	}, nil
+}
+
+func findSection(m *manifest.Manifest, name string) *manifest.Section {
+	for i := range m.Sections {
+		if m.Sections[i].Name == name {
+			return &m.Sections[i]
+		}
+	}
+	return nil
+}
+
+func readFile(f *zip.File) ([]byte, error) {
+	reader, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", f.Name, err)
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", f.Name, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,195 @@
+package jdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// NoRuntimeError is returned when none of the Java runtimes installed on the machine is
+// recent enough.
+type NoRuntimeError struct {
+	// Required is the minimum major Java version.
+	Required int
+	// Found are the runtimes found on the machine, all older than required.
+	Found []*Runtime
+}
+
+func (e *NoRuntimeError) Error() string {
+	if len(e.Found) == 0 {
+		return fmt.Sprintf("couldn't find Java on your machine, Java %d or later is required", e.Required)
+	}
+	found := make([]string, len(e.Found))
+	for i, r := range e.Found {
+		found[i] = r.String()
+	}
+	return fmt.Sprintf("couldn't find Java %d or later on your machine, found %s", e.Required, strings.Join(found, ", "))
+}
+
+// executable returns the name of the java command in the bin directory of a runtime.
+func executable() string {
+	if runtime.GOOS == "windows" {
+		return "java.exe"
+	}
+	return "java"
+}
+
+// installDirs returns the glob patterns of the directories Java runtimes are commonly
+// installed to, each containing one runtime per subdirectory.
+func installDirs() []string {
+	home, _ := os.UserHomeDir()
+	var dirs []string
+
+	// Version managers
+	sdkman := os.Getenv("SDKMAN_DIR")
+	if sdkman == "" && home != "" {
+		sdkman = filepath.Join(home, ".sdkman")
+	}
+	asdf := os.Getenv("ASDF_DATA_DIR")
+	if asdf == "" && home != "" {
+		asdf = filepath.Join(home, ".asdf")
+	}
+	if sdkman != "" {
+		dirs = append(dirs, filepath.Join(sdkman, "candidates", "java", "*"))
+	}
+	if asdf != "" {
+		dirs = append(dirs, filepath.Join(asdf, "installs", "java", "*"))
+	}
+	if home != "" {
+		dirs = append(dirs,
+			filepath.Join(home, ".local", "share", "mise", "installs", "java", "*"),
+			filepath.Join(home, ".jdks", "*"),
+			filepath.Join(home, ".gradle", "jdks", "*"),
+		)
+	}
+
+	// System locations
+	switch runtime.GOOS {
+	case "darwin":
+		dirs = append(dirs, "/Library/Java/JavaVirtualMachines/*/Contents/Home")
+		if home != "" {
+			dirs = append(dirs, filepath.Join(home, "Library", "Java", "JavaVirtualMachines", "*", "Contents", "Home"))
+		}
+		dirs = append(dirs, "/opt/homebrew/opt/openjdk*/libexec/openjdk.jdk/Contents/Home")
+	case "windows":
+		for _, env := range []string{"ProgramFiles", "ProgramW6432"} {
+			programFiles := os.Getenv(env)
+			if programFiles == "" {
+				continue
+			}
+			for _, vendor := range []string{"Java", "Eclipse Adoptium", "Microsoft", "Zulu", "Amazon Corretto", "BellSoft"} {
+				dirs = append(dirs, filepath.Join(programFiles, vendor, "*"))
+			}
+		}
+	default:
+		dirs = append(dirs, "/usr/lib/jvm/*", "/usr/lib64/jvm/*", "/usr/java/*", "/opt/java/*", "/opt/jdk*")
+	}
+	return dirs
+}
+
+// candidate is a java command that may be installed on the machine.
+type candidate struct {
+	path      string
+	preferred bool
+}
+
+// candidates returns the java commands that may be installed on the machine, in order of
+// preference: the one in JAVA_HOME, the one on the PATH, and the ones in the directories
+// Java is commonly installed to, including those of SDKMAN! and asdf. The same command is
+// only returned once.
+func candidates() []candidate {
+	var all []candidate
+	if javaHome := os.Getenv("JAVA_HOME"); javaHome != "" {
+		all = append(all, candidate{filepath.Join(javaHome, "bin", executable()), true})
+	}
+	if path, err := exec.LookPath("java"); err == nil {
+		all = append(all, candidate{path, true})
+	}
+	for _, pattern := range installDirs() {
+		matches, _ := filepath.Glob(pattern)
+		sort.Strings(matches)
+		for _, dir := range matches {
+			all = append(all, candidate{filepath.Join(dir, "bin", executable()), false})
+		}
+	}
+
+	// Keep the commands that exist, once each
+	seen := make(map[string]bool)
+	var existing []candidate
+	for _, c := range all {
+		resolved, err := filepath.EvalSymlinks(c.path)
+		if err != nil {
+			continue
+		}
+		if stat, err := os.Stat(resolved); err != nil || stat.IsDir() || seen[resolved] {
+			continue
+		}
+		seen[resolved] = true
+		existing = append(existing, c)
+	}
+	return existing
+}
+
+// Discover finds the Java runtimes installed on the machine, in order of preference.
+// Commands that fail to report their version are ignored.
+func Discover(ctx context.Context) []*Runtime {
+	var runtimes []*Runtime
+	for _, c := range candidates() {
+		if r, err := Probe(ctx, c.path); err == nil {
+			r.Preferred = c.preferred
+			runtimes = append(runtimes, r)
+		}
+	}
+	return runtimes
+}
+
+// Select picks a runtime with at least the required major version: the first preferred
+// one, or else the oldest of the others, which is the most likely to behave like the
+// version the Minecraft server was made for. If none is recent enough, the error is a
+// *NoRuntimeError.
+func Select(runtimes []*Runtime, required int) (*Runtime, error) {
+	var selected *Runtime
+	for _, r := range runtimes {
+		if r.Major < required {
+			continue
+		}
+		if r.Preferred {
+			return r, nil
+		}
+		if selected == nil || r.Major < selected.Major {
+			selected = r
+		}
+	}
+	if selected == nil {
+		return nil, &NoRuntimeError{Required: required, Found: runtimes}
+	}
+	return selected, nil
+}
+
+// Find finds an installed Java runtime with at least the required major version, as
+// picked by Select.
+func Find(ctx context.Context, required int) (*Runtime, error) {
+	return Select(Discover(ctx), required)
+}
+
+// Open returns the runtime given by the user, as the path of either a java command or a
+// Java installation directory.
+func Open(ctx context.Context, path string) (*Runtime, error) {
+	if stat, err := os.Stat(path); err == nil && stat.IsDir() {
+		path = filepath.Join(path, "bin", executable())
+	} else if errors.Is(err, os.ErrNotExist) && !strings.ContainsRune(path, filepath.Separator) {
+		// A command name, like "java21"
+		resolved, err := exec.LookPath(path)
+		if err != nil {
+			return nil, fmt.Errorf("java command %s not found", path)
+		}
+		path = resolved
+	}
+	return Probe(ctx, path)
+}
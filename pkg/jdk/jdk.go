@@ -0,0 +1,70 @@
+// Package jdk finds Java runtimes and their versions.
+package jdk
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Runtime is a Java runtime installed on the machine.
+type Runtime struct {
+	// Path is the path of the java command.
+	Path string
+	// Version is the full version of the runtime (e.g. "21.0.2" or "1.8.0_392").
+	Version string
+	// Major is the major Java version (e.g. 21, or 8 for "1.8.0_392").
+	Major int
+	// Preferred is set for the runtimes of JAVA_HOME and the PATH, which are picked before
+	// the other installed runtimes.
+	Preferred bool
+}
+
+func (r *Runtime) String() string {
+	return fmt.Sprintf("Java %s (%s)", r.Version, r.Path)
+}
+
+// versionRegexp matches the version in the output of "java -version", like
+// `openjdk version "21.0.2" 2024-01-16`.
+var versionRegexp = regexp.MustCompile(`version "([^"]+)"`)
+
+// Probe runs a java command to find out its version.
+func Probe(ctx context.Context, path string) (*Runtime, error) {
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, "-version")
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("running %s -version: %w", path, err)
+	}
+	version, major, err := ParseVersion(out.String())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &Runtime{Path: path, Version: version, Major: major}, nil
+}
+
+// ParseVersion parses the output of "java -version", and returns the full version and the
+// major version.
+func ParseVersion(output string) (string, int, error) {
+	match := versionRegexp.FindStringSubmatch(output)
+	if match == nil {
+		return "", 0, fmt.Errorf("unknown java version output %q", strings.TrimSpace(output))
+	}
+	version := match[1]
+
+	// Versions before Java 9 look like "1.8.0_392"
+	parts := strings.FieldsFunc(version, func(r rune) bool { return r == '.' || r == '_' || r == '-' || r == '+' })
+	if len(parts) > 1 && parts[0] == "1" {
+		parts = parts[1:]
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid java version %q", version)
+	}
+	return version, major, nil
+}
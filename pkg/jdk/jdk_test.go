@@ -0,0 +1,97 @@
+package jdk_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/customrealms/cli/pkg/jdk"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		output  string
+		version string
+		major   int
+	}{
+		{"openjdk version \"21.0.2\" 2024-01-16\nOpenJDK Runtime Environment (build 21.0.2+13-58)\n", "21.0.2", 21},
+		{"java version \"1.8.0_392\"\nJava(TM) SE Runtime Environment (build 1.8.0_392-b08)\n", "1.8.0_392", 8},
+		{"openjdk version \"17\" 2021-09-14\n", "17", 17},
+		{"openjdk version \"25-ea\" 2025-09-16\n", "25-ea", 25},
+	}
+	for _, tt := range tests {
+		version, major, err := jdk.ParseVersion(tt.output)
+		require.NoError(t, err)
+		require.Equal(t, tt.version, version)
+		require.Equal(t, tt.major, major)
+	}
+
+	_, _, err := jdk.ParseVersion("command not found")
+	require.Error(t, err)
+}
+
+// fakeJava installs a fake java command reporting a version in a runtime directory.
+func fakeJava(t *testing.T, dir, version string) string {
+	t.Helper()
+	filename := filepath.Join(dir, "bin", "java")
+	require.NoError(t, os.MkdirAll(filepath.Dir(filename), 0777))
+	script := fmt.Sprintf("#!/bin/sh\necho 'openjdk version \"%s\" 2024-01-16' >&2\n", version)
+	require.NoError(t, os.WriteFile(filename, []byte(script), 0777))
+	return filename
+}
+
+func TestFind(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake java commands are shell scripts")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("PATH", t.TempDir())
+	t.Setenv("ASDF_DATA_DIR", "")
+	t.Setenv("SDKMAN_DIR", "")
+	javaHome := filepath.Join(home, "jdk-17")
+	fakeJava(t, javaHome, "17.0.2")
+	t.Setenv("JAVA_HOME", javaHome)
+	sdkmanJava := fakeJava(t, filepath.Join(home, ".sdkman", "candidates", "java", "21.0.2-tem"), "21.0.2")
+
+	r, err := jdk.Find(context.Background(), 17)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(javaHome, "bin", "java"), r.Path)
+	require.True(t, r.Preferred)
+
+	r, err = jdk.Find(context.Background(), 21)
+	require.NoError(t, err)
+	require.Equal(t, sdkmanJava, r.Path)
+	require.Equal(t, 21, r.Major)
+
+	_, err = jdk.Find(context.Background(), 99)
+	var noRuntime *jdk.NoRuntimeError
+	require.ErrorAs(t, err, &noRuntime)
+	require.Equal(t, 99, noRuntime.Required)
+
+	r, err = jdk.Open(context.Background(), javaHome)
+	require.NoError(t, err)
+	require.Equal(t, "17.0.2", r.Version)
+}
+
+func TestSelect(t *testing.T) {
+	runtimes := []*jdk.Runtime{
+		{Path: "/java-home/bin/java", Major: 17, Preferred: true},
+		{Path: "/jvm/25/bin/java", Major: 25},
+		{Path: "/jvm/21/bin/java", Major: 21},
+	}
+	r, err := jdk.Select(runtimes, 17)
+	require.NoError(t, err)
+	require.Equal(t, "/java-home/bin/java", r.Path)
+
+	r, err = jdk.Select(runtimes, 21)
+	require.NoError(t, err)
+	require.Equal(t, "/jvm/21/bin/java", r.Path)
+
+	_, err = jdk.Select(runtimes, 26)
+	require.ErrorContains(t, err, "couldn't find Java 26 or later on your machine, found Java")
+}
@@ -0,0 +1,202 @@
+package manifest
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Filename is the path of the manifest file within a JAR file.
+const Filename = "META-INF/MANIFEST.MF"
+
+// maxLineLength is the maximum length of a line in a manifest file, in bytes, excluding the
+// line break.
+const maxLineLength = 72
+
+// Manifest is the parsed contents of a JAR manifest file.
+type Manifest struct {
+	// Main is the list of main attributes, which apply to the JAR file as a whole.
+	Main Attributes
+	// Sections is the list of per-entry sections, in the order they appear in the file.
+	Sections []Section
+}
+
+// Section is a per-entry section of a manifest file.
+type Section struct {
+	// Name is the name of the JAR entry the section applies to.
+	Name string
+	// Attributes is the list of attributes for the entry, excluding the "Name" attribute.
+	Attributes Attributes
+}
+
+// Attribute is a single "Name: Value" pair in a manifest file.
+type Attribute struct {
+	Name  string
+	Value string
+}
+
+// Attributes is an ordered list of manifest attributes. Attribute names are case-insensitive.
+type Attributes []Attribute
+
+// Get returns the value of the named attribute, and whether it was found.
+func (a Attributes) Get(name string) (string, bool) {
+	for _, attr := range a {
+		if strings.EqualFold(attr.Name, name) {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+// Set sets the value of the named attribute, replacing the existing value if there is one,
+// or appending the attribute otherwise.
+func (a *Attributes) Set(name, value string) {
+	for i, attr := range *a {
+		if strings.EqualFold(attr.Name, name) {
+			(*a)[i].Value = value
+			return
+		}
+	}
+	*a = append(*a, Attribute{name, value})
+}
+
+// Delete removes the named attribute, if it exists.
+func (a *Attributes) Delete(name string) {
+	for i, attr := range *a {
+		if strings.EqualFold(attr.Name, name) {
+			*a = append((*a)[:i], (*a)[i+1:]...)
+			return
+		}
+	}
+}
+
+// New creates an empty manifest with the "Manifest-Version" attribute set.
+func New() *Manifest {
+	return &Manifest{
+		Main: Attributes{{"Manifest-Version", "1.0"}},
+	}
+}
+
+// Parse parses the contents of a manifest file.
+func Parse(data []byte) (*Manifest, error) {
+	// Split the file into blocks of attributes separated by blank lines
+	blocks, err := parseBlocks(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// The first block holds the main attributes
+	var m Manifest
+	if len(blocks) > 0 {
+		m.Main = blocks[0]
+	}
+
+	// All other blocks are per-entry sections, which must start with a "Name" attribute
+	for _, block := range blocks[min(1, len(blocks)):] {
+		if !strings.EqualFold(block[0].Name, "Name") {
+			return nil, fmt.Errorf("section must start with a Name attribute, found %q", block[0].Name)
+		}
+		m.Sections = append(m.Sections, Section{
+			Name:       block[0].Value,
+			Attributes: block[1:],
+		})
+	}
+	return &m, nil
+}
+
+// parseBlocks splits the contents of a manifest file into blocks of attributes. Each block
+// is terminated by a blank line or the end of the file. Empty blocks are skipped.
+func parseBlocks(data []byte) ([]Attributes, error) {
+	var blocks []Attributes
+	var block Attributes
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+
+		// Blank lines terminate the current block
+		if line == "" {
+			if len(block) > 0 {
+				blocks = append(blocks, block)
+				block = nil
+			}
+			continue
+		}
+
+		// Continuation lines extend the value of the previous attribute
+		if strings.HasPrefix(line, " ") {
+			if len(block) == 0 {
+				return nil, fmt.Errorf("line %d: continuation line without an attribute", lineNum)
+			}
+			block[len(block)-1].Value += line[1:]
+			continue
+		}
+
+		// Split the "Name: Value" pair
+		name, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			return nil, fmt.Errorf("line %d: invalid attribute %q", lineNum, line)
+		}
+		block = append(block, Attribute{name, value})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(block) > 0 {
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+// Bytes encodes the manifest to the manifest file format.
+func (m *Manifest) Bytes() []byte {
+	var buf bytes.Buffer
+	buf.Write(m.MainBytes())
+	for _, section := range m.Sections {
+		buf.Write(section.Bytes())
+	}
+	return buf.Bytes()
+}
+
+// MainBytes encodes the main attributes of the manifest, including the blank line that
+// terminates them.
+func (m *Manifest) MainBytes() []byte {
+	var buf bytes.Buffer
+	for _, attr := range m.Main {
+		writeAttribute(&buf, attr.Name, attr.Value)
+	}
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
+
+// Bytes encodes the section, including the blank line that terminates it.
+func (s *Section) Bytes() []byte {
+	var buf bytes.Buffer
+	writeAttribute(&buf, "Name", s.Name)
+	for _, attr := range s.Attributes {
+		writeAttribute(&buf, attr.Name, attr.Value)
+	}
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
+
+// writeAttribute writes a single attribute, wrapping it onto continuation lines so that no
+// line exceeds the maximum line length. Lines are never split in the middle of a character.
+func writeAttribute(buf *bytes.Buffer, name, value string) {
+	line := name + ": " + value
+	limit := maxLineLength
+	for len(line) > limit {
+		split := limit
+		for split > 0 && !utf8.RuneStart(line[split]) {
+			split--
+		}
+		buf.WriteString(line[:split])
+		buf.WriteString("\r\n ")
+		line = line[split:]
+		limit = maxLineLength - 1
+	}
+	buf.WriteString(line)
+	buf.WriteString("\r\n")
+}
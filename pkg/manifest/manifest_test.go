@@ -0,0 +1,63 @@
+package manifest_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/customrealms/cli/pkg/manifest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	data := "Manifest-Version: 1.0\r\n" +
+		"Created-By: Maven JAR Plugin 3.3.0\r\n" +
+		"Build-Jdk-Spec: 17\r\n" +
+		"Class-Path: lib/aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa.jar li\r\n" +
+		" b/b.jar\r\n" +
+		"\r\n" +
+		"Name: plugin.js\r\n" +
+		"SHA-256-Digest: abc=\r\n" +
+		"\r\n"
+
+	m, err := manifest.Parse([]byte(data))
+	require.NoError(t, err)
+
+	version, ok := m.Main.Get("manifest-version")
+	require.True(t, ok)
+	require.Equal(t, "1.0", version)
+
+	classPath, _ := m.Main.Get("Class-Path")
+	require.Equal(t, "lib/aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa.jar lib/b.jar", classPath)
+
+	require.Len(t, m.Sections, 1)
+	require.Equal(t, "plugin.js", m.Sections[0].Name)
+	digest, _ := m.Sections[0].Attributes.Get("SHA-256-Digest")
+	require.Equal(t, "abc=", digest)
+
+	// Encoding the parsed manifest gives back the original file
+	require.Equal(t, data, string(m.Bytes()))
+}
+
+func TestBytes(t *testing.T) {
+	m := manifest.New()
+	m.Main.Set("Implementation-Title", strings.Repeat("é", 60))
+	m.Main.Set("Manifest-Version", "1.0")
+
+	data := m.Bytes()
+	for _, line := range strings.Split(string(data), "\r\n") {
+		require.LessOrEqual(t, len(line), 72)
+	}
+
+	// Wrapped values survive a round trip without splitting characters
+	parsed, err := manifest.Parse(data)
+	require.NoError(t, err)
+	require.Equal(t, m.Main, parsed.Main)
+}
+
+func TestParseInvalid(t *testing.T) {
+	_, err := manifest.Parse([]byte("Manifest-Version: 1.0\n\nSHA-256-Digest: abc=\n"))
+	require.ErrorContains(t, err, "Name attribute")
+
+	_, err = manifest.Parse([]byte(" continued\n"))
+	require.ErrorContains(t, err, "continuation")
+}
@@ -0,0 +1,57 @@
+package minecraft
+
+import (
+	"strconv"
+	"strings"
+)
+
+// DefaultVersion is the Minecraft version used when none is given.
+const DefaultVersion = "26.1.2"
+
+// javaVersions are the minimum Java versions required by Minecraft versions, from the
+// newest to the oldest. Each entry applies from its Minecraft version up to the next one.
+var javaVersions = []struct {
+	minecraft [3]int
+	java      int
+}{
+	{[3]int{26, 1, 0}, 25},
+	{[3]int{1, 20, 5}, 21},
+	{[3]int{1, 18, 0}, 17},
+	{[3]int{1, 17, 0}, 16},
+	{[3]int{0, 0, 0}, 8},
+}
+
+// MinJavaVersion returns the minimum Java major version needed to run a Minecraft server
+// of the given version (e.g. 21 for "1.21.4").
+func MinJavaVersion(version string) int {
+	parsed := parseVersion(version)
+	for _, v := range javaVersions {
+		if compareVersions(parsed, v.minecraft) >= 0 {
+			return v.java
+		}
+	}
+	return javaVersions[len(javaVersions)-1].java
+}
+
+// parseVersion parses the first three numbers of a version like "1.20.6". Missing or
+// invalid numbers are zero.
+func parseVersion(version string) [3]int {
+	var parsed [3]int
+	for i, part := range strings.SplitN(version, ".", 3) {
+		// Ignore suffixes like "-pre1"
+		if end := strings.IndexFunc(part, func(r rune) bool { return r < '0' || r > '9' }); end >= 0 {
+			part = part[:end]
+		}
+		parsed[i], _ = strconv.Atoi(part)
+	}
+	return parsed
+}
+
+func compareVersions(a, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] - b[i]
+		}
+	}
+	return 0
+}
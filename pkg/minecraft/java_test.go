@@ -0,0 +1,23 @@
+package minecraft_test
+
+import (
+	"testing"
+
+	"github.com/customrealms/cli/pkg/minecraft"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMinJavaVersion(t *testing.T) {
+	for version, java := range map[string]int{
+		"1.16.5": 8,
+		"1.17.1": 16,
+		"1.18":   17,
+		"1.20.4": 17,
+		"1.20.5": 21,
+		"1.21.4": 21,
+		"26.1":   25,
+		"26.1.2": 25,
+	} {
+		require.Equal(t, java, minecraft.MinJavaVersion(version), version)
+	}
+}
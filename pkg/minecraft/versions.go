@@ -3,10 +3,19 @@ package minecraft
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+
+	"github.com/customrealms/cli/pkg/cache"
+	"github.com/customrealms/cli/pkg/download"
 )
 
+// PaperMCURL is the base URL of the PaperMC download API. It can be replaced with a mirror
+// with the Mirrors of the download client, see the "Mirrors" section of the README.
+const PaperMCURL = "https://fill.papermc.io"
+
 type paperMcBuild struct {
 	ID        int    `json:"id"`
 	Channel   string `json:"channel"`
@@ -22,16 +31,21 @@ type paperMcBuild struct {
 
 func LookupVersion(ctx context.Context, versionStr string) (Version, error) {
 	// Lookup the version from PaperMC
-	builds, err := downloadJSON[[]paperMcBuild](ctx, fmt.Sprintf("https://fill.papermc.io/v3/projects/paper/versions/%s/builds", versionStr))
+	buildsUrl := fmt.Sprintf("%s/v3/projects/paper/versions/%s/builds", PaperMCURL, versionStr)
+	data, err := downloadBuilds(ctx, buildsUrl, versionStr)
 	if err != nil {
-		return nil, fmt.Errorf("download builds list: %w", err)
+		return nil, err
+	}
+	var builds []paperMcBuild
+	if err := json.Unmarshal(data, &builds); err != nil {
+		return nil, fmt.Errorf("decoding builds list: %w", err)
 	}
-	if builds == nil || len(*builds) == 0 {
+	if len(builds) == 0 {
 		return nil, fmt.Errorf("no builds found for version %s", versionStr)
 	}
 
 	// The latest entry is the latest build
-	build := (*builds)[0]
+	build := builds[0]
 
 	// Get the server jar URL for the build
 	serverJarDownload, ok := build.Downloads["server:default"]
@@ -39,46 +53,39 @@ func LookupVersion(ctx context.Context, versionStr string) (Version, error) {
 		return nil, fmt.Errorf("no server jar found for build %d", build.ID)
 	}
 
-	version := &paperMcVersion{versionStr, build.ID, serverJarDownload.URL}
-	return version, nil
-}
-
-func downloadJSON[T any](ctx context.Context, url string) (*T, error) {
-	// Create the HTTP request
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	// Mirrors may give the URL relative to the builds list
+	base, err := url.Parse(buildsUrl)
 	if err != nil {
-		return nil, fmt.Errorf("create http request: %w", err)
+		return nil, err
 	}
-
-	// Send the HTTP request
-	res, err := http.DefaultClient.Do(req)
+	serverJarUrl, err := base.Parse(serverJarDownload.URL)
 	if err != nil {
-		return nil, fmt.Errorf("send http request: %w", err)
+		return nil, fmt.Errorf("invalid server jar URL for build %d: %w", build.ID, err)
 	}
-	defer res.Body.Close()
 
-	// Decode the JSON response
-	var result T
-	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("decode json response: %w", err)
-	}
-	return &result, nil
+	version := &paperMcVersion{versionStr, build.ID, serverJarUrl.String()}
+	return version, nil
 }
 
-func checkHttpOK(ctx context.Context, url string) (bool, error) {
-	// Create the HTTP request
-	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
-	if err != nil {
-		return false, fmt.Errorf("create http request: %w", err)
-	}
+// BuildsCacheKey returns the cache key of the list of PaperMC builds of a Minecraft version.
+func BuildsCacheKey(versionStr string) string {
+	return "versions/paper-" + versionStr + ".json"
+}
 
-	// Send the HTTP request
-	res, err := http.DefaultClient.Do(req)
+// downloadBuilds downloads the list of PaperMC builds of a Minecraft version. The list is
+// cached, and the cached copy is used when the download fails, e.g. when offline.
+func downloadBuilds(ctx context.Context, buildsUrl, versionStr string) ([]byte, error) {
+	what := fmt.Sprintf("the builds of Minecraft %s", versionStr)
+	data, _, err := cache.Fetch(ctx, BuildsCacheKey(versionStr), what, func(ctx context.Context) ([]byte, cache.Meta, error) {
+		data, err := download.Default.Bytes(ctx, buildsUrl)
+		return data, cache.Meta{Kind: "versions", Version: versionStr, Source: buildsUrl}, err
+	})
+	var statusErr *download.StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("unknown Minecraft version %s", versionStr)
+	}
 	if err != nil {
-		return false, fmt.Errorf("send http request: %w", err)
+		return nil, fmt.Errorf("download builds list: %w", err)
 	}
-	defer res.Body.Close()
-
-	// Check the status code
-	return res.StatusCode == http.StatusOK, nil
+	return data, nil
 }
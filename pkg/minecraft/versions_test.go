@@ -0,0 +1,54 @@
+package minecraft_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/customrealms/cli/pkg/download"
+	"github.com/customrealms/cli/pkg/minecraft"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupVersionMirror(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	// Mirror the PaperMC API in a local directory
+	dir := t.TempDir()
+	versionDir := filepath.Join(dir, "v3", "projects", "paper", "versions", "1.21.4")
+	require.NoError(t, os.MkdirAll(versionDir, 0777))
+	require.NoError(t, os.WriteFile(filepath.Join(versionDir, "builds"), []byte(`[
+		{"id": 232, "channel": "STABLE", "downloads": {"server:default": {"name": "paper-1.21.4-232.jar", "url": "paper-1.21.4-232.jar"}}}
+	]`), 0666))
+
+	mirrors := download.Default.Mirrors
+	download.Default.Mirrors = map[string]string{
+		minecraft.PaperMCURL: (&url.URL{Scheme: "file", Path: filepath.ToSlash(dir)}).String(),
+	}
+	t.Cleanup(func() { download.Default.Mirrors = mirrors })
+
+	// Relative server JAR URLs are resolved against the builds list
+	version, err := minecraft.LookupVersion(context.Background(), "1.21.4")
+	require.NoError(t, err)
+	require.Equal(t, minecraft.PaperMCURL+"/v3/projects/paper/versions/1.21.4/paper-1.21.4-232.jar", version.ServerJarUrl())
+
+	// Versions missing from the mirror are unknown
+	_, err = minecraft.LookupVersion(context.Background(), "1.0")
+	require.ErrorContains(t, err, "unknown Minecraft version 1.0")
+
+	// The cached builds list is used when the mirror is unreachable
+	backoff := download.Default.Backoff
+	download.Default.Backoff = 0
+	t.Cleanup(func() { download.Default.Backoff = backoff })
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	download.Default.Mirrors[minecraft.PaperMCURL] = srv.URL
+	version, err = minecraft.LookupVersion(context.Background(), "1.21.4")
+	require.NoError(t, err)
+	require.Equal(t, "1.21.4", version.String())
+}
@@ -0,0 +1,143 @@
+package plugintest
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/customrealms/cli/pkg/serverlog"
+)
+
+// errTimeout is returned when the expected output doesn't come in time.
+var errTimeout = errors.New("timed out")
+
+// errStopped is returned when the server stops before the expected output comes.
+var errStopped = errors.New("server stopped")
+
+// tailLines is the number of lines of output kept to explain failures.
+const tailLines = 30
+
+// console reads the output of the server line by line, and keeps track of what the
+// plugins under test do.
+type console struct {
+	lines   chan string
+	plugins []Plugin
+	// output is the whole output of the server.
+	output strings.Builder
+	// tail are the last lines of output.
+	tail []string
+	// enabled are the plugins enabled, by name.
+	enabled map[string]bool
+	// errors are the errors logged by the plugins, or about them, in order.
+	errors []pluginError
+	// inError is set while reading the stack trace of the last error.
+	inError bool
+}
+
+// pluginError is an error logged by a plugin, or about it.
+type pluginError struct {
+	plugin  string
+	message string
+}
+
+func newConsole(r io.Reader, echo io.Writer, plugins []Plugin) *console {
+	c := &console{
+		lines:   make(chan string),
+		plugins: plugins,
+		enabled: make(map[string]bool),
+	}
+	go func() {
+		defer close(c.lines)
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if echo != nil {
+				fmt.Fprintln(echo, line)
+			}
+			c.lines <- line
+		}
+		// Keep the server running if its output can't be read anymore
+		io.Copy(io.Discard, r)
+	}()
+	return c
+}
+
+// waitFor reads the output until a line matches a regular expression, and returns it. If
+// the expression is nil, it reads until the server stops.
+func (c *console) waitFor(ctx context.Context, timeout time.Duration, re *regexp.Regexp) (string, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-timer.C:
+			return "", errTimeout
+		case line, ok := <-c.lines:
+			if !ok {
+				return "", errStopped
+			}
+			c.observe(line)
+			if re != nil && re.MatchString(line) {
+				return line, nil
+			}
+		}
+	}
+}
+
+// observe records a line of output.
+func (c *console) observe(line string) {
+	c.output.WriteString(line)
+	c.output.WriteByte('\n')
+	c.tail = append(c.tail, line)
+	if len(c.tail) > tailLines {
+		c.tail = c.tail[1:]
+	}
+
+	// Lines that don't start an event, like exceptions and their stack traces, continue the
+	// last error
+	event, ok := serverlog.Parse(line)
+	if !ok {
+		if c.inError {
+			last := &c.errors[len(c.errors)-1]
+			last.message += "\n" + line
+		}
+		return
+	}
+	c.inError = false
+
+	// "Enabling X" is logged before the plugin is enabled, so a plugin failing to enable
+	// isn't enabled after all
+	problem, isProblem := serverlog.ParseProblem(event)
+	for _, plugin := range c.plugins {
+		if strings.HasPrefix(event.Message, "Enabling "+plugin.Name+" v") {
+			c.enabled[plugin.Name] = true
+		}
+		if isProblem && problem.Stage == "enable" && problem.Plugin == plugin.Name {
+			c.enabled[plugin.Name] = false
+		}
+		if event.Level >= serverlog.LevelError && plugin.Owns(event) {
+			c.errors = append(c.errors, pluginError{plugin: plugin.Name, message: line})
+			c.inError = true
+		}
+	}
+}
+
+// errorsSince returns the messages of the errors of a plugin, starting from an index in
+// the list of errors. If the plugin name is empty, the errors of all the plugins are
+// returned.
+func (c *console) errorsSince(i int, plugin string) []string {
+	var messages []string
+	for _, err := range c.errors[i:] {
+		if plugin == "" || err.plugin == plugin {
+			messages = append(messages, err.message)
+		}
+	}
+	return messages
+}
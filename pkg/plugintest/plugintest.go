@@ -0,0 +1,261 @@
+// Package plugintest checks that plugins work in a real Minecraft server. It starts a
+// headless server with the plugins, checks that they're enabled without errors, runs
+// console commands and checks their output, then stops the server.
+package plugintest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/customrealms/cli/pkg/project"
+	"github.com/customrealms/cli/pkg/serve"
+	"github.com/customrealms/cli/pkg/serverlog"
+)
+
+const (
+	// DefaultStartTimeout is how long the server may take to start by default. The first
+	// start of a Paper server downloads and patches the vanilla server, which takes a while.
+	DefaultStartTimeout = 3 * time.Minute
+	// DefaultCommandTimeout is how long a command may take to print the expected output by
+	// default.
+	DefaultCommandTimeout = 10 * time.Second
+	// stopTimeout is how long the server may take to stop, before it's killed.
+	stopTimeout = 30 * time.Second
+	// settleTime is how long errors are watched for after a command without expected output.
+	settleTime = time.Second
+)
+
+// doneLine matches the line logged when the server is started, like
+// `Done (12.345s)! For help, type "help"`.
+var doneLine = regexp.MustCompile(`Done \([0-9.,]+s\)!`)
+
+// Plugin is a plugin under test.
+type Plugin = serverlog.Plugin
+
+// TestAction runs the test of plugins in a server.
+type TestAction struct {
+	// Serve runs the server. Its console input and output are replaced by the test, and
+	// server.properties defaults are set for a headless server on a free port.
+	Serve *serve.ServeAction
+	// Plugins are the plugins that must be enabled.
+	Plugins []Plugin
+	// Commands are run once the server started, in order.
+	Commands []project.TestCommand
+	// StartTimeout is how long the server may take to start. If it's zero,
+	// DefaultStartTimeout is used.
+	StartTimeout time.Duration
+	// Output receives the console output of the server. If it's nil, it's discarded.
+	Output io.Writer
+}
+
+// Run runs the test, and returns its report. The error is only set if the server couldn't
+// be run at all, e.g. because Java isn't installed. Failed tests are in the report.
+func (a *TestAction) Run(ctx context.Context) (*Report, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	start := time.Now()
+
+	// Set up a headless server
+	serveAction := *a.Serve
+	properties, err := headlessProperties(a.Serve.Properties)
+	if err != nil {
+		return nil, err
+	}
+	serveAction.Properties = properties
+	// The pipes are files, so they're given to the server process as they are, and the
+	// process exiting isn't held up by the copy of its input.
+	stdinReader, stdin, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer stdin.Close()
+	stdoutReader, stdout, err := os.Pipe()
+	if err != nil {
+		stdinReader.Close()
+		return nil, err
+	}
+	defer stdoutReader.Close()
+	serveAction.Stdin, serveAction.Stdout = stdinReader, stdout
+
+	// Run the server
+	chanServeErr := make(chan error, 1)
+	go func() {
+		err := serveAction.Run(ctx, nil)
+		stdout.Close()
+		stdinReader.Close()
+		chanServeErr <- err
+	}()
+	var serveErr error
+	served := false
+	waitServe := func() error {
+		if !served {
+			serveErr, served = <-chanServeErr, true
+		}
+		return serveErr
+	}
+	console := newConsole(stdoutReader, a.Output, a.Plugins)
+
+	report := &Report{Name: "crx test"}
+	defer func() {
+		report.Time = time.Since(start)
+		report.Output = console.output.String()
+	}()
+
+	// Wait for the server to start
+	timeout := a.StartTimeout
+	if timeout == 0 {
+		timeout = DefaultStartTimeout
+	}
+	started := report.run("server starts", func() error {
+		_, err := console.waitFor(ctx, timeout, doneLine)
+		switch {
+		case errors.Is(err, errTimeout):
+			return fmt.Errorf("the server didn't start within %s\n\n%s", timeout, strings.Join(console.tail, "\n"))
+		case errors.Is(err, errStopped):
+			if err := waitServe(); err != nil && console.output.Len() == 0 {
+				return &setupError{err}
+			}
+			return fmt.Errorf("the server stopped before it started\n\n%s", strings.Join(console.tail, "\n"))
+		}
+		return err
+	})
+	var setupErr *setupError
+	if errors.As(started, &setupErr) {
+		return nil, setupErr.err
+	}
+
+	// Check that the plugins are enabled without errors
+	for _, plugin := range a.Plugins {
+		report.run(fmt.Sprintf("plugin %s is enabled", plugin.Name), func() error {
+			if started != nil {
+				return errSkipped
+			}
+			if errs := console.errorsSince(0, plugin.Name); len(errs) > 0 {
+				return fmt.Errorf("the plugin logged errors:\n\n%s", strings.Join(errs, "\n"))
+			}
+			if !console.enabled[plugin.Name] {
+				return fmt.Errorf("the plugin wasn't enabled")
+			}
+			return nil
+		})
+	}
+
+	// Run the commands
+	for _, command := range a.Commands {
+		report.run("command "+command.Command, func() error {
+			if started != nil {
+				return errSkipped
+			}
+			return a.runCommand(ctx, console, stdin, command)
+		})
+	}
+
+	// Stop the server
+	report.run("server stops", func() error {
+		if started == nil {
+			fmt.Fprintln(stdin, "stop")
+		}
+		stdin.Close()
+		_, err := console.waitFor(ctx, stopTimeout, nil)
+		if errors.Is(err, errTimeout) {
+			cancel()
+			console.waitFor(context.Background(), stopTimeout, nil)
+			waitServe()
+			return fmt.Errorf("the server didn't stop within %s, and was killed", stopTimeout)
+		}
+		if err := waitServe(); err != nil && started == nil {
+			return fmt.Errorf("the server stopped with an error: %w", err)
+		}
+		return nil
+	})
+	return report, nil
+}
+
+// runCommand runs a console command, and checks its output.
+func (a *TestAction) runCommand(ctx context.Context, console *console, stdin io.Writer, command project.TestCommand) error {
+	timeout := time.Duration(command.Timeout)
+	if timeout == 0 {
+		timeout = DefaultCommandTimeout
+	}
+	var expect *regexp.Regexp
+	if command.Expect != "" {
+		var err error
+		if expect, err = regexp.Compile(command.Expect); err != nil {
+			return fmt.Errorf("invalid expected output: %w", err)
+		}
+	}
+
+	// Send the command
+	errorCount := len(console.errors)
+	if _, err := fmt.Fprintln(stdin, command.Command); err != nil {
+		return fmt.Errorf("sending the command: %w", err)
+	}
+
+	// Wait for the expected output, or for errors to show up
+	if expect != nil {
+		_, err := console.waitFor(ctx, timeout, expect)
+		switch {
+		case errors.Is(err, errTimeout):
+			return fmt.Errorf("no output matched %q within %s\n\n%s", command.Expect, timeout, strings.Join(console.tail, "\n"))
+		case errors.Is(err, errStopped):
+			return fmt.Errorf("the server stopped\n\n%s", strings.Join(console.tail, "\n"))
+		case err != nil:
+			return err
+		}
+	} else if _, err := console.waitFor(ctx, settleTime, nil); !errors.Is(err, errTimeout) {
+		return fmt.Errorf("the server stopped\n\n%s", strings.Join(console.tail, "\n"))
+	}
+	if errs := console.errorsSince(errorCount, ""); len(errs) > 0 {
+		return fmt.Errorf("the plugins logged errors:\n\n%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// setupError is an error running the server at all.
+type setupError struct {
+	err error
+}
+
+func (e *setupError) Error() string {
+	return e.err.Error()
+}
+
+// headlessProperties returns the server.properties of a server for tests: it listens on a
+// free port, doesn't authenticate players, and has a flat world, which is quick to
+// generate. The given properties take precedence.
+func headlessProperties(properties map[string]string) (map[string]string, error) {
+	port, err := freePort()
+	if err != nil {
+		return nil, fmt.Errorf("finding a free port for the server: %w", err)
+	}
+	headless := map[string]string{
+		"server-port":         strconv.Itoa(port),
+		"online-mode":         "false",
+		"level-type":          "flat",
+		"generate-structures": "false",
+		"spawn-npcs":          "false",
+		"spawn-monsters":      "false",
+	}
+	for key, value := range properties {
+		headless[key] = value
+	}
+	return headless, nil
+}
+
+// freePort returns a TCP port nothing is listening on.
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
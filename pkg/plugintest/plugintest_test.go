@@ -0,0 +1,139 @@
+package plugintest_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/customrealms/cli/pkg/minecraft"
+	"github.com/customrealms/cli/pkg/plugintest"
+	"github.com/customrealms/cli/pkg/project"
+	"github.com/customrealms/cli/pkg/serve"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeJava pretends to be Java running a server, which enables the Hello plugin, fails to
+// enable the Broken and Flaky plugins, and answers the "version" command.
+const fakeJava = `#!/bin/sh
+if [ "$1" = "-version" ]; then
+	echo 'openjdk version "21.0.2" 2024-01-16' >&2
+	exit 0
+fi
+echo '[12:00:00 INFO]: [Hello] Enabling Hello v1.0.0'
+echo '[12:00:00 INFO]: [Broken] Enabling Broken v1.0.0'
+echo '[12:00:00 ERROR]: Error occurred while enabling Broken v1.0.0 (Is it up to date?)'
+echo 'java.lang.IllegalStateException: boom'
+echo '	at Broken.onEnable(plugin.js:1)'
+echo '[12:00:00 INFO]: [Flaky] Enabling Flaky v1.0.0'
+echo '[12:00:00 WARN]: Error occurred while enabling Flaky v1.0.0 (Is it up to date?)'
+echo '[12:00:01 INFO]: Done (1.234s)! For help, type "help"'
+while read line; do
+	case "$line" in
+	stop) echo '[12:00:02 INFO]: Stopping server'; exit 0;;
+	version) echo '[12:00:01 INFO]: This server is running Paper version 1.20.4';;
+	*) echo '[12:00:01 INFO]: Unknown command. Type "/help" for help.';;
+	esac
+done
+`
+
+type fakeVersion struct{}
+
+func (fakeVersion) String() string        { return "1.20.4" }
+func (fakeVersion) ApiVersion() string    { return "1.20" }
+func (fakeVersion) ServerJarType() string { return "paper" }
+func (fakeVersion) ServerJarUrl() string  { return "" }
+
+type fakeFetcher struct{}
+
+func (fakeFetcher) Fetch(ctx context.Context, version minecraft.Version) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader("server")), nil
+}
+
+func TestRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake Java runtime is a shell script")
+	}
+	dir := t.TempDir()
+	java := filepath.Join(dir, "java")
+	require.NoError(t, os.WriteFile(java, []byte(fakeJava), 0755))
+	var jars []string
+	for _, name := range []string{"hello.jar", "broken.jar", "flaky.jar", "missing.jar"} {
+		jars = append(jars, filepath.Join(dir, name))
+		require.NoError(t, os.WriteFile(jars[len(jars)-1], []byte("jar"), 0644))
+	}
+
+	action := &plugintest.TestAction{
+		Serve: &serve.ServeAction{
+			MinecraftVersion: fakeVersion{},
+			PluginJarPaths:   jars,
+			ServerJarFetcher: fakeFetcher{},
+			Java:             java,
+		},
+		Plugins: []plugintest.Plugin{
+			{Name: "Hello", JarName: "hello.jar"},
+			{Name: "Broken", JarName: "broken.jar"},
+			{Name: "Flaky", JarName: "flaky.jar"},
+			{Name: "Missing", JarName: "missing.jar"},
+		},
+		Commands: []project.TestCommand{
+			{Command: "version", Expect: `Paper version 1\.20`},
+			{Command: "nope", Expect: `Paper`, Timeout: project.Duration(200 * time.Millisecond)},
+			{Command: "help"},
+		},
+		StartTimeout: 10 * time.Second,
+	}
+	report, err := action.Run(context.Background())
+	require.NoError(t, err)
+
+	results := make(map[string]string)
+	for _, c := range report.Cases {
+		results[c.Name] = c.Failure
+	}
+	require.Len(t, results, 9)
+	require.Empty(t, results["server starts"])
+	require.Empty(t, results["plugin Hello is enabled"])
+	require.Contains(t, results["plugin Broken is enabled"], "Error occurred while enabling Broken")
+	require.Contains(t, results["plugin Broken is enabled"], "IllegalStateException: boom")
+	require.Contains(t, results["plugin Flaky is enabled"], "wasn't enabled")
+	require.Contains(t, results["plugin Missing is enabled"], "wasn't enabled")
+	require.Empty(t, results["command version"])
+	require.Contains(t, results["command nope"], `no output matched "Paper"`)
+	require.Empty(t, results["command help"])
+	require.Empty(t, results["server stops"])
+	require.Equal(t, 4, report.Failures())
+
+	var junit strings.Builder
+	require.NoError(t, report.WriteJUnit(&junit))
+	require.Contains(t, junit.String(), `<testsuite name="crx test" tests="9" failures="4" skipped="0"`)
+	require.Contains(t, junit.String(), `<failure message="the plugin wasn&#39;t enabled">`)
+}
+
+func TestRunNotStarted(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake Java runtime is a shell script")
+	}
+	dir := t.TempDir()
+	java := filepath.Join(dir, "java")
+	script := strings.Replace(fakeJava, `echo '[12:00:01 INFO]: Done`, `exit 1; echo '`, 1)
+	require.NoError(t, os.WriteFile(java, []byte(script), 0755))
+
+	action := &plugintest.TestAction{
+		Serve: &serve.ServeAction{
+			MinecraftVersion: fakeVersion{},
+			ServerJarFetcher: fakeFetcher{},
+			Java:             java,
+		},
+		Plugins: []plugintest.Plugin{{Name: "Hello", JarName: "hello.jar"}},
+	}
+	report, err := action.Run(context.Background())
+	require.NoError(t, err)
+	require.Len(t, report.Cases, 3)
+	require.Contains(t, report.Cases[0].Failure, "the server stopped before it started")
+	require.True(t, report.Cases[1].Skipped)
+	require.Empty(t, report.Cases[2].Failure)
+}
@@ -0,0 +1,163 @@
+package plugintest
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// errSkipped marks test cases that weren't run, because the server didn't start.
+var errSkipped = errors.New("skipped")
+
+// Report is the result of a test run.
+type Report struct {
+	Name  string
+	Cases []Case
+	Time  time.Duration
+	// Output is the console output of the server.
+	Output string
+}
+
+// Case is the result of a test case.
+type Case struct {
+	Name string
+	Time time.Duration
+	// Failure explains why the case failed. It's empty if the case passed.
+	Failure string
+	// Skipped is set if the case wasn't run.
+	Skipped bool
+}
+
+// run runs a test case, and records its result. It returns the error of the case.
+func (r *Report) run(name string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	c := Case{Name: name, Time: time.Since(start)}
+	switch {
+	case errors.Is(err, errSkipped):
+		c.Skipped = true
+	case err != nil:
+		c.Failure = err.Error()
+	}
+	r.Cases = append(r.Cases, c)
+	return err
+}
+
+// Failures returns the number of failed test cases.
+func (r *Report) Failures() int {
+	failures := 0
+	for _, c := range r.Cases {
+		if c.Failure != "" {
+			failures++
+		}
+	}
+	return failures
+}
+
+// Skipped returns the number of skipped test cases.
+func (r *Report) Skipped() int {
+	skipped := 0
+	for _, c := range r.Cases {
+		if c.Skipped {
+			skipped++
+		}
+	}
+	return skipped
+}
+
+// Print writes a summary of the test cases, with the reasons of the failures.
+func (r *Report) Print(w io.Writer) {
+	for _, c := range r.Cases {
+		switch {
+		case c.Skipped:
+			fmt.Fprintf(w, "skip %s\n", c.Name)
+		case c.Failure != "":
+			fmt.Fprintf(w, "FAIL %s (%s)\n", c.Name, c.Time.Round(time.Millisecond))
+			for _, line := range strings.Split(c.Failure, "\n") {
+				fmt.Fprintf(w, "     %s\n", line)
+			}
+		default:
+			fmt.Fprintf(w, "ok   %s (%s)\n", c.Name, c.Time.Round(time.Millisecond))
+		}
+	}
+	fmt.Fprintf(w, "\n%d passed, %d failed, %d skipped in %s\n",
+		len(r.Cases)-r.Failures()-r.Skipped(), r.Failures(), r.Skipped(), r.Time.Round(time.Millisecond))
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	Cases     []junitTestCase `xml:"testcase"`
+	SystemOut *junitText      `xml:"system-out,omitempty"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *struct{}     `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",cdata"`
+}
+
+// junitText is the text of an element, kept as it is in a CDATA section.
+type junitText struct {
+	Text string `xml:",cdata"`
+}
+
+// WriteJUnit writes the report in the JUnit XML format, which CI systems display.
+func (r *Report) WriteJUnit(w io.Writer) error {
+	suite := junitTestSuite{
+		Name:     r.Name,
+		Tests:    len(r.Cases),
+		Failures: r.Failures(),
+		Skipped:  r.Skipped(),
+		Time:     seconds(r.Time),
+	}
+	if r.Output != "" {
+		suite.SystemOut = &junitText{Text: r.Output}
+	}
+	for _, c := range r.Cases {
+		tc := junitTestCase{Name: c.Name, ClassName: r.Name, Time: seconds(c.Time)}
+		if c.Failure != "" {
+			message, _, _ := strings.Cut(c.Failure, "\n")
+			tc.Failure = &junitFailure{Message: message, Text: c.Failure}
+		}
+		if c.Skipped {
+			tc.Skipped = &struct{}{}
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// seconds formats a duration in seconds, like JUnit reports do.
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
@@ -1,6 +1,7 @@
 package pluginyml
 
 import (
+	"encoding/json"
 	"fmt"
 
 	"gopkg.in/yaml.v3"
@@ -8,57 +9,57 @@ import (
 
 type Plugin struct {
 	// Name is the name of your plugin.
-	Name string `yaml:"name"`
+	Name string `yaml:"name" json:"name"`
 	// Version is the semantic version of the plugin (e.g. '1.4.1').
-	Version string `yaml:"version"`
+	Version string `yaml:"version" json:"version"`
 	// ApiVersion is the version of the Bukkit API your plugin is built against.
-	ApiVersion *string `yaml:"api-version,omitempty"`
+	ApiVersion *string `yaml:"api-version,omitempty" json:"api-version,omitempty"`
 	// Description is a human friendly description of the functionality your plugin provides.
-	Description *string `yaml:"description,omitempty"`
+	Description *string `yaml:"description,omitempty" json:"description,omitempty"`
 	// Load explicitly states when the plugin should be loaded. if not supplied will default to 'postworld'.
-	Load *string `yaml:"load,omitempty"`
+	Load *string `yaml:"load,omitempty" json:"load,omitempty"`
 	// Author uniquely identifies who developed this plugin.
-	Author *string `yaml:"author,omitempty"`
+	Author *string `yaml:"author,omitempty" json:"author,omitempty"`
 	// Authors allows you to list multiple authors, if it is a collaborative project.
-	Authors []string `yaml:"authors,flow,omitempty"`
+	Authors []string `yaml:"authors,flow,omitempty" json:"authors,omitempty"`
 	// Website is the URL to the plugin's or author's website.
-	Website *string `yaml:"website,omitempty"`
+	Website *string `yaml:"website,omitempty" json:"website,omitempty"`
 	// Main points to the class that extends JavaPlugin.
-	Main string `yaml:"main"`
+	Main string `yaml:"main" json:"main"`
 	// Prefix is the name to use when logging to console instead of the plugin's name.
-	Prefix *string `yaml:"prefix,omitempty"`
+	Prefix *string `yaml:"prefix,omitempty" json:"prefix,omitempty"`
 	// SoftDepend is a list of plugins that are required for your plugin to have full functionality.
-	SoftDepend []string `yaml:"softdepend,flow,omitempty"`
+	SoftDepend []string `yaml:"softdepend,flow,omitempty" json:"softdepend,omitempty"`
 	// LoadBefore is a list of plugins that should be loaded after your plugin.
-	LoadBefore []string `yaml:"loadbefore,flow,omitempty"`
+	LoadBefore []string `yaml:"loadbefore,flow,omitempty" json:"loadbefore,omitempty"`
 	// Libraries is a list of libraries your plugin needs which can be loaded from Maven Central.
-	Libraries []string `yaml:"libraries,omitempty"`
+	Libraries []string `yaml:"libraries,omitempty" json:"libraries,omitempty"`
 	// Commands is a map of command names to command attributes.
-	Commands map[string]Command `yaml:"commands,omitempty"`
+	Commands map[string]Command `yaml:"commands,omitempty" json:"commands,omitempty"`
 	// Permissions is a map of permission names to permission attributes.
-	Permissions map[string]Permission `yaml:"permissions,omitempty"`
+	Permissions map[string]Permission `yaml:"permissions,omitempty" json:"permissions,omitempty"`
 }
 
 type Command struct {
 	// Description is a short description of what the command does.
-	Description *string `yaml:"description,omitempty"`
+	Description *string `yaml:"description,omitempty" json:"description,omitempty"`
 	// Aliases is a list of alternate command names a user may use.
-	Aliases []string `yaml:"aliases,flow,omitempty"`
+	Aliases []string `yaml:"aliases,flow,omitempty" json:"aliases,omitempty"`
 	// Permission is the most basic permission node required to use the command.
-	Permission *string `yaml:"permission,omitempty"`
+	Permission *string `yaml:"permission,omitempty" json:"permission,omitempty"`
 	// PermissionMessage is the message to display to a user when they do not have the required permission.
-	PermissionMessage *string `yaml:"permission-message,omitempty"`
+	PermissionMessage *string `yaml:"permission-message,omitempty" json:"permission-message,omitempty"`
 	// Usage is a short description of how to use this command.
-	Usage *string `yaml:"usage,omitempty"`
+	Usage *string `yaml:"usage,omitempty" json:"usage,omitempty"`
 }
 
 type Permission struct {
 	// Description is a short description of what the permission allows.
-	Description *string `yaml:"description,omitempty"`
+	Description *string `yaml:"description,omitempty" json:"description,omitempty"`
 	// Default is the default value of the permission.
-	Default *string `yaml:"default,omitempty"`
+	Default *string `yaml:"default,omitempty" json:"default,omitempty"`
 	// Children allows you to set children for the permission.
-	Children map[string]PermissionChild `yaml:"children,omitempty"`
+	Children map[string]PermissionChild `yaml:"children,omitempty" json:"children,omitempty"`
 }
 
 type PermissionChild struct {
@@ -78,6 +79,16 @@ func (p PermissionChild) MarshalYAML() (any, error) {
 	return nil, nil
 }
 
+func (p PermissionChild) MarshalJSON() ([]byte, error) {
+	if p.Bool != nil {
+		return json.Marshal(*p.Bool)
+	}
+	if p.Permission != nil {
+		return json.Marshal(*p.Permission)
+	}
+	return []byte("null"), nil
+}
+
 func (p *PermissionChild) UnmarshalYAML(node *yaml.Node) error {
 	if node.Tag == "!!bool" {
 		var b bool
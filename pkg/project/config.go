@@ -0,0 +1,179 @@
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config is the CLI configuration for a project, read from the "crx" field of package.json.
+type Config struct {
+	// Budgets sets size limits for the build output.
+	Budgets Budgets `json:"budgets"`
+	// Targets is the list of plugins built from the project. If it's empty, the project
+	// builds a single plugin.
+	Targets []Target `json:"targets"`
+	// Test configures crx test.
+	Test TestConfig `json:"test"`
+}
+
+// Target is one of several plugins built from the same project, which share the project's
+// code and dependencies.
+type Target struct {
+	// Name identifies the target. It's also the default plugin name.
+	Name string `json:"name"`
+	// Entrypoint is the path of the TypeScript entrypoint, relative to the project directory.
+	Entrypoint string `json:"entrypoint"`
+	// Output is the path of the output JAR file, relative to the project directory.
+	Output string `json:"output"`
+	// Plugin holds plugin.yml fields that override the project's plugin descriptor.
+	Plugin json.RawMessage `json:"plugin,omitempty"`
+}
+
+// Target returns the target with the given name, or nil if there isn't one.
+func (c *Config) Target(name string) *Target {
+	for i := range c.Targets {
+		if c.Targets[i].Name == name {
+			return &c.Targets[i]
+		}
+	}
+	return nil
+}
+
+// Validate checks that every target has a unique name and an entrypoint.
+func (c *Config) Validate() error {
+	names := make(map[string]bool)
+	for i, target := range c.Targets {
+		if target.Name == "" {
+			return fmt.Errorf("target %d is missing a name", i)
+		}
+		if strings.ContainsAny(target.Name, `/\`) || strings.Contains(target.Name, "..") {
+			return fmt.Errorf("target name %q can't contain /, \\ or ..", target.Name)
+		}
+		if names[target.Name] {
+			return fmt.Errorf("duplicate target %q", target.Name)
+		}
+		names[target.Name] = true
+		if target.Entrypoint == "" {
+			return fmt.Errorf("target %q is missing an entrypoint", target.Name)
+		}
+	}
+	return nil
+}
+
+// TestConfig configures the integration test of the plugin with crx test.
+type TestConfig struct {
+	// Timeout is how long the server may take to start. Zero means the default timeout.
+	Timeout Duration `json:"timeout"`
+	// Commands are console commands run once the server started, in order.
+	Commands []TestCommand `json:"commands"`
+}
+
+// TestCommand is a console command run by crx test, and the output it's expected to print.
+type TestCommand struct {
+	// Command is the console command, without a leading slash.
+	Command string `json:"command"`
+	// Expect is a regular expression a line of output must match for the command to pass.
+	// If it's empty, the command passes unless the plugin logs an error meanwhile.
+	Expect string `json:"expect"`
+	// Timeout is how long to wait for the expected output. Zero means the default timeout.
+	Timeout Duration `json:"timeout"`
+}
+
+// Budgets sets size limits for the build output. A zero size means there is no limit.
+type Budgets struct {
+	// Bundle is the maximum size of the plugin.js bundle.
+	Bundle Size `json:"bundle"`
+	// Jar is the maximum size of the final JAR file.
+	Jar Size `json:"jar"`
+}
+
+// Size is a size in bytes. In JSON, it's either a number of bytes, or a string with a unit
+// like "500KB" or "3MB". Units are powers of 1024.
+type Size int64
+
+var sizeUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+// ParseSize parses a size like "500KB" or "3MB". A number without a unit is in bytes.
+func ParseSize(s string) (Size, error) {
+	str := strings.ToUpper(strings.TrimSpace(s))
+	multiplier := int64(1)
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(str, unit.suffix) {
+			str = strings.TrimSpace(strings.TrimSuffix(str, unit.suffix))
+			multiplier = unit.multiplier
+			break
+		}
+	}
+	value, err := strconv.ParseFloat(str, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return Size(value * float64(multiplier)), nil
+}
+
+func (s *Size) UnmarshalJSON(data []byte) error {
+	// Sizes can be plain numbers of bytes
+	var bytes int64
+	if err := json.Unmarshal(data, &bytes); err == nil {
+		*s = Size(bytes)
+		return nil
+	}
+
+	// Otherwise they are strings with a unit
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return fmt.Errorf("size must be a number or a string")
+	}
+	size, err := ParseSize(str)
+	if err != nil {
+		return err
+	}
+	*s = size
+	return nil
+}
+
+// String formats the size with the largest unit that fits.
+func (s Size) String() string {
+	for _, unit := range sizeUnits {
+		if int64(s) >= unit.multiplier && unit.multiplier > 1 {
+			value := strconv.FormatFloat(float64(s)/float64(unit.multiplier), 'f', 1, 64)
+			return strings.TrimSuffix(value, ".0") + unit.suffix
+		}
+	}
+	return strconv.FormatInt(int64(s), 10) + "B"
+}
+
+// Duration is a duration. In JSON, it's a string like "30s" or "2m", or a number of seconds.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	// Durations can be plain numbers of seconds
+	var seconds float64
+	if err := json.Unmarshal(data, &seconds); err == nil {
+		*d = Duration(seconds * float64(time.Second))
+		return nil
+	}
+
+	// Otherwise they are strings with a unit
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return fmt.Errorf("duration must be a number or a string")
+	}
+	duration, err := time.ParseDuration(str)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q", str)
+	}
+	*d = Duration(duration)
+	return nil
+}
@@ -0,0 +1,78 @@
+package project
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+// GitInfo describes the state of the Git repository containing a project.
+type GitInfo struct {
+	// Commit is the full hash of the HEAD commit.
+	Commit string
+	// Branch is the name of the checked out branch, or empty if HEAD is detached.
+	Branch string
+	// Dirty is true if the working tree has uncommitted changes.
+	Dirty bool
+}
+
+// ShortCommit returns the abbreviated hash of the HEAD commit.
+func (g *GitInfo) ShortCommit() string {
+	if len(g.Commit) > 7 {
+		return g.Commit[:7]
+	}
+	return g.Commit
+}
+
+func (p *project) Git(ctx context.Context) (*GitInfo, error) {
+	// If Git isn't installed, there is no information to return
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, nil
+	}
+
+	// Get the HEAD commit. This fails if the directory isn't in a repository,
+	// or if the repository doesn't have any commits yet.
+	commit, err := p.git(ctx, "rev-parse", "HEAD")
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	// Get the branch name. "HEAD" means we're in a detached state.
+	branch, err := p.git(ctx, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	if branch == "HEAD" {
+		branch = ""
+	}
+
+	// Check for uncommitted changes
+	status, err := p.git(ctx, "status", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+
+	return &GitInfo{
+		Commit: commit,
+		Branch: branch,
+		Dirty:  status != "",
+	}, nil
+}
+
+// git runs a git command in the project directory and returns its trimmed output.
+func (p *project) git(ctx context.Context, args ...string) (string, error) {
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = p.dir
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
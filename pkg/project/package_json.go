@@ -1,6 +1,39 @@
 package project
 
+import (
+	"encoding/json"
+)
+
 type PackageJSON struct {
 	Name    string `json:"name"`
 	Version string `json:"version"`
+	// PackageManager is the package manager of the project, with its version (e.g. "pnpm@9.1.0").
+	PackageManager string `json:"packageManager,omitempty"`
+	// Dependencies and DevDependencies map package names to version ranges.
+	Dependencies    map[string]string `json:"dependencies,omitempty"`
+	DevDependencies map[string]string `json:"devDependencies,omitempty"`
+	// Workspaces lists the glob patterns of the packages in an npm or yarn workspace.
+	Workspaces Workspaces `json:"workspaces,omitempty"`
+	// Crx is the CLI configuration for the project.
+	Crx *Config `json:"crx,omitempty"`
+}
+
+// Workspaces is the "workspaces" field of package.json. It's either a list of patterns, or
+// an object with a "packages" list of patterns, as used by yarn.
+type Workspaces []string
+
+func (w *Workspaces) UnmarshalJSON(data []byte) error {
+	var patterns []string
+	if err := json.Unmarshal(data, &patterns); err == nil {
+		*w = patterns
+		return nil
+	}
+	var object struct {
+		Packages []string `json:"packages"`
+	}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return err
+	}
+	*w = object.Packages
+	return nil
 }
@@ -0,0 +1,125 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PackageManager is a Node.js package manager, used to install the dependencies of
+// projects.
+type PackageManager string
+
+const (
+	NPM  PackageManager = "npm"
+	Yarn PackageManager = "yarn"
+	Pnpm PackageManager = "pnpm"
+	Bun  PackageManager = "bun"
+)
+
+// PackageManagers are the supported package managers.
+var PackageManagers = []PackageManager{NPM, Yarn, Pnpm, Bun}
+
+// lockfiles maps the lockfile of each package manager to the package manager.
+var lockfiles = []struct {
+	filename       string
+	packageManager PackageManager
+}{
+	{"package-lock.json", NPM},
+	{"yarn.lock", Yarn},
+	{"pnpm-lock.yaml", Pnpm},
+	{"bun.lockb", Bun},
+	{"bun.lock", Bun},
+}
+
+// InstallFiles returns the names of the files and directories that installing the
+// dependencies may create or change in a directory: the lockfiles and node_modules.
+func InstallFiles() []string {
+	names := make([]string, 0, len(lockfiles)+1)
+	for _, lockfile := range lockfiles {
+		names = append(names, lockfile.filename)
+	}
+	return append(names, "node_modules")
+}
+
+// ParsePackageManager parses the name of a package manager.
+func ParsePackageManager(name string) (PackageManager, error) {
+	for _, pm := range PackageManagers {
+		if string(pm) == name {
+			return pm, nil
+		}
+	}
+	return "", fmt.Errorf("unknown package manager %q", name)
+}
+
+// DetectPackageManager detects the package manager used in a directory, from the
+// "packageManager" field of package.json (e.g. "pnpm@9.1.0") or from the lockfiles. It checks
+// the directory and then each of its parents, so packages of a workspace use the package
+// manager of the workspace. If nothing is found, it returns npm.
+func DetectPackageManager(dir string) (PackageManager, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		pm, err := detectPackageManagerIn(dir)
+		if err != nil {
+			return "", err
+		}
+		if pm != "" {
+			return pm, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return NPM, nil
+		}
+		dir = parent
+	}
+}
+
+// detectPackageManagerIn detects the package manager from the files of a single directory.
+// If there's no sign of one, it returns an empty string.
+func detectPackageManagerIn(dir string) (PackageManager, error) {
+	// The "packageManager" field has precedence over the lockfiles
+	packageJSON, err := New(dir).PackageJSON()
+	if err != nil {
+		return "", err
+	}
+	if packageJSON != nil && packageJSON.PackageManager != "" {
+		name, _, _ := strings.Cut(packageJSON.PackageManager, "@")
+		pm, err := ParsePackageManager(name)
+		if err != nil {
+			return "", fmt.Errorf("packageManager field of package.json: %w", err)
+		}
+		return pm, nil
+	}
+
+	// Check for the lockfiles
+	for _, lockfile := range lockfiles {
+		if _, err := os.Stat(filepath.Join(dir, lockfile.filename)); err == nil {
+			return lockfile.packageManager, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("checking %s: %w", lockfile.filename, err)
+		}
+	}
+	return "", nil
+}
+
+// Install installs the dependencies of a project.
+func (pm PackageManager) Install(ctx context.Context, p Project) error {
+	if err := p.Exec(ctx, string(pm), "install"); err != nil {
+		return fmt.Errorf("%s install: %w", pm, err)
+	}
+	return nil
+}
+
+// Run runs a script from the package.json file of a project.
+func (pm PackageManager) Run(ctx context.Context, p Project, script string) error {
+	if err := p.Exec(ctx, string(pm), "run", script); err != nil {
+		return fmt.Errorf("%s run %s: %w", pm, script, err)
+	}
+	return nil
+}
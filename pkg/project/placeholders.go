@@ -0,0 +1,141 @@
+package project
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// placeholderRegexp matches placeholders like "${version}" or "${env.FOO}". A placeholder
+// prefixed with an extra "$" (e.g. "$${version}") is escaped and left as a literal.
+var placeholderRegexp = regexp.MustCompile(`\$?\$\{([A-Za-z0-9_.\-]+)\}`)
+
+// placeholderResolver resolves the values of placeholders in the plugin descriptor. Values
+// that are expensive to compute are only looked up once.
+type placeholderResolver struct {
+	ctx     context.Context
+	project *project
+	pkg     *PackageJSON
+	pkgRead bool
+	git     *GitInfo
+	gitRead bool
+}
+
+func (r *placeholderResolver) packageJSON() (*PackageJSON, error) {
+	if !r.pkgRead {
+		pkg, err := r.project.PackageJSON()
+		if err != nil {
+			return nil, err
+		}
+		r.pkg, r.pkgRead = pkg, true
+	}
+	if r.pkg == nil {
+		return nil, fmt.Errorf("package.json is missing")
+	}
+	return r.pkg, nil
+}
+
+func (r *placeholderResolver) gitInfo() (*GitInfo, error) {
+	if !r.gitRead {
+		info, err := r.project.Git(r.ctx)
+		if err != nil {
+			return nil, err
+		}
+		r.git, r.gitRead = info, true
+	}
+	if r.git == nil {
+		return nil, fmt.Errorf("project is not in a git repository")
+	}
+	return r.git, nil
+}
+
+// resolve returns the value of a single placeholder key.
+func (r *placeholderResolver) resolve(key string) (string, error) {
+	switch {
+	case key == "name" || key == "version":
+		pkg, err := r.packageJSON()
+		if err != nil {
+			return "", err
+		}
+		if key == "name" {
+			return pkg.Name, nil
+		}
+		return pkg.Version, nil
+	case strings.HasPrefix(key, "env."):
+		value, ok := os.LookupEnv(strings.TrimPrefix(key, "env."))
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", strings.TrimPrefix(key, "env."))
+		}
+		return value, nil
+	case strings.HasPrefix(key, "git."):
+		info, err := r.gitInfo()
+		if err != nil {
+			return "", err
+		}
+		switch strings.TrimPrefix(key, "git.") {
+		case "commit":
+			return info.Commit, nil
+		case "shortCommit":
+			return info.ShortCommit(), nil
+		case "branch":
+			return info.Branch, nil
+		case "dirty":
+			if info.Dirty {
+				return "-dirty", nil
+			}
+			return "", nil
+		}
+	}
+	return "", fmt.Errorf("unknown placeholder")
+}
+
+// expandString replaces all placeholders in a string with their values.
+func (r *placeholderResolver) expandString(s string) (string, error) {
+	var expandErr error
+	expanded := placeholderRegexp.ReplaceAllStringFunc(s, func(match string) string {
+		// Escaped placeholders are written without the leading "$"
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+		key := placeholderRegexp.FindStringSubmatch(match)[1]
+		value, err := r.resolve(key)
+		if err != nil && expandErr == nil {
+			expandErr = fmt.Errorf("expanding ${%s}: %w", key, err)
+		}
+		return value
+	})
+	return expanded, expandErr
+}
+
+// expand walks a YAML document and expands the placeholders in all string values. Map keys
+// are left untouched.
+func (r *placeholderResolver) expand(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if node.ShortTag() != "!!str" {
+			return nil
+		}
+		value, err := r.expandString(node.Value)
+		if err != nil {
+			return err
+		}
+		node.Value = value
+	case yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			if err := r.expand(node.Content[i]); err != nil {
+				return err
+			}
+		}
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			if err := r.expand(child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package project
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -21,9 +22,32 @@ type Project interface {
 	// PackageJSON reads the package.json file contents from the project directory.
 	// If the file does not exist, it returns nil.
 	PackageJSON() (*PackageJSON, error)
-	// PluginYML reads the plugin.yml file contents from the project directory.
+	// Config reads the CLI configuration from the "crx" field of the package.json file.
+	// If the file or the field does not exist, it returns an empty configuration.
+	Config() (*Config, error)
+	// PluginYMLFile returns the path to the plugin descriptor file, checking each of the
+	// PluginYMLFilenames in order. If none of them exist, it returns an empty string.
+	PluginYMLFile() (string, error)
+	// PluginYML reads the plugin descriptor contents from the project directory, expanding
+	// placeholders like "${version}", "${name}", "${env.FOO}" and "${git.commit}".
 	// If the file does not exist, it returns nil.
-	PluginYML() (*pluginyml.Plugin, error)
+	PluginYML(ctx context.Context) (*pluginyml.Plugin, error)
+	// TargetPluginYML reads the plugin descriptor like PluginYML, names the plugin after the
+	// target and applies the target's plugin.yml overrides. If the target is nil, it's the
+	// same as PluginYML.
+	TargetPluginYML(ctx context.Context, target *Target) (*pluginyml.Plugin, error)
+	// Git reads the state of the Git repository containing the project directory.
+	// If the directory is not in a Git repository, or Git is not installed, it returns nil.
+	Git(ctx context.Context) (*GitInfo, error)
+}
+
+// PluginYMLFilenames are the plugin descriptor filenames accepted in a project, in order
+// of precedence.
+var PluginYMLFilenames = []string{
+	"plugin.yml",
+	"plugin.yaml",
+	"plugin.json",
+	filepath.Join("src", "plugin.yml"),
 }
 
 // New creates a new project from the given directory.
@@ -66,21 +90,127 @@ func (p *project) PackageJSON() (*PackageJSON, error) {
 	return &packageJSON, nil
 }
 
-func (p *project) PluginYML() (*pluginyml.Plugin, error) {
-	// Open the file
-	file, err := os.Open(filepath.Join(p.dir, "plugin.yml"))
+func (p *project) Config() (*Config, error) {
+	packageJSON, err := p.PackageJSON()
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return nil, nil
+		return nil, err
+	}
+	if packageJSON == nil || packageJSON.Crx == nil {
+		return &Config{}, nil
+	}
+	if err := packageJSON.Crx.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid crx config in package.json: %w", err)
+	}
+	return packageJSON.Crx, nil
+}
+
+func (p *project) PluginYMLFile() (string, error) {
+	for _, filename := range PluginYMLFilenames {
+		stat, err := os.Stat(filepath.Join(p.dir, filename))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return "", fmt.Errorf("checking %s: %w", filename, err)
+		}
+		if !stat.IsDir() {
+			return filepath.Join(p.dir, filename), nil
 		}
-		return nil, fmt.Errorf("opening plugin.yml: %w", err)
 	}
-	defer file.Close()
+	return "", nil
+}
+
+func (p *project) PluginYML(ctx context.Context) (*pluginyml.Plugin, error) {
+	// Find the plugin descriptor file
+	filename, err := p.PluginYMLFile()
+	if err != nil {
+		return nil, err
+	}
+	if filename == "" {
+		return nil, nil
+	}
+	base := filepath.Base(filename)
+
+	// Read the file
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", base, err)
+	}
+
+	// Decode the document and expand its placeholders
+	doc, err := p.decodePluginDocument(ctx, data, filepath.Ext(filename) == ".json")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", base, err)
+	}
 
-	// Decode the yaml file
+	// Decode the expanded document into the plugin type
 	var plugin pluginyml.Plugin
-	if err := yaml.NewDecoder(file).Decode(&plugin); err != nil {
-		return nil, fmt.Errorf("decoding plugin.yml: %w", err)
+	if err := doc.Decode(&plugin); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", base, err)
 	}
 	return &plugin, nil
 }
+
+func (p *project) TargetPluginYML(ctx context.Context, target *Target) (*pluginyml.Plugin, error) {
+	// Read the project's plugin descriptor
+	plugin, err := p.PluginYML(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		return plugin, nil
+	}
+
+	// Each target is a separate plugin, named after the target unless the overrides say
+	// otherwise
+	if plugin == nil {
+		plugin = &pluginyml.Plugin{}
+	}
+	plugin.Name = target.Name
+	if len(target.Plugin) == 0 {
+		return plugin, nil
+	}
+
+	// Decode the overrides and expand their placeholders
+	doc, err := p.decodePluginDocument(ctx, target.Plugin, true)
+	if err != nil {
+		return nil, fmt.Errorf("plugin overrides for target %q: %w", target.Name, err)
+	}
+
+	// Decode the overrides on top of the project's plugin descriptor
+	if err := doc.Decode(plugin); err != nil {
+		return nil, fmt.Errorf("decoding plugin overrides for target %q: %w", target.Name, err)
+	}
+	return plugin, nil
+}
+
+// decodePluginDocument decodes a plugin descriptor document and expands its placeholders.
+func (p *project) decodePluginDocument(ctx context.Context, data []byte, isJSON bool) (*yaml.Node, error) {
+	// JSON documents are converted to YAML first, since not every valid JSON document is
+	// valid YAML (e.g. tab indentation)
+	if isJSON {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		var doc any
+		if err := dec.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("decoding json: %w", err)
+		}
+		var err error
+		if data, err = yaml.Marshal(doc); err != nil {
+			return nil, fmt.Errorf("converting json: %w", err)
+		}
+	}
+
+	// Decode the yaml document
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("decoding yaml: %w", err)
+	}
+
+	// Expand the placeholders in the document
+	resolver := placeholderResolver{ctx: ctx, project: p}
+	if err := resolver.expand(&doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
@@ -0,0 +1,301 @@
+package project_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/customrealms/cli/pkg/project"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestFile(t *testing.T, dir, filename, contents string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, filename)), 0777); err != nil {
+		t.Fatalf("create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, filename), []byte(contents), 0666); err != nil {
+		t.Fatalf("write test file: %v", err)
+	}
+}
+
+func TestPluginYML(t *testing.T) {
+	t.Run("missing", func(t *testing.T) {
+		plugin, err := project.New(t.TempDir()).PluginYML(context.Background())
+		require.NoError(t, err)
+		require.Nil(t, plugin)
+	})
+
+	t.Run("plugin.yaml", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTestFile(t, dir, "plugin.yaml", "name: MyPlugin\nversion: 1.0\n")
+
+		plugin, err := project.New(dir).PluginYML(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, "MyPlugin", plugin.Name)
+		require.Equal(t, "1.0", plugin.Version)
+	})
+
+	t.Run("plugin.json", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTestFile(t, dir, "plugin.json", "{\n\t\"name\": \"MyPlugin\",\n\t\"authors\": [\"a\", \"b\"]\n}\n")
+
+		plugin, err := project.New(dir).PluginYML(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, "MyPlugin", plugin.Name)
+		require.Equal(t, []string{"a", "b"}, plugin.Authors)
+	})
+
+	t.Run("src/plugin.yml", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTestFile(t, dir, "src/plugin.yml", "name: MyPlugin\n")
+
+		plugin, err := project.New(dir).PluginYML(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, "MyPlugin", plugin.Name)
+	})
+
+	t.Run("precedence", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTestFile(t, dir, "plugin.json", `{"name": "FromJSON"}`)
+		writeTestFile(t, dir, "plugin.yml", "name: FromYML\n")
+
+		plugin, err := project.New(dir).PluginYML(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, "FromYML", plugin.Name)
+	})
+
+	t.Run("placeholders", func(t *testing.T) {
+		dir := t.TempDir()
+		t.Setenv("CRX_TEST_AUTHOR", "Steve")
+		writeTestFile(t, dir, "package.json", `{"name": "my-plugin", "version": "1.2.3"}`)
+		writeTestFile(t, dir, "plugin.yml", `
+name: ${name}
+version: ${version}-beta
+author: ${env.CRX_TEST_AUTHOR}
+description: Costs $${price}
+permissions:
+  ${name}.use:
+    default: true
+`)
+
+		plugin, err := project.New(dir).PluginYML(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, "my-plugin", plugin.Name)
+		require.Equal(t, "1.2.3-beta", plugin.Version)
+		require.Equal(t, "Steve", *plugin.Author)
+		require.Equal(t, "Costs ${price}", *plugin.Description)
+		require.Contains(t, plugin.Permissions, "${name}.use")
+	})
+
+	t.Run("git placeholders", func(t *testing.T) {
+		if _, err := exec.LookPath("git"); err != nil {
+			t.Skip("git is not installed")
+		}
+		dir := t.TempDir()
+		writeTestFile(t, dir, "plugin.yml", "name: Test\nversion: 1.0.0+${git.shortCommit}${git.dirty}\n")
+		git := func(args ...string) {
+			cmd := exec.Command("git", append([]string{"-c", "user.name=Test", "-c", "user.email=test@example.com"}, args...)...)
+			cmd.Dir = dir
+			out, err := cmd.CombinedOutput()
+			require.NoError(t, err, string(out))
+		}
+		git("init", "-q")
+		git("add", "-A")
+		git("commit", "-q", "-m", "Initial commit")
+		p := project.New(dir)
+
+		info, err := p.Git(context.Background())
+		require.NoError(t, err)
+		plugin, err := p.PluginYML(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, "1.0.0+"+info.ShortCommit(), plugin.Version)
+
+		// Uncommitted changes mark the version as dirty
+		writeTestFile(t, dir, "README.md", "changed")
+		plugin, err = p.PluginYML(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, "1.0.0+"+info.ShortCommit()+"-dirty", plugin.Version)
+	})
+
+	t.Run("unknown placeholder", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTestFile(t, dir, "plugin.yml", "name: ${nope}\n")
+
+		_, err := project.New(dir).PluginYML(context.Background())
+		require.ErrorContains(t, err, "${nope}")
+	})
+
+	t.Run("missing environment variable", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTestFile(t, dir, "plugin.yml", "name: ${env.CRX_TEST_UNSET_VARIABLE}\n")
+
+		_, err := project.New(dir).PluginYML(context.Background())
+		require.ErrorContains(t, err, "CRX_TEST_UNSET_VARIABLE is not set")
+	})
+}
+
+func TestConfig(t *testing.T) {
+	t.Run("missing", func(t *testing.T) {
+		config, err := project.New(t.TempDir()).Config()
+		require.NoError(t, err)
+		require.Equal(t, project.Size(0), config.Budgets.Bundle)
+	})
+
+	t.Run("budgets", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTestFile(t, dir, "package.json", `{"name": "my-plugin", "crx": {"budgets": {"bundle": "1.5MB", "jar": 4096}}}`)
+
+		config, err := project.New(dir).Config()
+		require.NoError(t, err)
+		require.Equal(t, project.Size(1572864), config.Budgets.Bundle)
+		require.Equal(t, project.Size(4096), config.Budgets.Jar)
+	})
+
+	t.Run("test", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTestFile(t, dir, "package.json", `{"name": "my-plugin", "crx": {"test": {
+			"timeout": "2m",
+			"commands": [{"command": "version", "expect": "Paper", "timeout": 5}]
+		}}}`)
+
+		config, err := project.New(dir).Config()
+		require.NoError(t, err)
+		require.Equal(t, project.Duration(2*time.Minute), config.Test.Timeout)
+		require.Equal(t, []project.TestCommand{
+			{Command: "version", Expect: "Paper", Timeout: project.Duration(5 * time.Second)},
+		}, config.Test.Commands)
+	})
+
+	t.Run("invalid budget", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTestFile(t, dir, "package.json", `{"name": "my-plugin", "crx": {"budgets": {"bundle": "lots"}}}`)
+
+		_, err := project.New(dir).Config()
+		require.ErrorContains(t, err, `invalid size "lots"`)
+	})
+}
+
+func TestTargetPluginYML(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "package.json", `{
+	"name": "my-plugins",
+	"version": "1.2.3",
+	"crx": {
+		"targets": [
+			{"name": "Economy", "entrypoint": "src/economy.ts"},
+			{"name": "Chat", "entrypoint": "src/chat.ts", "plugin": {"name": "BetterChat", "description": "Chat ${version}", "softdepend": ["Economy"]}}
+		]
+	}
+}`)
+	writeTestFile(t, dir, "plugin.yml", "name: Shared\nauthor: Steve\ndescription: Shared plugin\n")
+
+	p := project.New(dir)
+	config, err := p.Config()
+	require.NoError(t, err)
+
+	t.Run("no overrides", func(t *testing.T) {
+		plugin, err := p.TargetPluginYML(context.Background(), config.Target("Economy"))
+		require.NoError(t, err)
+		require.Equal(t, "Economy", plugin.Name)
+		require.Equal(t, "Steve", *plugin.Author)
+		require.Equal(t, "Shared plugin", *plugin.Description)
+	})
+
+	t.Run("overrides", func(t *testing.T) {
+		plugin, err := p.TargetPluginYML(context.Background(), config.Target("Chat"))
+		require.NoError(t, err)
+		require.Equal(t, "BetterChat", plugin.Name)
+		require.Equal(t, "Steve", *plugin.Author)
+		require.Equal(t, "Chat 1.2.3", *plugin.Description)
+		require.Equal(t, []string{"Economy"}, plugin.SoftDepend)
+	})
+
+	t.Run("nil target", func(t *testing.T) {
+		plugin, err := p.TargetPluginYML(context.Background(), nil)
+		require.NoError(t, err)
+		require.Equal(t, "Shared", plugin.Name)
+	})
+}
+
+func TestConfigTargets(t *testing.T) {
+	tests := map[string]string{
+		"missing name":       `[{"entrypoint": "src/a.ts"}]`,
+		"missing entrypoint": `[{"name": "a"}]`,
+		"duplicate target":   `[{"name": "a", "entrypoint": "src/a.ts"}, {"name": "a", "entrypoint": "src/b.ts"}]`,
+		"slash in name":      `[{"name": "a/b", "entrypoint": "src/a.ts"}]`,
+		"backslash in name":  `[{"name": "a\\b", "entrypoint": "src/a.ts"}]`,
+		"dots in name":       `[{"name": "..", "entrypoint": "src/a.ts"}]`,
+	}
+	for name, targets := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTestFile(t, dir, "package.json", `{"name": "my-plugins", "crx": {"targets": `+targets+`}}`)
+
+			_, err := project.New(dir).Config()
+			require.ErrorContains(t, err, "invalid crx config")
+		})
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	for input, expected := range map[string]project.Size{
+		"100":    100,
+		"100B":   100,
+		"500KB":  500 * 1024,
+		"500 kb": 500 * 1024,
+		"3MB":    3 * 1024 * 1024,
+		"1.5GB":  1536 * 1024 * 1024,
+	} {
+		size, err := project.ParseSize(input)
+		require.NoError(t, err, input)
+		require.Equal(t, expected, size, input)
+	}
+
+	_, err := project.ParseSize("-1MB")
+	require.Error(t, err)
+
+	require.Equal(t, "3MB", project.Size(3*1024*1024).String())
+	require.Equal(t, "1.5KB", project.Size(1536).String())
+	require.Equal(t, "12B", project.Size(12).String())
+}
+
+func TestDetectPackageManager(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  project.PackageManager
+	}{
+		{"default", nil, project.NPM},
+		{"npm lockfile", map[string]string{"package-lock.json": "{}"}, project.NPM},
+		{"yarn lockfile", map[string]string{"yarn.lock": ""}, project.Yarn},
+		{"pnpm lockfile", map[string]string{"pnpm-lock.yaml": ""}, project.Pnpm},
+		{"bun lockfile", map[string]string{"bun.lockb": ""}, project.Bun},
+		{"packageManager field", map[string]string{"package.json": `{"packageManager": "pnpm@9.1.0"}`, "yarn.lock": ""}, project.Pnpm},
+		{"workspace root", map[string]string{"../yarn.lock": "", "package.json": `{"name": "a"}`}, project.Yarn},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "project")
+			writeTestFile(t, dir, "package.json", "{}")
+			for filename, contents := range tt.files {
+				writeTestFile(t, dir, filename, contents)
+			}
+
+			pm, err := project.DetectPackageManager(dir)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, pm)
+		})
+	}
+
+	t.Run("unknown", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTestFile(t, dir, "package.json", `{"packageManager": "cargo@1.0.0"}`)
+
+		_, err := project.DetectPackageManager(dir)
+		require.ErrorContains(t, err, `unknown package manager "cargo"`)
+	})
+}
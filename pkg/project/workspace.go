@@ -0,0 +1,250 @@
+package project
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PnpmWorkspaceFilename is the file declaring the packages of a pnpm workspace.
+const PnpmWorkspaceFilename = "pnpm-workspace.yaml"
+
+// Workspace is a monorepo containing several packages, declared with the "workspaces" field
+// of package.json (npm and yarn) or with a pnpm-workspace.yaml file (pnpm).
+type Workspace struct {
+	// Dir is the root directory of the workspace.
+	Dir string
+	// Patterns are the glob patterns of the package directories, relative to the root
+	// directory. Patterns starting with "!" exclude directories.
+	Patterns []string
+}
+
+// FindWorkspace finds the workspace containing a directory, checking the directory itself
+// and then each of its parents. If there isn't one, it returns nil.
+func FindWorkspace(dir string) (*Workspace, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		ws, err := readWorkspace(dir)
+		if err != nil {
+			return nil, err
+		}
+		if ws != nil {
+			return ws, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// readWorkspace reads the workspace declared in a directory. If the directory isn't the
+// root of a workspace, it returns nil.
+func readWorkspace(dir string) (*Workspace, error) {
+	// pnpm declares the workspace in its own file
+	data, err := os.ReadFile(filepath.Join(dir, PnpmWorkspaceFilename))
+	if err == nil {
+		var pnpmWorkspace struct {
+			Packages []string `yaml:"packages"`
+		}
+		if err := yaml.Unmarshal(data, &pnpmWorkspace); err != nil {
+			return nil, fmt.Errorf("decoding %s: %w", PnpmWorkspaceFilename, err)
+		}
+		return &Workspace{Dir: dir, Patterns: pnpmWorkspace.Packages}, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("reading %s: %w", PnpmWorkspaceFilename, err)
+	}
+
+	// npm and yarn declare the workspace in package.json
+	packageJSON, err := New(dir).PackageJSON()
+	if err != nil {
+		return nil, err
+	}
+	if packageJSON == nil || len(packageJSON.Workspaces) == 0 {
+		return nil, nil
+	}
+	return &Workspace{Dir: dir, Patterns: packageJSON.Workspaces}, nil
+}
+
+// Contains reports whether a directory is one of the package directories of the workspace.
+func (w *Workspace) Contains(dir string) (bool, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return false, err
+	}
+	rel, err := filepath.Rel(w.Dir, dir)
+	if err != nil {
+		return false, err
+	}
+	if rel == "." || strings.HasPrefix(rel, "..") {
+		return false, nil
+	}
+	return w.matches(filepath.ToSlash(rel)), nil
+}
+
+// matches reports whether a directory path, relative to the root directory, matches the
+// patterns of the workspace.
+func (w *Workspace) matches(rel string) bool {
+	matched := false
+	for _, pattern := range w.Patterns {
+		exclude := strings.HasPrefix(pattern, "!")
+		pattern = path.Clean(strings.TrimPrefix(strings.TrimPrefix(pattern, "!"), "./"))
+		if MatchGlob(pattern, rel) {
+			matched = !exclude
+		}
+	}
+	return matched
+}
+
+// Packages returns the packages of the workspace, sorted by directory.
+func (w *Workspace) Packages() ([]Project, error) {
+	var packages []Project
+	err := filepath.WalkDir(w.Dir, func(filename string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if filename != w.Dir && (d.Name() == "node_modules" || strings.HasPrefix(d.Name(), ".")) {
+			return filepath.SkipDir
+		}
+		rel, err := filepath.Rel(w.Dir, filename)
+		if err != nil {
+			return err
+		}
+		if rel == "." || !w.matches(filepath.ToSlash(rel)) {
+			return nil
+		}
+		if _, err := os.Stat(filepath.Join(filename, "package.json")); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		packages = append(packages, New(filename))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("finding workspace packages: %w", err)
+	}
+	sort.Slice(packages, func(i, j int) bool {
+		return packages[i].Dir() < packages[j].Dir()
+	})
+	return packages, nil
+}
+
+// PluginPackages returns the packages of the workspace that are plugin projects.
+func (w *Workspace) PluginPackages() ([]Project, error) {
+	packages, err := w.Packages()
+	if err != nil {
+		return nil, err
+	}
+	var plugins []Project
+	for _, p := range packages {
+		ok, err := IsPlugin(p)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			plugins = append(plugins, p)
+		}
+	}
+	return plugins, nil
+}
+
+// Filter returns the packages matching any of the filters. A filter is a glob pattern
+// matching either the package name, or its directory relative to the workspace root
+// (e.g. "./plugins/*"). It's an error for a filter to match no packages.
+func (w *Workspace) Filter(packages []Project, filters []string) ([]Project, error) {
+	if len(filters) == 0 {
+		return packages, nil
+	}
+	matched := make(map[Project]bool)
+	for _, filter := range filters {
+		found := false
+		for _, p := range packages {
+			ok, err := w.matchesFilter(p, filter)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				matched[p] = true
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no workspace packages match filter %q", filter)
+		}
+	}
+	var filtered []Project
+	for _, p := range packages {
+		if matched[p] {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered, nil
+}
+
+// matchesFilter reports whether a package matches a filter.
+func (w *Workspace) matchesFilter(p Project, filter string) (bool, error) {
+	if _, err := path.Match(filter, ""); err != nil {
+		return false, fmt.Errorf("invalid filter %q: %w", filter, err)
+	}
+
+	// Match the directory of the package
+	if strings.HasPrefix(filter, "./") {
+		rel, err := filepath.Rel(w.Dir, p.Dir())
+		if err != nil {
+			return false, err
+		}
+		ok, _ := path.Match(path.Clean(filter), filepath.ToSlash(rel))
+		return ok, nil
+	}
+
+	// Match the name of the package
+	packageJSON, err := p.PackageJSON()
+	if err != nil || packageJSON == nil {
+		return false, err
+	}
+	ok, _ := path.Match(filter, packageJSON.Name)
+	return ok, nil
+}
+
+// IsPlugin reports whether a project is a plugin project, rather than a library. Plugin
+// projects have a plugin descriptor, a "crx" field in package.json, or the default
+// entrypoint "src/main.ts".
+func IsPlugin(p Project) (bool, error) {
+	filename, err := p.PluginYMLFile()
+	if err != nil {
+		return false, err
+	}
+	if filename != "" {
+		return true, nil
+	}
+	packageJSON, err := p.PackageJSON()
+	if err != nil {
+		return false, err
+	}
+	if packageJSON != nil && packageJSON.Crx != nil {
+		return true, nil
+	}
+	if _, err := os.Stat(filepath.Join(p.Dir(), "src", "main.ts")); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
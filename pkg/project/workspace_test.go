@@ -0,0 +1,130 @@
+package project_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/customrealms/cli/pkg/project"
+	"github.com/stretchr/testify/require"
+)
+
+// packageDirs returns the directories of the projects, relative to a root directory.
+func packageDirs(t *testing.T, root string, projects []project.Project) []string {
+	t.Helper()
+	dirs := make([]string, 0, len(projects))
+	for _, p := range projects {
+		rel, err := filepath.Rel(root, p.Dir())
+		require.NoError(t, err)
+		dirs = append(dirs, filepath.ToSlash(rel))
+	}
+	return dirs
+}
+
+func TestFindWorkspace(t *testing.T) {
+	t.Run("none", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTestFile(t, dir, "package.json", `{"name": "my-plugin"}`)
+
+		ws, err := project.FindWorkspace(dir)
+		require.NoError(t, err)
+		require.Nil(t, ws)
+	})
+
+	t.Run("npm", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTestFile(t, dir, "package.json", `{"name": "root", "workspaces": ["plugins/*"]}`)
+		writeTestFile(t, dir, "plugins/a/package.json", `{"name": "a"}`)
+
+		ws, err := project.FindWorkspace(filepath.Join(dir, "plugins", "a"))
+		require.NoError(t, err)
+		require.Equal(t, dir, ws.Dir)
+		require.Equal(t, []string{"plugins/*"}, ws.Patterns)
+	})
+
+	t.Run("yarn", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTestFile(t, dir, "package.json", `{"name": "root", "workspaces": {"packages": ["packages/*"]}}`)
+
+		ws, err := project.FindWorkspace(dir)
+		require.NoError(t, err)
+		require.Equal(t, []string{"packages/*"}, ws.Patterns)
+	})
+
+	t.Run("pnpm", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTestFile(t, dir, "package.json", `{"name": "root"}`)
+		writeTestFile(t, dir, "pnpm-workspace.yaml", "packages:\n  - 'plugins/*'\n")
+
+		ws, err := project.FindWorkspace(dir)
+		require.NoError(t, err)
+		require.Equal(t, []string{"plugins/*"}, ws.Patterns)
+	})
+}
+
+func TestWorkspacePackages(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "package.json", `{"name": "root", "workspaces": ["plugins/*", "libs/**", "!plugins/old"]}`)
+	writeTestFile(t, dir, "plugins/economy/package.json", `{"name": "@acme/economy"}`)
+	writeTestFile(t, dir, "plugins/economy/src/main.ts", "")
+	writeTestFile(t, dir, "plugins/chat/package.json", `{"name": "@acme/chat"}`)
+	writeTestFile(t, dir, "plugins/chat/plugin.yml", "name: Chat\n")
+	writeTestFile(t, dir, "plugins/old/package.json", `{"name": "@acme/old"}`)
+	writeTestFile(t, dir, "plugins/old/src/main.ts", "")
+	writeTestFile(t, dir, "plugins/economy/node_modules/dep/package.json", `{"name": "dep"}`)
+	writeTestFile(t, dir, "libs/shared/utils/package.json", `{"name": "@acme/utils"}`)
+	writeTestFile(t, dir, "libs/shared/utils/src/index.ts", "")
+
+	ws, err := project.FindWorkspace(dir)
+	require.NoError(t, err)
+
+	packages, err := ws.Packages()
+	require.NoError(t, err)
+	require.Equal(t, []string{"libs/shared/utils", "plugins/chat", "plugins/economy"}, packageDirs(t, dir, packages))
+
+	plugins, err := ws.PluginPackages()
+	require.NoError(t, err)
+	require.Equal(t, []string{"plugins/chat", "plugins/economy"}, packageDirs(t, dir, plugins))
+
+	ok, err := ws.Contains(filepath.Join(dir, "plugins", "new"))
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = ws.Contains(filepath.Join(dir, "plugins", "old"))
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	t.Run("filter by name", func(t *testing.T) {
+		filtered, err := ws.Filter(plugins, []string{"@acme/chat"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"plugins/chat"}, packageDirs(t, dir, filtered))
+	})
+
+	t.Run("filter by dir", func(t *testing.T) {
+		filtered, err := ws.Filter(packages, []string{"./plugins/*"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"plugins/chat", "plugins/economy"}, packageDirs(t, dir, filtered))
+	})
+
+	t.Run("filter without match", func(t *testing.T) {
+		_, err := ws.Filter(plugins, []string{"@acme/nope"})
+		require.ErrorContains(t, err, `no workspace packages match filter "@acme/nope"`)
+	})
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"packages/*", "packages/a", true},
+		{"packages/*", "packages/a/b", false},
+		{"packages/**", "packages/a/b", true},
+		{"src/**/*.ts", "src/main.ts", true},
+		{"src/**/*.ts", "src/a/b/main.ts", true},
+		{"src/**/*.ts", "src/main.js", false},
+		{"**", "anything/at/all", true},
+		{"plugin.yml", "src/plugin.yml", false},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, project.MatchGlob(tt.pattern, tt.name), "%s %s", tt.pattern, tt.name)
+	}
+}
@@ -7,7 +7,11 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
+	"strings"
 
+	"github.com/customrealms/cli/pkg/errdefs"
+	"github.com/customrealms/cli/pkg/jdk"
 	"github.com/customrealms/cli/pkg/minecraft"
 	"github.com/customrealms/cli/pkg/server"
 	"golang.org/x/sync/errgroup"
@@ -15,14 +19,28 @@ import (
 
 type ServeAction struct {
 	MinecraftVersion minecraft.Version
-	PluginJarPath    string
+	// PluginJarPaths are the plugin JAR files loaded into the server. Their base names
+	// must be unique.
+	PluginJarPaths   []string
 	ServerJarFetcher server.JarFetcher
+	// Java is the java command, or the Java installation directory, to run the server with.
+	// If it's empty, an installed Java runtime recent enough for the Minecraft version is
+	// used.
+	Java string
+	// Properties are written to the server.properties file, replacing the defaults of the
+	// server.
+	Properties map[string]string
+	// Stdin is the console input of the server. If it's nil, it's the standard input.
+	Stdin io.Reader
+	// Stdout receives the console output of the server. If it's nil, it's the standard
+	// output.
+	Stdout io.Writer
 }
 
-func (a *ServeAction) DownloadJarTo(dest string) error {
+func (a *ServeAction) DownloadJarTo(ctx context.Context, dest string) error {
 
 	// Download the JAR to a reader stream
-	jarReader, err := a.ServerJarFetcher.Fetch(a.MinecraftVersion)
+	jarReader, err := a.ServerJarFetcher.Fetch(ctx, a.MinecraftVersion)
 	if err != nil {
 		return err
 	}
@@ -62,13 +80,71 @@ func copyFile(from, to string) error {
 	return nil
 }
 
+// copyPlugins copies the plugin JAR files to the server's plugins directory.
+func (a *ServeAction) copyPlugins(pluginsDir string) error {
+	for _, pluginJarPath := range a.PluginJarPaths {
+		if err := copyFile(pluginJarPath, filepath.Join(pluginsDir, filepath.Base(pluginJarPath))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeProperties writes a server.properties file.
+func writeProperties(filename string, properties map[string]string) error {
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	escaper := strings.NewReplacer(`\`, `\\`, ":", `\:`, "=", `\=`)
+	for _, key := range keys {
+		fmt.Fprintf(&b, "%s=%s\n", escaper.Replace(key), escaper.Replace(properties[key]))
+	}
+	return os.WriteFile(filename, []byte(b.String()), 0666)
+}
+
+// findJava finds the Java runtime to run the server with.
+func (a *ServeAction) findJava(ctx context.Context) (*jdk.Runtime, error) {
+	required := minecraft.MinJavaVersion(a.MinecraftVersion.String())
+
+	// Use the runtime chosen by the user, even if it may be too old
+	if a.Java != "" {
+		java, err := jdk.Open(ctx, a.Java)
+		if err != nil {
+			return nil, errdefs.Prerequisite(err)
+		}
+		if java.Major < required {
+			fmt.Printf("Minecraft %s needs Java %d or later, the server may not start with %s.\n", a.MinecraftVersion, required, java)
+		}
+		return java, nil
+	}
+
+	// Find a recent enough runtime
+	java, err := jdk.Find(ctx, required)
+	if err != nil {
+		return nil, errdefs.Prerequisite(fmt.Errorf("%w: install it from https://adoptium.net, or choose a Java installation with --java", err))
+	}
+	return java, nil
+}
+
 func (a *ServeAction) Run(ctx context.Context, chanPluginUpdated <-chan struct{}) error {
 
-	// Check if Java is installed on the machine
-	if _, err := exec.LookPath("java"); err != nil {
-		fmt.Println("Couldn't find 'java' command on your machine. Make sure Java is installed.")
-		fmt.Println("Visit https://dev.java/download and download the most recent version.")
-		return nil
+	// Check that the plugin JAR files don't overwrite each other
+	pluginJarNames := make(map[string]bool)
+	for _, pluginJarPath := range a.PluginJarPaths {
+		name := filepath.Base(pluginJarPath)
+		if pluginJarNames[name] {
+			return fmt.Errorf("several plugin JAR files are named %s", name)
+		}
+		pluginJarNames[name] = true
+	}
+
+	// Find the Java runtime to run the server with
+	java, err := a.findJava(ctx)
+	if err != nil {
+		return err
 	}
 
 	fmt.Println("============================================================")
@@ -94,7 +170,7 @@ func (a *ServeAction) Run(ctx context.Context, chanPluginUpdated <-chan struct{}
 	jarFile := filepath.Join(dir, jarBase)
 
 	// Download the JAR file to the path
-	if err := a.DownloadJarTo(jarFile); err != nil {
+	if err := a.DownloadJarTo(ctx, jarFile); err != nil {
 		return err
 	}
 
@@ -102,7 +178,7 @@ func (a *ServeAction) Run(ctx context.Context, chanPluginUpdated <-chan struct{}
 	fmt.Println()
 
 	fmt.Println("============================================================")
-	fmt.Println("Copying plugin JAR file to server 'plugins' folder...")
+	fmt.Println("Copying plugin JAR files to server 'plugins' folder...")
 	fmt.Println("============================================================")
 
 	// Make the plugin directory
@@ -110,7 +186,7 @@ func (a *ServeAction) Run(ctx context.Context, chanPluginUpdated <-chan struct{}
 	if err := os.MkdirAll(pluginsDir, 0777); err != nil {
 		return err
 	}
-	if err := copyFile(a.PluginJarPath, filepath.Join(pluginsDir, filepath.Base(a.PluginJarPath))); err != nil {
+	if err := a.copyPlugins(pluginsDir); err != nil {
 		return err
 	}
 
@@ -118,6 +194,11 @@ func (a *ServeAction) Run(ctx context.Context, chanPluginUpdated <-chan struct{}
 	if err := os.WriteFile(filepath.Join(dir, "eula.txt"), []byte("eula=true\n"), 0777); err != nil {
 		return err
 	}
+	if len(a.Properties) > 0 {
+		if err := writeProperties(filepath.Join(dir, "server.properties"), a.Properties); err != nil {
+			return err
+		}
+	}
 
 	fmt.Println(" -> Done")
 	fmt.Println()
@@ -125,6 +206,7 @@ func (a *ServeAction) Run(ctx context.Context, chanPluginUpdated <-chan struct{}
 	fmt.Println("============================================================")
 	fmt.Println("Launching server...")
 	fmt.Println("============================================================")
+	fmt.Println(" -> Using", java)
 	fmt.Println()
 
 	eg, ctx := errgroup.WithContext(ctx)
@@ -143,8 +225,8 @@ func (a *ServeAction) Run(ctx context.Context, chanPluginUpdated <-chan struct{}
 				}
 			}
 
-			// Copy the plugin file to the server
-			if err := copyFile(a.PluginJarPath, filepath.Join(pluginsDir, filepath.Base(a.PluginJarPath))); err != nil {
+			// Copy the plugin files to the server
+			if err := a.copyPlugins(pluginsDir); err != nil {
 				return err
 			}
 			fmt.Println("Plugin JAR updated. Run `/reload confirm` to reload the plugin.")
@@ -154,11 +236,15 @@ func (a *ServeAction) Run(ctx context.Context, chanPluginUpdated <-chan struct{}
 		defer close(chanServerStopped)
 
 		// Run the server
-		cmd := exec.CommandContext(ctx, "java", "-jar", jarBase, "-nogui")
+		cmd := exec.CommandContext(ctx, java.Path, "-jar", jarBase, "-nogui")
 		cmd.Dir = dir
-		cmd.Stdin = os.Stdin
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if a.Stdin != nil {
+			cmd.Stdin = a.Stdin
+		}
+		if a.Stdout != nil {
+			cmd.Stdout, cmd.Stderr = a.Stdout, a.Stdout
+		}
 		return cmd.Run()
 	})
 	return eg.Wait()
@@ -1,11 +1,12 @@
 package server
 
 import (
+	"context"
 	"io"
 
 	"github.com/customrealms/cli/pkg/minecraft"
 )
 
 type JarFetcher interface {
-	Fetch(version minecraft.Version) (io.ReadCloser, error)
+	Fetch(ctx context.Context, version minecraft.Version) (io.ReadCloser, error)
 }
@@ -1,23 +1,53 @@
 package server
 
 import (
+	"context"
 	"io"
-	"net/http"
+	"os"
 
+	"github.com/customrealms/cli/pkg/download"
 	"github.com/customrealms/cli/pkg/minecraft"
 )
 
-type HttpFetcher struct{}
+type HttpFetcher struct {
+	// Client downloads the JAR files. If it's nil, download.Default is used.
+	Client *download.Client
+}
 
-func (f *HttpFetcher) Fetch(version minecraft.Version) (io.ReadCloser, error) {
+func (f *HttpFetcher) Fetch(ctx context.Context, version minecraft.Version) (io.ReadCloser, error) {
+	client := f.Client
+	if client == nil {
+		client = download.Default
+	}
 
-	// Fetch the JAR file from the server
-	res, err := http.Get(version.ServerJarUrl())
+	// Download the JAR file to a temporary file of this process, so concurrent runs don't
+	// write to the same file
+	file, err := os.CreateTemp("", "cr-"+JarCacheKey(version)+"-*")
 	if err != nil {
 		return nil, err
 	}
+	file.Close()
+	if err := client.File(ctx, version.ServerJarUrl(), file.Name()); err != nil {
+		os.Remove(file.Name())
+		return nil, err
+	}
 
-	// Return the body of the response
-	return res.Body, nil
+	// Return the file, which is removed once it's read
+	filename := file.Name()
+	if file, err = os.Open(filename); err != nil {
+		os.Remove(filename)
+		return nil, err
+	}
+	return &tempFile{file}, nil
+}
+
+// tempFile is a file removed when it's closed.
+type tempFile struct {
+	*os.File
+}
 
+func (f *tempFile) Close() error {
+	err := f.File.Close()
+	os.Remove(f.Name())
+	return err
 }
@@ -0,0 +1,210 @@
+// Package serverlog parses the console output of Minecraft servers into log events. Servers
+// log with Log4j, in the formats of the console, like "[12:00:00 INFO]: [MyPlugin] Hello",
+// and of the log files, like "[12:00:00] [Server thread/INFO]: [MyPlugin] Hello".
+package serverlog
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Level is the level of a log event.
+type Level int
+
+const (
+	LevelTrace Level = iota
+	LevelDebug
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelFatal
+)
+
+var levelNames = []string{"TRACE", "DEBUG", "INFO", "WARN", "ERROR", "FATAL"}
+
+// ParseLevel parses the name of a level, ignoring case. The names of the java.util.logging
+// levels used by older servers are accepted too, like SEVERE for ERROR.
+func ParseLevel(name string) (Level, error) {
+	name = strings.ToUpper(name)
+	for i, levelName := range levelNames {
+		if name == levelName {
+			return Level(i), nil
+		}
+	}
+	switch name {
+	case "FINEST", "FINER":
+		return LevelTrace, nil
+	case "FINE", "CONFIG":
+		return LevelDebug, nil
+	case "WARNING":
+		return LevelWarn, nil
+	case "SEVERE":
+		return LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q", name)
+}
+
+func (l Level) String() string {
+	if l < 0 || int(l) >= len(levelNames) {
+		return fmt.Sprintf("Level(%d)", int(l))
+	}
+	return levelNames[l]
+}
+
+func (l Level) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+func (l *Level) UnmarshalText(text []byte) error {
+	level, err := ParseLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*l = level
+	return nil
+}
+
+// Event is a log event of a server.
+type Event struct {
+	// Time is the time of the event as logged, like "12:00:00".
+	Time string `json:"time,omitempty"`
+	// Level is the level of the event.
+	Level Level `json:"level"`
+	// Thread is the thread logging the event. Only the log files have it.
+	Thread string `json:"thread,omitempty"`
+	// Logger is the name of the logger, which is the plugin name for plugins. It's empty for
+	// the server itself.
+	Logger string `json:"logger,omitempty"`
+	// Message is the logged message.
+	Message string `json:"message"`
+	// Trace are the lines logged after the event that don't start a new event, like the stack
+	// trace of an exception.
+	Trace []string `json:"trace,omitempty"`
+}
+
+var (
+	// consoleLine matches the lines of the console, like "[12:00:00 INFO]: Hello".
+	consoleLine = regexp.MustCompile(`^\[(\d{1,2}:\d{2}:\d{2}) ([A-Za-z]+)\]:? ?(.*)$`)
+	// fileLine matches the lines of log files, like "[12:00:00] [Server thread/INFO]: Hello",
+	// and "[12:00:00] [Server thread/INFO] [MyPlugin]: Hello" in recent Paper versions.
+	fileLine = regexp.MustCompile(`^\[(\d{1,2}:\d{2}:\d{2})\] \[([^\]]*)/([A-Za-z]+)\](?: \[([^\]]+)\])?:? ?(.*)$`)
+	// loggerPrefix matches the logger name at the start of messages, like "[MyPlugin] Hello".
+	loggerPrefix = regexp.MustCompile(`^\[([^\] ]+)\] (.*)$`)
+	// ansiEscape matches the escape sequences of terminal colors.
+	ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+)
+
+// Parse parses a line of output into an event. It returns false if the line doesn't start
+// a new event, e.g. if it's a line of a stack trace.
+func Parse(line string) (Event, bool) {
+	line = StripANSI(strings.TrimRight(line, "\r"))
+	var event Event
+	var level string
+	if match := consoleLine.FindStringSubmatch(line); match != nil {
+		event.Time, level, event.Message = match[1], match[2], match[3]
+	} else if match := fileLine.FindStringSubmatch(line); match != nil {
+		event.Time, event.Thread, level, event.Logger, event.Message = match[1], match[2], match[3], match[4], match[5]
+	} else {
+		return Event{}, false
+	}
+	var err error
+	if event.Level, err = ParseLevel(level); err != nil {
+		return Event{}, false
+	}
+	if event.Logger == "" {
+		if match := loggerPrefix.FindStringSubmatch(event.Message); match != nil {
+			event.Logger, event.Message = match[1], match[2]
+		}
+	}
+	return event, true
+}
+
+// StripANSI removes the terminal colors from a line.
+func StripANSI(line string) string {
+	return ansiEscape.ReplaceAllString(line, "")
+}
+
+// Plugin is a plugin whose events are tracked, usually the plugin under development.
+type Plugin struct {
+	// Name is the name of the plugin, from its plugin.yml file.
+	Name string
+	// JarName is the base name of the JAR file of the plugin.
+	JarName string
+}
+
+// pluginMessage matches the messages the server logs about a plugin, with its name and
+// version: loading, enabling and disabling it, like "Enabling MyPlugin v1.0.0" and "Error
+// occurred while enabling MyPlugin v1.0.0", and the exceptions thrown by its event
+// listeners, commands and tasks, like "Could not pass event PlayerJoinEvent to MyPlugin
+// v1.0.0", "Unhandled exception executing command 'foo' in plugin MyPlugin v1.0.0" and
+// "Task #12 for MyPlugin v1.0.0 generated an exception".
+var pluginMessage = regexp.MustCompile(`(?:^(?:Loading|Enabling|Disabling|Error occurred while (?:loading|enabling|disabling)) |^Could not pass event \S+ to | in plugin |^Task #\d+ for )(\S+) v`)
+
+// Owns reports whether an event is about the plugin: it's logged by the plugin, it's a
+// message of the server about the plugin (see pluginMessage), or it mentions the JAR file of
+// the plugin, like "Could not load 'plugins/my-plugin.jar' in folder 'plugins'". Other
+// messages mentioning the name of the plugin aren't about it.
+func (p Plugin) Owns(event Event) bool {
+	if event.Logger == p.Name {
+		return true
+	}
+	if match := pluginMessage.FindStringSubmatch(event.Message); match != nil && match[1] == p.Name {
+		return true
+	}
+	return p.JarName != "" && strings.Contains(event.Message, p.JarName)
+}
+
+// Problem is a plugin that failed to load or to enable.
+type Problem struct {
+	// Plugin is the name of the plugin, or of its JAR file if it couldn't be loaded.
+	Plugin string `json:"plugin"`
+	// Stage is "load" or "enable".
+	Stage string `json:"stage"`
+	// Message is the message of the error event.
+	Message string `json:"message"`
+	// Exception is the exception that caused the error, if it was logged.
+	Exception string `json:"exception,omitempty"`
+}
+
+var (
+	// loadError matches the errors loading plugins, like "Could not load 'plugins/X.jar' in
+	// folder 'plugins'", "Could not load plugin 'X.jar' in folder 'plugins'", and "Error
+	// initializing plugin 'X.jar' in folder 'plugins'".
+	loadError = regexp.MustCompile(`^(?:Could not load (?:plugin )?|Error initializing plugin )'([^']+)'`)
+	// enableError matches the errors enabling plugins, like "Error occurred while enabling
+	// MyPlugin v1.0.0 (Is it up to date?)".
+	enableError = regexp.MustCompile(`^Error occurred while enabling (\S+) v`)
+)
+
+// ParseProblem returns the problem reported by an event, if it's about a plugin failing to
+// load or to enable.
+func ParseProblem(event Event) (Problem, bool) {
+	if event.Level < LevelWarn {
+		return Problem{}, false
+	}
+	var problem Problem
+	if match := loadError.FindStringSubmatch(event.Message); match != nil {
+		problem = Problem{Plugin: path.Base(strings.ReplaceAll(match[1], `\`, "/")), Stage: "load"}
+	} else if match := enableError.FindStringSubmatch(event.Message); match != nil {
+		problem = Problem{Plugin: match[1], Stage: "enable"}
+	} else {
+		return Problem{}, false
+	}
+	problem.Message = event.Message
+	problem.Exception = Exception(event)
+	return problem, true
+}
+
+// Exception returns the exception in the trace of an event: the first line that isn't a
+// stack frame. It's empty if there's none.
+func Exception(event Event) string {
+	for _, line := range event.Trace {
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" && !strings.HasPrefix(trimmed, "at ") && !strings.HasPrefix(trimmed, "...") {
+			return trimmed
+		}
+	}
+	return ""
+}
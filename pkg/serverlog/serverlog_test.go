@@ -0,0 +1,187 @@
+package serverlog_test
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/customrealms/cli/pkg/serverlog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		line  string
+		event serverlog.Event
+		ok    bool
+	}{
+		{
+			line:  "[12:00:00 INFO]: Starting minecraft server version 1.21.4",
+			event: serverlog.Event{Time: "12:00:00", Level: serverlog.LevelInfo, Message: "Starting minecraft server version 1.21.4"},
+			ok:    true,
+		},
+		{
+			line:  "[12:00:00 WARN]: [MyPlugin] Something is off",
+			event: serverlog.Event{Time: "12:00:00", Level: serverlog.LevelWarn, Logger: "MyPlugin", Message: "Something is off"},
+			ok:    true,
+		},
+		{
+			line:  "[12:00:00] [Server thread/ERROR]: Error occurred while enabling MyPlugin v1.0.0 (Is it up to date?)",
+			event: serverlog.Event{Time: "12:00:00", Level: serverlog.LevelError, Thread: "Server thread", Message: "Error occurred while enabling MyPlugin v1.0.0 (Is it up to date?)"},
+			ok:    true,
+		},
+		{
+			line:  "[12:00:00] [Server thread/INFO] [MyPlugin]: Enabling MyPlugin v1.0.0",
+			event: serverlog.Event{Time: "12:00:00", Level: serverlog.LevelInfo, Thread: "Server thread", Logger: "MyPlugin", Message: "Enabling MyPlugin v1.0.0"},
+			ok:    true,
+		},
+		{
+			line:  "[12:00:00 SEVERE]: \x1b[31mOld server\x1b[0m",
+			event: serverlog.Event{Time: "12:00:00", Level: serverlog.LevelError, Message: "Old server"},
+			ok:    true,
+		},
+		{line: "\tat MyPlugin.onEnable(plugin.js:1)"},
+		{line: "java.lang.IllegalStateException: boom"},
+		{line: "[MyPlugin] not an event"},
+	}
+	for _, test := range tests {
+		t.Run(test.line, func(t *testing.T) {
+			event, ok := serverlog.Parse(test.line)
+			require.Equal(t, test.ok, ok)
+			require.Equal(t, test.event, event)
+		})
+	}
+}
+
+// output is the console output of a server whose plugin under development fails to enable.
+const output = `Starting org.bukkit.craftbukkit.Main
+[12:00:00 INFO]: Loading 2 libraries... please wait
+[12:00:00 DEBUG]: Debugging
+[12:00:00 ERROR]: Could not load 'plugins/Other.jar' in folder 'plugins'
+org.bukkit.plugin.UnknownDependencyException: Unknown dependency Vault
+	at org.bukkit.plugin.SimplePluginManager.loadPlugins(SimplePluginManager.java:1)
+[12:00:01 INFO]: [MyPlugin] Enabling MyPlugin v1.0.0
+[12:00:01 ERROR]: Error occurred while enabling MyPlugin v1.0.0 (Is it up to date?)
+java.lang.IllegalStateException: boom
+	at MyPlugin.onEnable(plugin.js:1)
+[12:00:02 INFO]: Done (2.345s)! For help, type "help"
+[12:00:03 INFO]: [MyPlugin] Hello`
+
+func TestWriter(t *testing.T) {
+	var out, jsonOut strings.Builder
+	w := serverlog.NewWriter(&out, serverlog.Options{
+		Plugins: []serverlog.Plugin{{Name: "MyPlugin", JarName: "my-plugin.jar"}},
+		Level:   serverlog.LevelInfo,
+		Filter:  regexp.MustCompile(`Done|Hello|Could not`),
+		JSON:    &jsonOut,
+	})
+
+	// Write in chunks that split lines
+	for i := 0; i < len(output); i += 7 {
+		_, err := w.Write([]byte(output[i:min(i+7, len(output))]))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+
+	// Only the filtered events and the errors of the plugin under development are shown, and
+	// the problems are summarized once the server is started
+	require.Equal(t, `[12:00:00 ERROR]: Could not load 'plugins/Other.jar' in folder 'plugins'
+org.bukkit.plugin.UnknownDependencyException: Unknown dependency Vault
+	at org.bukkit.plugin.SimplePluginManager.loadPlugins(SimplePluginManager.java:1)
+[12:00:01 ERROR]: Error occurred while enabling MyPlugin v1.0.0 (Is it up to date?)
+java.lang.IllegalStateException: boom
+	at MyPlugin.onEnable(plugin.js:1)
+[12:00:02 INFO]: Done (2.345s)! For help, type "help"
+============================================================
+2 plugins failed to load or enable:
+ -> Other.jar failed to load: org.bukkit.plugin.UnknownDependencyException: Unknown dependency Vault
+ -> MyPlugin failed to enable: java.lang.IllegalStateException: boom (plugin under development)
+============================================================
+[12:00:03 INFO]: [MyPlugin] Hello
+`, out.String())
+	require.Equal(t, []serverlog.Problem{
+		{Plugin: "Other.jar", Stage: "load", Message: "Could not load 'plugins/Other.jar' in folder 'plugins'", Exception: "org.bukkit.plugin.UnknownDependencyException: Unknown dependency Vault"},
+		{Plugin: "MyPlugin", Stage: "enable", Message: "Error occurred while enabling MyPlugin v1.0.0 (Is it up to date?)", Exception: "java.lang.IllegalStateException: boom"},
+	}, w.Problems())
+
+	// Every event is written to the JSON output
+	lines := strings.Split(strings.TrimSpace(jsonOut.String()), "\n")
+	require.Len(t, lines, 8)
+	var event serverlog.Event
+	require.NoError(t, json.Unmarshal([]byte(lines[5]), &event))
+	require.Equal(t, "Error occurred while enabling MyPlugin v1.0.0 (Is it up to date?)", event.Message)
+	require.Equal(t, []string{"java.lang.IllegalStateException: boom", "\tat MyPlugin.onEnable(plugin.js:1)"}, event.Trace)
+	require.Contains(t, lines[5], `"level":"ERROR"`)
+}
+
+func TestWriterReload(t *testing.T) {
+	var out strings.Builder
+	w := serverlog.NewWriter(&out, serverlog.Options{
+		Plugins: []serverlog.Plugin{{Name: "MyPlugin"}},
+		Level:   serverlog.LevelError,
+	})
+	_, err := w.Write([]byte(`[12:00:00 ERROR]: Error occurred while enabling MyPlugin v1.0.0 (Is it up to date?)
+[12:00:01 INFO]: Done (1.234s)! For help, type "help"
+[12:00:02 WARN]: Please note that this command is not supported and may cause issues when using some plugins.
+[12:00:02 ERROR]: Error occurred while enabling MyPlugin v1.0.1 (Is it up to date?)
+[12:00:03 INFO]: Reload complete.
+[12:00:04 ERROR]: [Other] MyPlugin isn't compatible
+`))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	// The problems are summarized again after a reload
+	require.Equal(t, `[12:00:00 ERROR]: Error occurred while enabling MyPlugin v1.0.0 (Is it up to date?)
+============================================================
+1 plugin failed to load or enable:
+ -> MyPlugin failed to enable: Error occurred while enabling MyPlugin v1.0.0 (Is it up to date?) (plugin under development)
+============================================================
+[12:00:02 ERROR]: Error occurred while enabling MyPlugin v1.0.1 (Is it up to date?)
+============================================================
+1 plugin failed to load or enable:
+ -> MyPlugin failed to enable: Error occurred while enabling MyPlugin v1.0.1 (Is it up to date?) (plugin under development)
+============================================================
+[12:00:04 ERROR]: [Other] MyPlugin isn't compatible
+`, out.String())
+	require.Len(t, w.Problems(), 1)
+
+	// Other plugins mentioning the plugin aren't about it
+	require.False(t, serverlog.Plugin{Name: "MyPlugin"}.Owns(serverlog.Event{Logger: "Other", Message: "MyPlugin isn't compatible"}))
+}
+
+func TestPluginOwns(t *testing.T) {
+	plugin := serverlog.Plugin{Name: "MyPlugin", JarName: "my-plugin.jar"}
+	for _, message := range []string{
+		"Enabling MyPlugin v1.0.0",
+		"Error occurred while enabling MyPlugin v1.0.0 (Is it up to date?)",
+		"Could not pass event PlayerJoinEvent to MyPlugin v1.0.0",
+		"Unhandled exception executing command 'foo' in plugin MyPlugin v1.0.0",
+		"Task #12 for MyPlugin v1.0.0 generated an exception",
+		"Could not load 'plugins/my-plugin.jar' in folder 'plugins'",
+	} {
+		require.True(t, plugin.Owns(serverlog.Event{Message: message}), message)
+	}
+	for _, message := range []string{
+		"Could not pass event PlayerJoinEvent to OtherPlugin v1.0.0",
+		"Unhandled exception executing command 'foo' in plugin OtherPlugin v1.0.0",
+		"Task #12 for MyPluginExtras v1.0.0 generated an exception",
+		"OtherPlugin depends on MyPlugin v1.0.0",
+	} {
+		require.False(t, plugin.Owns(serverlog.Event{Message: message}), message)
+	}
+}
+
+func TestWriterColor(t *testing.T) {
+	var out strings.Builder
+	w := serverlog.NewWriter(&out, serverlog.Options{
+		Plugins: []serverlog.Plugin{{Name: "MyPlugin"}},
+		Color:   true,
+	})
+	_, err := w.Write([]byte("[12:00:00 WARN]: Slow\n[12:00:00 INFO]: [MyPlugin] Hi\n[12:00:00 ERROR]: [MyPlugin] Oops\n\tat x\n"))
+	require.NoError(t, err)
+	require.Equal(t, "\x1b[33m[12:00:00 WARN]: Slow\x1b[0m\n"+
+		"[12:00:00 INFO]: \x1b[36m[MyPlugin]\x1b[0m Hi\n"+
+		"\x1b[1;31m[12:00:00 ERROR]: [MyPlugin] Oops\x1b[0m\n"+
+		"\x1b[1;31m\tat x\x1b[0m\n", out.String())
+}
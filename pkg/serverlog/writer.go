@@ -0,0 +1,256 @@
+package serverlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var (
+	// doneMessage matches the message logged when the server is started, like
+	// `Done (12.345s)! For help, type "help"`, or when the plugins are reloaded with /reload,
+	// "Reload complete.".
+	doneMessage = regexp.MustCompile(`^(?:Done \([0-9.,]+s\)!|Reload complete\b)`)
+	// loadMessage matches the messages logged when the server starts loading the plugins,
+	// like "Initializing plugins..." or "Loading 3 plugins", or reloading them with /reload,
+	// like "Reloading plugins" or the warning the command starts with.
+	loadMessage = regexp.MustCompile(`^(?:Initializing plugins\b|Loading (?:\d+ )?plugins\b|Reloading plugins\b|Please note that this command is not supported)`)
+)
+
+// Terminal colors of the output.
+const (
+	colorReset     = "\x1b[0m"
+	colorDim       = "\x1b[2m"
+	colorRed       = "\x1b[31m"
+	colorYellow    = "\x1b[33m"
+	colorCyan      = "\x1b[36m"
+	colorHighlight = "\x1b[1;31m"
+)
+
+// Options sets how a Writer shows the console output of a server.
+type Options struct {
+	// Plugins are the plugins under development. Their warnings and errors, with the stack
+	// traces, are highlighted and always shown.
+	Plugins []Plugin
+	// Level is the minimum level of the events shown.
+	Level Level
+	// Filter only shows the events whose line matches it, if it's set.
+	Filter *regexp.Regexp
+	// Color colors the events by level.
+	Color bool
+	// JSON receives all the events, shown or not, as a JSON object per line, if it's set.
+	JSON io.Writer
+}
+
+// Writer parses the console output of a server written to it, and shows the events. Once
+// the server is started, or when the Writer is closed if it never started, a summary of the
+// plugins that failed to load or to enable is shown. The plugins are summarized again when
+// they're reloaded with /reload.
+type Writer struct {
+	out  io.Writer
+	opts Options
+
+	mu sync.Mutex
+	// partial is the last line written, until it's complete.
+	partial []byte
+	// event is the last event, which continues until another one starts.
+	event *Event
+	// shown is set if the last event is shown.
+	shown bool
+	// highlight is set if the last event is a warning or an error of a plugin under
+	// development.
+	highlight bool
+	// problem is the index of the problem reported by the last event, or -1.
+	problem    int
+	problems   []Problem
+	summarized bool
+}
+
+// NewWriter creates a Writer showing the events on an output.
+func NewWriter(out io.Writer, opts Options) *Writer {
+	return &Writer{out: out, opts: opts, problem: -1}
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		line := string(w.partial[:i])
+		w.partial = w.partial[i+1:]
+		if err := w.line(line); err != nil {
+			return len(p), err
+		}
+	}
+	return len(p), nil
+}
+
+// Close shows the last incomplete line, and the summary of the problems if it wasn't shown
+// yet.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.partial) > 0 {
+		line := string(w.partial)
+		w.partial = nil
+		if err := w.line(line); err != nil {
+			return err
+		}
+	}
+	if err := w.flushJSON(); err != nil {
+		return err
+	}
+	return w.summarize()
+}
+
+// Problems returns the plugins that failed to load or to enable so far, since the plugins
+// were last loaded.
+func (w *Writer) Problems() []Problem {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]Problem(nil), w.problems...)
+}
+
+// line handles a line of output.
+func (w *Writer) line(line string) error {
+	text := StripANSI(strings.TrimRight(line, "\r"))
+	event, ok := Parse(text)
+	if !ok {
+		// The line continues the last event
+		if w.event != nil {
+			w.event.Trace = append(w.event.Trace, text)
+			if w.problem >= 0 && w.problems[w.problem].Exception == "" {
+				w.problems[w.problem].Exception = Exception(*w.event)
+			}
+			if w.shown {
+				return w.print(text, w.event, w.highlight)
+			}
+			return nil
+		}
+		event = Event{Level: LevelInfo, Message: text}
+	}
+
+	// Start a new event
+	if err := w.flushJSON(); err != nil {
+		return err
+	}
+	w.event = &event
+	w.problem = -1
+	if loadMessage.MatchString(event.Message) {
+		// The plugins are loaded again, so the previous problems are summarized already
+		w.problems = nil
+		w.summarized = false
+	}
+	if problem, ok := ParseProblem(event); ok {
+		w.problems = append(w.problems, problem)
+		w.problem = len(w.problems) - 1
+	}
+	w.highlight = event.Level >= LevelWarn && w.owned(event)
+	w.shown = w.highlight || (event.Level >= w.opts.Level && (w.opts.Filter == nil || w.opts.Filter.MatchString(text)))
+	if w.shown {
+		if err := w.print(text, w.event, w.highlight); err != nil {
+			return err
+		}
+	}
+	if doneMessage.MatchString(event.Message) {
+		return w.summarize()
+	}
+	return nil
+}
+
+// owned reports whether an event is about a plugin under development.
+func (w *Writer) owned(event Event) bool {
+	for _, plugin := range w.opts.Plugins {
+		if plugin.Owns(event) {
+			return true
+		}
+	}
+	return false
+}
+
+// print shows a line of an event.
+func (w *Writer) print(text string, event *Event, highlight bool) error {
+	if w.opts.Color {
+		switch {
+		case highlight:
+			text = colorHighlight + text + colorReset
+		case event.Level >= LevelError:
+			text = colorRed + text + colorReset
+		case event.Level == LevelWarn:
+			text = colorYellow + text + colorReset
+		case event.Level <= LevelDebug:
+			text = colorDim + text + colorReset
+		case event.Logger != "" && w.owned(Event{Logger: event.Logger}):
+			// Tell the logs of the plugins under development apart
+			tag := "[" + event.Logger + "]"
+			text = strings.Replace(text, tag, colorCyan+tag+colorReset, 1)
+		}
+	}
+	_, err := fmt.Fprintln(w.out, text)
+	return err
+}
+
+// flushJSON writes the last event to the JSON output, now that it's complete.
+func (w *Writer) flushJSON() error {
+	if w.opts.JSON == nil || w.event == nil {
+		return nil
+	}
+	data, err := json.Marshal(w.event)
+	if err != nil {
+		return err
+	}
+	w.event = nil
+	_, err = w.opts.JSON.Write(append(data, '\n'))
+	return err
+}
+
+// summarize shows the plugins that failed to load or to enable, once.
+func (w *Writer) summarize() error {
+	if w.summarized || len(w.problems) == 0 {
+		return nil
+	}
+	w.summarized = true
+
+	var b strings.Builder
+	b.WriteString("============================================================\n")
+	if len(w.problems) == 1 {
+		b.WriteString("1 plugin failed to load or enable:\n")
+	} else {
+		fmt.Fprintf(&b, "%d plugins failed to load or enable:\n", len(w.problems))
+	}
+	for _, problem := range w.problems {
+		detail := problem.Exception
+		if detail == "" {
+			detail = problem.Message
+		}
+		line := fmt.Sprintf(" -> %s failed to %s: %s", problem.Plugin, problem.Stage, detail)
+		if w.ownsProblem(problem) {
+			line += " (plugin under development)"
+			if w.opts.Color {
+				line = colorHighlight + line + colorReset
+			}
+		}
+		b.WriteString(line + "\n")
+	}
+	b.WriteString("============================================================\n")
+	_, err := io.WriteString(w.out, b.String())
+	return err
+}
+
+// ownsProblem reports whether a problem is about a plugin under development.
+func (w *Writer) ownsProblem(problem Problem) bool {
+	for _, plugin := range w.opts.Plugins {
+		if problem.Plugin == plugin.Name || problem.Plugin == plugin.JarName {
+			return true
+		}
+	}
+	return false
+}